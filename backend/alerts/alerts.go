@@ -0,0 +1,253 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sloggo/models"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule describes a webhook that fires when an incoming log matches all of its conditions
+type Rule struct {
+	Name         string `json:"name"`
+	Match        string `json:"match"`   // e.g. "severity<=1 and appName=auth"
+	Webhook      string `json:"webhook"` // URL receiving the POST request
+	MaxPerMinute int    `json:"maxPerMinute"`
+
+	conditions  []condition
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+}
+
+// condition is a single "field operator value" comparison of a rule
+type condition struct {
+	field    string
+	operator string
+	value    string
+}
+
+// webhookPayload is the JSON body sent to the webhook, "text" keeps it Slack compatible
+type webhookPayload struct {
+	Text  string          `json:"text"`
+	Rule  string          `json:"rule"`
+	Entry models.LogEntry `json:"entry"`
+}
+
+var (
+	rules      []*Rule
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+
+	// Longer operators first so "<=" isn't mistaken for "<"
+	operators = []string{"<=", ">=", "!=", "<", ">", "="}
+)
+
+func init() {
+	if utils.AlertRules == "" {
+		return
+	}
+
+	parsed, err := ParseRules(utils.AlertRules)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_ALERT_RULES, alerts are disabled: %v", err)
+		return
+	}
+
+	rules = parsed
+	log.Printf("Loaded %d alert rule(s)", len(rules))
+}
+
+// ParseRules parses a JSON array of rules and compiles their match expressions
+func ParseRules(config string) ([]*Rule, error) {
+	var parsed []*Rule
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		return nil, fmt.Errorf("error decoding rules: %v", err)
+	}
+
+	for i, rule := range parsed {
+		if rule.Webhook == "" {
+			return nil, fmt.Errorf("rule %d has no webhook", i+1)
+		}
+
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+
+		if rule.MaxPerMinute <= 0 {
+			rule.MaxPerMinute = 10
+		}
+
+		conditions, err := parseMatch(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule.Name, err)
+		}
+		rule.conditions = conditions
+	}
+
+	return parsed, nil
+}
+
+// parseMatch splits an expression like "severity<=1 and appName=auth" into conditions
+func parseMatch(match string) ([]condition, error) {
+	match = strings.TrimSpace(match)
+	if match == "" {
+		return nil, fmt.Errorf("empty match expression")
+	}
+
+	conditions := []condition{}
+	for _, part := range strings.Split(match, " and ") {
+		part = strings.TrimSpace(part)
+
+		parsed := false
+		for _, operator := range operators {
+			if idx := strings.Index(part, operator); idx > 0 {
+				cond := condition{
+					field:    strings.TrimSpace(part[:idx]),
+					operator: operator,
+					value:    strings.TrimSpace(part[idx+len(operator):]),
+				}
+
+				switch cond.field {
+				case "severity", "facility":
+					if _, err := strconv.Atoi(cond.value); err != nil {
+						return nil, fmt.Errorf("%s expects an integer, got %q", cond.field, cond.value)
+					}
+				case "hostname", "appName", "procId", "msgId":
+					if cond.operator != "=" && cond.operator != "!=" {
+						return nil, fmt.Errorf("%s only supports = and !=", cond.field)
+					}
+				default:
+					return nil, fmt.Errorf("unknown field %q", cond.field)
+				}
+
+				conditions = append(conditions, cond)
+				parsed = true
+				break
+			}
+		}
+
+		if !parsed {
+			return nil, fmt.Errorf("invalid condition %q", part)
+		}
+	}
+
+	return conditions, nil
+}
+
+// Evaluate checks the entry against all configured rules and fires the webhooks of matching ones
+// Webhooks are sent asynchronously so ingestion is never blocked
+func Evaluate(entry models.LogEntry) {
+	for _, rule := range rules {
+		if rule.Matches(entry) && rule.allow(time.Now()) {
+			go send(rule, entry)
+		}
+	}
+}
+
+// Matches reports whether the entry satisfies every condition of the rule
+func (r *Rule) Matches(entry models.LogEntry) bool {
+	for _, cond := range r.conditions {
+		if !cond.matches(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) matches(entry models.LogEntry) bool {
+	switch c.field {
+	case "severity":
+		return compareInt(int(entry.Severity), c.operator, c.value)
+	case "facility":
+		return compareInt(int(entry.Facility), c.operator, c.value)
+	case "hostname":
+		return compareString(entry.Hostname, c.operator, c.value)
+	case "appName":
+		return compareString(entry.AppName, c.operator, c.value)
+	case "procId":
+		return compareString(entry.ProcID, c.operator, c.value)
+	case "msgId":
+		return compareString(entry.MsgID, c.operator, c.value)
+	}
+	return false
+}
+
+func compareInt(actual int, operator string, value string) bool {
+	expected, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+
+	switch operator {
+	case "<=":
+		return actual <= expected
+	case ">=":
+		return actual >= expected
+	case "<":
+		return actual < expected
+	case ">":
+		return actual > expected
+	case "!=":
+		return actual != expected
+	default:
+		return actual == expected
+	}
+}
+
+func compareString(actual string, operator string, value string) bool {
+	if operator == "!=" {
+		return actual != value
+	}
+	return actual == value
+}
+
+// allow debounces the rule to at most MaxPerMinute webhooks per minute
+func (r *Rule) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.windowStart) >= time.Minute {
+		r.windowStart = now
+		r.windowCount = 0
+	}
+
+	if r.windowCount >= r.MaxPerMinute {
+		return false
+	}
+
+	r.windowCount++
+	return true
+}
+
+// send posts the matching entry to the rule's webhook
+func send(rule *Rule, entry models.LogEntry) {
+	payload := webhookPayload{
+		Text:  fmt.Sprintf("[%s] %s %s: %s", rule.Name, entry.Hostname, entry.AppName, entry.Message),
+		Rule:  rule.Name,
+		Entry: entry,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding alert for rule %s: %v", rule.Name, err)
+		return
+	}
+
+	resp, err := httpClient.Post(rule.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error sending alert for rule %s: %v", rule.Name, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log.Printf("Alert webhook for rule %s returned status %d", rule.Name, resp.StatusCode)
+	}
+}
@@ -0,0 +1,116 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		shouldError bool
+	}{
+		{"valid rule", `[{"match":"severity<=1 and appName=auth","webhook":"http://example.com"}]`, false},
+		{"invalid json", `[{"match":`, true},
+		{"missing webhook", `[{"match":"severity<=1"}]`, true},
+		{"unknown field", `[{"match":"color=red","webhook":"http://example.com"}]`, true},
+		{"non integer severity", `[{"match":"severity<=high","webhook":"http://example.com"}]`, true},
+		{"ordering on string field", `[{"match":"hostname>=a","webhook":"http://example.com"}]`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRules(tc.config)
+			if tc.shouldError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tc.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	parsed, err := ParseRules(`[{"match":"severity<=1 and appName=auth","webhook":"http://example.com"}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	rule := parsed[0]
+
+	tests := []struct {
+		name     string
+		entry    models.LogEntry
+		expected bool
+	}{
+		{"emergency from auth", models.LogEntry{Severity: 0, AppName: "auth"}, true},
+		{"alert from auth", models.LogEntry{Severity: 1, AppName: "auth"}, true},
+		{"critical from auth", models.LogEntry{Severity: 2, AppName: "auth"}, false},
+		{"emergency from other app", models.LogEntry{Severity: 0, AppName: "web"}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rule.Matches(tc.entry); got != tc.expected {
+				t.Errorf("Matches: got %t, want %t", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRuleDebounce(t *testing.T) {
+	rule := &Rule{MaxPerMinute: 2}
+	now := time.Now()
+
+	if !rule.allow(now) || !rule.allow(now) {
+		t.Fatal("Expected the first two alerts to be allowed")
+	}
+	if rule.allow(now.Add(30 * time.Second)) {
+		t.Error("Expected the third alert within a minute to be debounced")
+	}
+	if !rule.allow(now.Add(61 * time.Second)) {
+		t.Error("Expected alerts to be allowed again after a minute")
+	}
+}
+
+func TestEvaluateSendsWebhook(t *testing.T) {
+	received := make(chan webhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Invalid webhook payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	parsed, err := ParseRules(`[{"name":"auth-emergency","match":"severity=0","webhook":"` + server.URL + `"}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	originalRules := rules
+	rules = parsed
+	defer func() {
+		rules = originalRules
+	}()
+
+	Evaluate(models.LogEntry{Severity: 0, Hostname: "host1", AppName: "auth", Message: "Kernel panic"})
+
+	select {
+	case payload := <-received:
+		if payload.Rule != "auth-emergency" {
+			t.Errorf("Rule: got %q, want %q", payload.Rule, "auth-emergency")
+		}
+		if payload.Entry.Message != "Kernel panic" {
+			t.Errorf("Message: got %q, want %q", payload.Entry.Message, "Kernel panic")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Webhook was not called")
+	}
+}
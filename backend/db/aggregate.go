@@ -0,0 +1,237 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AggregateRow represents the value of a metric for one group
+type AggregateRow struct {
+	Group     string    `json:"group"`
+	Value     float64   `json:"value"`
+	Sparkline []float64 `json:"sparkline,omitempty"` // Metric per time bucket, oldest first, with sparkline=true
+}
+
+// aggregateGroupColumns maps the fields logs can be grouped by to their database columns
+var aggregateGroupColumns = map[string]string{
+	"hostname": "hostname",
+	"appName":  "app_name",
+	"procId":   "procid",
+	"msgId":    "msgid",
+	"facility": "facility",
+	"severity": "severity",
+}
+
+// hourOfDayGroup groups logs by the hour of the day of their timestamp, from 0 to 23
+const hourOfDayGroup = "hourOfDay"
+
+// IsAggregateGroupField reports whether logs can be grouped by the given field
+func IsAggregateGroupField(field string) bool {
+	_, ok := aggregateGroupColumns[field]
+	return ok || field == hourOfDayGroup
+}
+
+// noiseWeights holds the weight of each severity in the noise score, indexed by severity
+// Each level weighs twice the next less severe one, emergency being the highest
+var noiseWeights = [8]float64{128, 64, 32, 16, 8, 4, 2, 1}
+
+func init() {
+	if utils.NoiseWeights == "" {
+		return
+	}
+
+	weights, err := parseNoiseWeights(utils.NoiseWeights)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_NOISE_WEIGHTS, using the default weights: %v", err)
+		return
+	}
+
+	noiseWeights = weights
+}
+
+// parseNoiseWeights parses a comma-separated list of 8 weights, from emergency (0) to debug (7)
+func parseNoiseWeights(config string) ([8]float64, error) {
+	var weights [8]float64
+
+	values := strings.Split(config, ",")
+	if len(values) != len(weights) {
+		return weights, fmt.Errorf("expected %d weights, got %d", len(weights), len(values))
+	}
+
+	for i, value := range values {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return weights, fmt.Errorf("invalid weight %q for severity %d", value, i)
+		}
+		weights[i] = weight
+	}
+
+	return weights, nil
+}
+
+// Aggregate computes a metric for each value of the groupBy field, sorted by descending value
+// Supported metrics are "count" and "noiseScore", the sum of the severity weights.
+// Grouping by "hourOfDay" returns the 24 hours of the day in order, in the given IANA timezone.
+func Aggregate(metric string, groupBy string, filters map[string]any, limit int, timezone string) ([]AggregateRow, error) {
+	args := []any{}
+
+	column, ok := aggregateGroupColumns[groupBy]
+	if groupBy == hourOfDayGroup {
+		// Timestamps are stored in UTC, other timezones need the conversion of the ICU extension
+		column = "EXTRACT(hour FROM timestamp)"
+		if timezone != "" && timezone != "UTC" {
+			column = "EXTRACT(hour FROM (timestamp AT TIME ZONE 'UTC') AT TIME ZONE ?)"
+			args = append(args, timezone)
+		}
+	} else if !ok {
+		return nil, fmt.Errorf("unsupported group field %q", groupBy)
+	}
+
+	expression, err := metricExpression(metric, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(fmt.Sprintf("SELECT CAST(%s AS VARCHAR) AS grp, CAST(%s AS DOUBLE) AS value FROM %s", column, expression, tableOf(filters)))
+
+	whereClause := buildWhereClause(filters, time.Time{}, "", &args)
+	if whereClause != "" {
+		queryBuilder.WriteString(" WHERE ")
+		queryBuilder.WriteString(whereClause)
+	}
+
+	if groupBy == hourOfDayGroup {
+		queryBuilder.WriteString(" GROUP BY grp")
+	} else {
+		queryBuilder.WriteString(fmt.Sprintf(" GROUP BY grp ORDER BY value DESC, grp ASC LIMIT %d", limit))
+	}
+
+	rows, err := db.Query(queryBuilder.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying aggregate: %v", err)
+	}
+	defer rows.Close()
+
+	aggregateRows := []AggregateRow{}
+	for rows.Next() {
+		var row AggregateRow
+		if err := rows.Scan(&row.Group, &row.Value); err != nil {
+			return nil, fmt.Errorf("error scanning aggregate row: %v", err)
+		}
+		aggregateRows = append(aggregateRows, row)
+	}
+
+	if groupBy == hourOfDayGroup {
+		return hoursOfDay(aggregateRows), nil
+	}
+
+	return aggregateRows, nil
+}
+
+// metricExpression returns the SQL aggregate computing a metric, appending its arguments
+func metricExpression(metric string, args *[]any) (string, error) {
+	switch metric {
+	case "count":
+		return "COUNT(*)", nil
+	case "noiseScore":
+		cases := strings.Builder{}
+		cases.WriteString("SUM(CASE severity")
+		for severity, weight := range noiseWeights {
+			cases.WriteString(" WHEN ? THEN ?")
+			*args = append(*args, severity, weight)
+		}
+		cases.WriteString(" ELSE 0 END)")
+		return cases.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported metric %q", metric)
+	}
+}
+
+// Sparklines fills the sparkline of each aggregated group, the metric over equal time buckets
+// The window is the startDate and endDate filters, which the groups should have been aggregated with.
+func Sparklines(metric string, groupBy string, filters map[string]any, rows []AggregateRow, buckets int) error {
+	column, ok := aggregateGroupColumns[groupBy]
+	if !ok {
+		return fmt.Errorf("unsupported sparkline group field %q", groupBy)
+	}
+
+	start, startOk := filters["startDate"].(time.Time)
+	end, endOk := filters["endDate"].(time.Time)
+	if !startOk || !endOk || !end.After(start) || buckets <= 0 {
+		return fmt.Errorf("sparklines need a time window and buckets")
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Buckets are at least a millisecond wide, the last one ends with the window
+	bucketMs := max((end.UnixMilli()-start.UnixMilli()+int64(buckets)-1)/int64(buckets), 1)
+
+	args := []any{start.UnixMilli(), bucketMs}
+	expression, err := metricExpression(metric, &args)
+	if err != nil {
+		return err
+	}
+
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(fmt.Sprintf("SELECT CAST(%s AS VARCHAR) AS grp, CAST((epoch_ms(timestamp) - ?) // ? AS BIGINT) AS bucket, CAST(%s AS DOUBLE) AS value FROM %s", column, expression, tableOf(filters)))
+
+	queryBuilder.WriteString(" WHERE ")
+	if whereClause := buildWhereClause(filters, time.Time{}, "", &args); whereClause != "" {
+		queryBuilder.WriteString(whereClause)
+		queryBuilder.WriteString(" AND ")
+	}
+
+	// Only the top groups are bucketed, bounding the result to groups times buckets
+	placeholders := make([]string, len(rows))
+	index := make(map[string]int, len(rows))
+	for i, row := range rows {
+		placeholders[i] = "?"
+		args = append(args, row.Group)
+		index[row.Group] = i
+		rows[i].Sparkline = make([]float64, buckets)
+	}
+	queryBuilder.WriteString(fmt.Sprintf("CAST(%s AS VARCHAR) IN (%s) GROUP BY grp, bucket", column, strings.Join(placeholders, ",")))
+
+	result, err := db.Query(queryBuilder.String(), args...)
+	if err != nil {
+		return fmt.Errorf("error querying sparklines: %v", err)
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var group string
+		var bucket int64
+		var value float64
+		if err := result.Scan(&group, &bucket, &value); err != nil {
+			return fmt.Errorf("error scanning sparkline row: %v", err)
+		}
+
+		if i, ok := index[group]; ok && bucket >= 0 {
+			rows[i].Sparkline[min(bucket, int64(buckets-1))] += value
+		}
+	}
+
+	return result.Err()
+}
+
+// hoursOfDay returns a row for each hour of the day in order, hours without logs having a zero value
+func hoursOfDay(rows []AggregateRow) []AggregateRow {
+	values := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		values[row.Group] = row.Value
+	}
+
+	hours := make([]AggregateRow, 24)
+	for hour := range hours {
+		group := strconv.Itoa(hour)
+		hours[hour] = AggregateRow{Group: group, Value: values[group]}
+	}
+
+	return hours
+}
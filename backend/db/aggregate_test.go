@@ -0,0 +1,227 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sloggo/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseNoiseWeights(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		shouldError bool
+	}{
+		{"eight weights", "100,50,25,10,5,2,1,0.5", false},
+		{"too few weights", "100,50,25", true},
+		{"negative weight", "100,50,25,10,5,2,1,-1", true},
+		{"invalid weight", "100,50,25,10,5,2,1,abc", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseNoiseWeights(tc.config)
+			if tc.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAggregateNoiseScore(t *testing.T) {
+	originalWeights := noiseWeights
+	defer func() {
+		noiseWeights = originalWeights
+	}()
+	noiseWeights = [8]float64{100, 50, 25, 10, 5, 2, 1, 0.5}
+
+	entries := []struct {
+		hostname string
+		severity uint8
+	}{
+		{"noise-quiet", 6},
+		{"noise-quiet", 6},
+		{"noise-quiet", 7},
+		{"noise-loud", 3},
+		{"noise-loud", 0},
+	}
+
+	for i, e := range entries {
+		err := StoreLog(models.LogEntry{
+			Severity:       e.severity,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       e.hostname,
+			AppName:        "noise-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Noise message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "noise-app"}
+
+	rows, err := Aggregate("noiseScore", "hostname", filters, 10, "UTC")
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+
+	expected := []AggregateRow{
+		{Group: "noise-loud", Value: 110},
+		{Group: "noise-quiet", Value: 2.5},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d", len(expected), len(rows))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(rows[i], expected[i]) {
+			t.Errorf("Group %d: expected %+v, got %+v", i, expected[i], rows[i])
+		}
+	}
+
+	rows, err = Aggregate("count", "hostname", filters, 10, "UTC")
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Group != "noise-quiet" || rows[0].Value != 3 {
+		t.Errorf("Unexpected count aggregate: %+v", rows)
+	}
+
+	if _, err := Aggregate("noiseScore", "msg", filters, 10, "UTC"); err == nil {
+		t.Error("Expected an error for an unsupported group field")
+	}
+}
+
+func TestAggregateHourOfDay(t *testing.T) {
+	timestamps := []time.Time{
+		time.Date(2024, 3, 1, 3, 15, 0, 0, time.UTC),
+		time.Date(2024, 3, 2, 3, 45, 0, 0, time.UTC),
+		time.Date(2024, 3, 2, 22, 0, 0, 0, time.UTC),
+	}
+
+	for i, timestamp := range timestamps {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      timestamp,
+			Hostname:       "hour-host",
+			AppName:        "hour-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Hour message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "hour-app"}
+
+	testCases := []struct {
+		timezone string
+		expected map[string]float64
+	}{
+		{"UTC", map[string]float64{"3": 2, "22": 1}},
+		{"Asia/Tokyo", map[string]float64{"12": 2, "7": 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.timezone, func(t *testing.T) {
+			rows, err := Aggregate("count", "hourOfDay", filters, 1, tc.timezone)
+			if err != nil {
+				t.Fatalf("Failed to aggregate: %v", err)
+			}
+
+			// Every hour is returned in order, regardless of the limit
+			if len(rows) != 24 {
+				t.Fatalf("Expected 24 hours, got %d", len(rows))
+			}
+			for hour, row := range rows {
+				if row.Group != strconv.Itoa(hour) {
+					t.Errorf("Expected hour %d, got %q", hour, row.Group)
+				}
+				if row.Value != tc.expected[row.Group] {
+					t.Errorf("Hour %s: expected %v, got %v", row.Group, tc.expected[row.Group], row.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestSparklines(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+
+	// Minutes of the hour at which each host logs
+	logMinutes := map[string][]int{
+		"spark-busy":  {0, 1, 2, 30, 31, 59},
+		"spark-quiet": {45},
+		"spark-other": {10, 20},
+	}
+	for hostname, minutes := range logMinutes {
+		for _, minute := range minutes {
+			err := StoreLog(models.LogEntry{
+				Severity:  6,
+				Facility:  1,
+				Version:   1,
+				Timestamp: start.Add(time.Duration(minute)*time.Minute + time.Second),
+				Hostname:  hostname,
+				AppName:   "spark-app",
+				ProcID:    "-",
+				MsgID:     "-",
+				Message:   fmt.Sprintf("Sparkline message at minute %d", minute),
+			})
+			if err != nil {
+				t.Fatalf("Failed to store log entry: %v", err)
+			}
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "spark-app", "startDate": start, "endDate": start.Add(time.Hour)}
+
+	rows, err := Aggregate("count", "hostname", filters, 2, "UTC")
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	if err := Sparklines("count", "hostname", filters, rows, 4); err != nil {
+		t.Fatalf("Failed to compute sparklines: %v", err)
+	}
+
+	// Only the top 2 groups are bucketed, in 15 minute buckets
+	expected := []AggregateRow{
+		{Group: "spark-busy", Value: 6, Sparkline: []float64{3, 0, 2, 1}},
+		{Group: "spark-other", Value: 2, Sparkline: []float64{1, 1, 0, 0}},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, rows)
+	}
+
+	// A window is required
+	if err := Sparklines("count", "hostname", map[string]any{"appName": "spark-app"}, rows, 4); err == nil {
+		t.Error("Expected an error without a time window")
+	}
+}
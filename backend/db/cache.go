@@ -0,0 +1,121 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sloggo/utils"
+)
+
+// maxCachedQueries bounds the number of results kept by a query cache
+const maxCachedQueries = 256
+
+// dataVersion is incremented whenever logs are inserted or deleted, invalidating cached results
+var dataVersion atomic.Int64
+
+// facetCache and chartCache hold the results of GetFacets and GetChartData for SLOGGO_FACET_CACHE_SECONDS
+var (
+	facetCache = newQueryCache()
+	chartCache = newQueryCache()
+)
+
+// queryCache keeps query results until they expire or the logs change
+type queryCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+// cachedResult is a query result along with the data version it was computed from
+type cachedResult struct {
+	value     any
+	version   int64
+	expiresAt time.Time
+}
+
+func newQueryCache() *queryCache {
+	return &queryCache{entries: make(map[string]cachedResult)}
+}
+
+// get returns the cached result for the key if it's neither expired nor computed from older logs
+func (c *queryCache) get(key string) (any, bool) {
+	if utils.FacetCacheSeconds <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.entries[key]
+	if !ok || result.version != dataVersion.Load() || time.Now().After(result.expiresAt) {
+		return nil, false
+	}
+
+	return result.value, true
+}
+
+// set caches a result computed from the given data version
+// The version must be read before running the query so that logs inserted meanwhile invalidate the result
+func (c *queryCache) set(key string, value any, version int64) {
+	if utils.FacetCacheSeconds <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop stale results before making room, then everything if the cache is still full
+	if len(c.entries) >= maxCachedQueries {
+		for k, result := range c.entries {
+			if result.version != dataVersion.Load() || now.After(result.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= maxCachedQueries {
+		clear(c.entries)
+	}
+
+	c.entries[key] = cachedResult{
+		value:     value,
+		version:   version,
+		expiresAt: now.Add(time.Duration(utils.FacetCacheSeconds) * time.Second),
+	}
+}
+
+// filtersCacheKey builds a key identifying a filter set, independent of the map iteration order
+func filtersCacheKey(filters map[string]any) string {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	builder := strings.Builder{}
+	for _, key := range keys {
+		builder.WriteString(key)
+		builder.WriteString("=")
+
+		switch value := filters[key].(type) {
+		case *Expression:
+			fmt.Fprintf(&builder, "%q%#v", value.sql, value.args)
+		case []*StructuredDataFilter:
+			for _, filter := range value {
+				fmt.Fprintf(&builder, "%q:%q,", filter.jsonPath, filter.value)
+			}
+		case time.Time:
+			fmt.Fprintf(&builder, "%d", value.UnixNano())
+		default:
+			fmt.Fprintf(&builder, "%#v", value)
+		}
+
+		builder.WriteString(";")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"sloggo/models"
+	"sloggo/utils"
+)
+
+func TestFiltersCacheKey(t *testing.T) {
+	first, err := ParseExpression("severity<=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := ParseExpression("severity<=3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, err := ParseExpression("severity<=4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := filtersCacheKey(map[string]any{"hostname": "edge1", "severity": []int{1, 2}, "expression": first})
+
+	// Equal filters share a key, even when built separately
+	for range 10 {
+		if got := filtersCacheKey(map[string]any{"expression": second, "severity": []int{1, 2}, "hostname": "edge1"}); got != key {
+			t.Fatalf("Expected equal filter sets to share a key, got %q and %q", got, key)
+		}
+	}
+
+	different := []map[string]any{
+		{"hostname": "edge1", "severity": []int{1, 2}, "expression": other},
+		{"hostname": "edge2", "severity": []int{1, 2}, "expression": first},
+		{"hostname": "edge1", "severity": []int{1}, "expression": first},
+		{"hostname": "edge1", "severity": []int{1, 2}},
+	}
+	for _, filters := range different {
+		if got := filtersCacheKey(filters); got == key {
+			t.Errorf("Expected a different key for %v", filters)
+		}
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	original := utils.FacetCacheSeconds
+	defer func() { utils.FacetCacheSeconds = original }()
+	utils.FacetCacheSeconds = 60
+
+	cache := newQueryCache()
+	cache.set("key", 42, dataVersion.Load())
+
+	if value, ok := cache.get("key"); !ok || value != 42 {
+		t.Fatalf("Expected a cache hit, got %v, %t", value, ok)
+	}
+	if _, ok := cache.get("other"); ok {
+		t.Error("Expected a cache miss for an unknown key")
+	}
+
+	// New logs invalidate the cached results
+	dataVersion.Add(1)
+	if _, ok := cache.get("key"); ok {
+		t.Error("Expected a cache miss after the logs changed")
+	}
+
+	// Expired results are not returned
+	cache.set("key", 42, dataVersion.Load())
+	cache.entries["key"] = cachedResult{value: 42, version: dataVersion.Load(), expiresAt: time.Now().Add(-time.Second)}
+	if _, ok := cache.get("key"); ok {
+		t.Error("Expected a cache miss after expiry")
+	}
+
+	// A result computed before the logs changed is never cached as current
+	version := dataVersion.Load()
+	dataVersion.Add(1)
+	cache.set("key", 42, version)
+	if _, ok := cache.get("key"); ok {
+		t.Error("Expected a cache miss for a result computed from older logs")
+	}
+
+	// The cache is disabled with a zero TTL
+	utils.FacetCacheSeconds = 0
+	cache.set("disabled", 42, dataVersion.Load())
+	if _, ok := cache.get("disabled"); ok {
+		t.Error("Expected no caching with a zero TTL")
+	}
+}
+
+func TestGetFacetsCacheInvalidatedByInserts(t *testing.T) {
+	original := utils.FacetCacheSeconds
+	defer func() { utils.FacetCacheSeconds = original }()
+	utils.FacetCacheSeconds = 60
+
+	filters := map[string]any{"hostname": "cache-host"}
+
+	facets, err := GetFacets(filters, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+	if len(facets["severity"].Rows) != 0 {
+		t.Fatalf("Expected no severity facet rows, got %v", facets["severity"].Rows)
+	}
+
+	err = StoreLog(models.LogEntry{
+		Severity:       4,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "cache-host",
+		AppName:        "cache-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Cache invalidation message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	facets, err = GetFacets(filters, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+	if len(facets["severity"].Rows) != 1 || facets["severity"].Rows[0].Total != 1 {
+		t.Errorf("Expected the new log in the facets, got %v", facets["severity"].Rows)
+	}
+}
+
+func TestGetChartDataCacheIgnoresMilliseconds(t *testing.T) {
+	original := utils.FacetCacheSeconds
+	defer func() { utils.FacetCacheSeconds = original }()
+	utils.FacetCacheSeconds = 60
+
+	// Two default ranges requested a few milliseconds apart, within the same hour
+	cursor := time.Now().Truncate(time.Hour).Add(-30 * time.Minute)
+	filters := map[string]any{"appName": "chart-cache-app"}
+
+	if _, err := GetChartData(cursor, filters, "absolute", nil); err != nil {
+		t.Fatalf("Failed to get chart data: %v", err)
+	}
+
+	// Mark the cached points to tell a cache hit from a new query
+	chartCache.mu.Lock()
+	for key, result := range chartCache.entries {
+		result.value = []ChartDataPoint{{Timestamp: 42}}
+		chartCache.entries[key] = result
+	}
+	chartCache.mu.Unlock()
+
+	points, err := GetChartData(cursor.Add(5*time.Millisecond), filters, "absolute", nil)
+	if err != nil {
+		t.Fatalf("Failed to get chart data: %v", err)
+	}
+	if len(points) != 1 || points[0].Timestamp != 42 {
+		t.Errorf("Expected the second request to hit the cache, got %+v", points)
+	}
+}
+
+func TestTruncateToBucket(t *testing.T) {
+	at := time.Date(2024, time.May, 16, 13, 45, 30, 123456789, time.UTC) // A Thursday
+
+	tests := []struct {
+		unit     string
+		expected time.Time
+	}{
+		{"minute", time.Date(2024, time.May, 16, 13, 45, 0, 0, time.UTC)},
+		{"hour", time.Date(2024, time.May, 16, 13, 0, 0, 0, time.UTC)},
+		{"day", time.Date(2024, time.May, 16, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC)},
+		{"month", time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		if got := truncateToBucket(at, tc.unit); !got.Equal(tc.expected) {
+			t.Errorf("truncateToBucket(%v, %q) = %v, want %v", at, tc.unit, got, tc.expected)
+		}
+	}
+}
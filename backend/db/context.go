@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sloggo/models"
+	"strings"
+	"time"
+)
+
+// ErrLogNotFound is returned when the requested log doesn't exist
+var ErrLogNotFound = errors.New("log not found")
+
+// LogContext holds the logs surrounding an anchor log
+type LogContext struct {
+	Before []models.LogEntry
+	Anchor models.LogEntry
+	After  []models.LogEntry
+}
+
+// GetContext retrieves the logs before and after the anchor log, ordered by timestamp
+// The surrounding logs are scoped to the hostname and app name of the anchor, on top of the given filters
+func GetContext(id int64, before int, after int, filters map[string]any) (*LogContext, error) {
+	rows, err := db.Query("SELECT "+logColumns+" FROM "+tableOf(filters)+" WHERE rowid = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying log: %v", err)
+	}
+	anchors, err := scanLogEntries(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(anchors) == 0 {
+		return nil, ErrLogNotFound
+	}
+
+	anchor := anchors[0]
+
+	contextFilters := make(map[string]any, len(filters)+2)
+	for k, v := range filters {
+		contextFilters[k] = v
+	}
+	contextFilters["hostname"] = anchor.Hostname
+	contextFilters["appName"] = anchor.AppName
+
+	beforeLogs, err := queryContextSide(anchor, contextFilters, before, "<", "DESC")
+	if err != nil {
+		return nil, err
+	}
+	// Fetched closest first, return them in timestamp order
+	slices.Reverse(beforeLogs)
+
+	afterLogs, err := queryContextSide(anchor, contextFilters, after, ">", "ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogContext{Before: beforeLogs, Anchor: anchor, After: afterLogs}, nil
+}
+
+// queryContextSide fetches up to limit logs on one side of the anchor, closest first
+// Logs sharing the anchor timestamp are ordered by rowid so each one appears on a single side
+func queryContextSide(anchor models.LogEntry, filters map[string]any, limit int, operator string, order string) ([]models.LogEntry, error) {
+	if limit <= 0 {
+		return []models.LogEntry{}, nil
+	}
+
+	args := []any{}
+	conditions := []string{}
+
+	if whereClause := buildWhereClause(filters, time.Time{}, "", &args); whereClause != "" {
+		conditions = append(conditions, whereClause)
+	}
+
+	timestamp := anchor.Timestamp.Format(time.RFC3339Nano)
+	conditions = append(conditions, fmt.Sprintf("(timestamp %s ? OR (timestamp = ? AND rowid %s ?))", operator, operator))
+	args = append(args, timestamp, timestamp, anchor.RowID)
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY timestamp %s, rowid %s LIMIT %d",
+		logColumns, tableOf(filters), strings.Join(conditions, " AND "), order, order, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying log context: %v", err)
+	}
+	defer rows.Close()
+
+	return scanLogEntries(rows)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestGetContext(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+
+	store := func(offset int, hostname string, message string) {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      base.Add(time.Duration(offset) * time.Second),
+			Hostname:       hostname,
+			AppName:        "context-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        message,
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	for i := range 5 {
+		store(i, "context-host", fmt.Sprintf("Context message %d", i))
+		// Logs from another host are interleaved and must not appear in the context
+		store(i, "context-other", fmt.Sprintf("Other message %d", i))
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var anchorID int64
+	err := GetDBInstance().QueryRow("SELECT rowid FROM logs WHERE msg = ?", "Context message 2").Scan(&anchorID)
+	if err != nil {
+		t.Fatalf("Failed to find anchor: %v", err)
+	}
+
+	logContext, err := GetContext(anchorID, 1, 5, map[string]any{})
+	if err != nil {
+		t.Fatalf("Failed to get context: %v", err)
+	}
+
+	messages := func(logs []models.LogEntry) []string {
+		result := []string{}
+		for _, entry := range logs {
+			result = append(result, entry.Message)
+		}
+		return result
+	}
+
+	if got := messages(logContext.Before); fmt.Sprint(got) != fmt.Sprint([]string{"Context message 1"}) {
+		t.Errorf("Unexpected logs before: %v", got)
+	}
+	if logContext.Anchor.Message != "Context message 2" {
+		t.Errorf("Unexpected anchor: %q", logContext.Anchor.Message)
+	}
+	if got := messages(logContext.After); fmt.Sprint(got) != fmt.Sprint([]string{"Context message 3", "Context message 4"}) {
+		t.Errorf("Unexpected logs after: %v", got)
+	}
+
+	if _, err := GetContext(-1, 1, 1, map[string]any{}); !errors.Is(err, ErrLogNotFound) {
+		t.Errorf("Expected ErrLogNotFound, got %v", err)
+	}
+}
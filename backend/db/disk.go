@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sloggo/models"
+	"sloggo/utils"
+	"sync/atomic"
+	"time"
+)
+
+// diskCheckInterval is how often the free space of the database volume is checked
+const diskCheckInterval = time.Minute
+
+// diskPressureTrimPercent is the share of the oldest logs of each table deleted when space becomes low
+const diskPressureTrimPercent = 10
+
+// diskPressureKeepRows is the number of logs of each table never trimmed for disk space, replaced in tests
+// Low space may come from other files on the volume, the logs must not be wiped for them
+var diskPressureKeepRows int64 = 100000
+
+var (
+	// databaseDir is the directory of the database file, empty for the in-memory test database
+	databaseDir string
+
+	// lowDiskSpace is set while the database volume has less free space than SLOGGO_MIN_FREE_DISK_MB
+	lowDiskSpace atomic.Bool
+
+	// droppedLowDisk counts the debug logs dropped while disk space is low
+	droppedLowDisk = expvar.NewInt("droppedLowDisk")
+
+	// freeDiskSpace returns the bytes available in a directory, replaced in tests
+	freeDiskSpace = diskFreeBytes
+)
+
+// IsLowDiskSpace reports whether the database volume is below SLOGGO_MIN_FREE_DISK_MB
+func IsLowDiskSpace() bool {
+	return lowDiskSpace.Load()
+}
+
+// monitorDiskSpace checks the free space of the database volume on a timer, until the context is done
+func monitorDiskSpace(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := checkDiskSpace(databaseDir); err != nil {
+				log.Printf("Error checking disk space: %v", err)
+			}
+		}
+	}
+}
+
+// checkDiskSpace compares the free space of a directory to SLOGGO_MIN_FREE_DISK_MB
+// Once it falls below, the oldest logs are trimmed a single time and debug logs are dropped at ingest
+// until space is available again, so DuckDB writes don't fail once the volume is full.
+// Trimming isn't repeated while space stays low, deleted rows don't give space back to the volume.
+func checkDiskSpace(dir string) error {
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return err
+	}
+
+	minFree := uint64(utils.MinFreeDiskMB) * 1024 * 1024
+	if free >= minFree {
+		if lowDiskSpace.Swap(false) {
+			log.Printf("Disk space recovered, %d MB free on %s", free/1024/1024, dir)
+		}
+		return nil
+	}
+
+	if lowDiskSpace.Swap(true) {
+		// Already trimmed when space became low
+		return nil
+	}
+
+	log.Printf("WARNING: low disk space, only %d MB free on %s, below SLOGGO_MIN_FREE_DISK_MB=%d. Trimming the oldest logs once and dropping debug logs until space is available", free/1024/1024, dir, utils.MinFreeDiskMB)
+
+	trimmed := false
+	for _, table := range tables {
+		rows, err := trimOldestLogs(table, diskPressureTrimPercent, diskPressureKeepRows)
+		if err != nil {
+			return err
+		}
+		trimmed = trimmed || rows > 0
+	}
+
+	// Write the deletions to the database file so the WAL doesn't keep growing
+	if trimmed {
+		if _, err := db.Exec("CHECKPOINT"); err != nil {
+			return fmt.Errorf("error running checkpoint: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// trimOldestLogs deletes the given percentage of the oldest logs of a table, keeping at least keepRows logs
+// It returns the number of deleted logs
+func trimOldestLogs(table string, percent int, keepRows int64) (int64, error) {
+	var count int64
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting logs of %s: %v", table, err)
+	}
+
+	trim := min(count*int64(percent)/100, count-keepRows)
+	if trim <= 0 {
+		return 0, nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE rowid IN (SELECT rowid FROM %s ORDER BY timestamp ASC LIMIT %d)", table, table, trim)
+
+	result, err := db.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("error trimming logs of %s: %v", table, err)
+	}
+	dataVersion.Add(1)
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil
+	}
+	if rowsAffected > 0 {
+		log.Printf("Trimmed %d oldest log entries of %s to free disk space", rowsAffected, table)
+	}
+
+	return rowsAffected, nil
+}
+
+// droppedForDiskSpace reports whether a log is dropped at ingest because disk space is low
+// Only debug logs are dropped, the more severe ones are still stored
+func droppedForDiskSpace(entry models.LogEntry) bool {
+	if !lowDiskSpace.Load() || entry.Severity < 7 {
+		return false
+	}
+
+	droppedLowDisk.Add(1)
+	return true
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package db
+
+import "errors"
+
+// diskFreeBytes isn't supported on this platform, SLOGGO_MIN_FREE_DISK_MB has no effect
+func diskFreeBytes(dir string) (uint64, error) {
+	return 0, errors.New("disk space checks are not supported on this platform")
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"sloggo/models"
+	"sloggo/utils"
+	"testing"
+	"time"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	originalMinFree := utils.MinFreeDiskMB
+	originalFree := freeDiskSpace
+	originalKeepRows := diskPressureKeepRows
+	defer func() {
+		utils.MinFreeDiskMB = originalMinFree
+		freeDiskSpace = originalFree
+		diskPressureKeepRows = originalKeepRows
+		lowDiskSpace.Store(false)
+	}()
+	utils.MinFreeDiskMB = 100
+	diskPressureKeepRows = 15
+
+	if _, err := db.Exec("DELETE FROM logs"); err != nil {
+		t.Fatalf("Failed to clean database: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	for i := range 20 {
+		err := StoreLog(models.LogEntry{
+			Severity:  6,
+			Facility:  1,
+			Version:   1,
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Hostname:  "disk-host",
+			AppName:   "disk-app",
+			ProcID:    "-",
+			MsgID:     "-",
+			Message:   "Disk message",
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	// Plenty of space, nothing changes
+	freeDiskSpace = func(string) (uint64, error) { return 1024 * 1024 * 1024, nil }
+	if err := checkDiskSpace(""); err != nil {
+		t.Fatalf("Failed to check disk space: %v", err)
+	}
+	if IsLowDiskSpace() || !IsReady() {
+		t.Error("Expected enough disk space to keep the database ready")
+	}
+
+	// Low space trims the oldest logs and fails readiness
+	freeDiskSpace = func(string) (uint64, error) { return 10 * 1024 * 1024, nil }
+	if err := checkDiskSpace(""); err != nil {
+		t.Fatalf("Failed to check disk space: %v", err)
+	}
+	if !IsLowDiskSpace() || IsReady() {
+		t.Error("Expected low disk space to fail readiness")
+	}
+
+	var count int
+	var oldest time.Time
+	if err := db.QueryRow("SELECT COUNT(*), MIN(timestamp) FROM logs").Scan(&count, &oldest); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 18 {
+		t.Errorf("Expected the oldest 10%% of the logs to be trimmed, %d remain", count)
+	}
+	if !oldest.Equal(base.Add(2 * time.Second).Truncate(time.Microsecond)) {
+		t.Errorf("Expected the oldest logs to be trimmed first, the oldest remaining is %v", oldest)
+	}
+
+	// Space staying low doesn't trim again, the deleted rows don't give space back
+	for range 3 {
+		if err := checkDiskSpace(""); err != nil {
+			t.Fatalf("Failed to check disk space: %v", err)
+		}
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&count); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 18 {
+		t.Errorf("Expected no trim while space stays low, %d logs remain", count)
+	}
+
+	// Debug logs are dropped, the others still stored
+	droppedBefore := droppedLowDisk.Value()
+	for _, severity := range []uint8{7, 3} {
+		entry := models.LogEntry{Severity: severity, Facility: 1, Timestamp: time.Now(), Hostname: "disk-host", AppName: "disk-app", Message: "Under pressure"}
+		if err := StoreLog(entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if dropped := droppedLowDisk.Value() - droppedBefore; dropped != 1 {
+		t.Errorf("Expected 1 debug log to be dropped, got %d", dropped)
+	}
+
+	// Readiness recovers with the space
+	freeDiskSpace = func(string) (uint64, error) { return 1024 * 1024 * 1024, nil }
+	if err := checkDiskSpace(""); err != nil {
+		t.Fatalf("Failed to check disk space: %v", err)
+	}
+	if IsLowDiskSpace() || !IsReady() {
+		t.Error("Expected the database to be ready once disk space is available")
+	}
+
+	// Every new low space transition trims again, down to the kept rows at most
+	for range 3 {
+		freeDiskSpace = func(string) (uint64, error) { return 10 * 1024 * 1024, nil }
+		if err := checkDiskSpace(""); err != nil {
+			t.Fatalf("Failed to check disk space: %v", err)
+		}
+		freeDiskSpace = func(string) (uint64, error) { return 1024 * 1024 * 1024, nil }
+		if err := checkDiskSpace(""); err != nil {
+			t.Fatalf("Failed to check disk space: %v", err)
+		}
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&count); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if int64(count) != diskPressureKeepRows {
+		t.Errorf("Expected the trims to stop at %d logs, %d remain", diskPressureKeepRows, count)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package db
+
+import "syscall"
+
+// diskFreeBytes returns the bytes available to unprivileged users on the volume of a directory
+func diskFreeBytes(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
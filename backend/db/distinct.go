@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/utils"
+	"strings"
+	"time"
+)
+
+// distinctColumns maps the fields whose distinct values can be listed to their database columns
+var distinctColumns = map[string]string{
+	"hostname": "hostname",
+	"appName":  "app_name",
+	"procId":   "procid",
+	"msgId":    "msgid",
+}
+
+// facetExclusions holds the values omitted from the facet and distinct values of a field, keyed by field
+// Logs with these values are still stored and can be filtered on
+var facetExclusions = map[string][]string{}
+
+func init() {
+	setFacetExclusions("appName", utils.FacetExcludeAppName)
+}
+
+// setFacetExclusions sets the comma-separated values omitted from the listed values of a field, such as "healthcheck,kube-probe"
+func setFacetExclusions(field string, config string) {
+	var values []string
+	for value := range strings.SplitSeq(config, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	if len(values) == 0 {
+		delete(facetExclusions, field)
+		return
+	}
+	facetExclusions[field] = values
+}
+
+// exclusionCondition returns the condition omitting the excluded values of a field, empty when none is excluded
+func exclusionCondition(field string, column string, args *[]any) string {
+	values := facetExclusions[field]
+	if len(values) == 0 {
+		return ""
+	}
+
+	for _, value := range values {
+		*args = append(*args, value)
+	}
+	return fmt.Sprintf("%s NOT IN (?%s)", column, strings.Repeat(", ?", len(values)-1))
+}
+
+// IsDistinctField reports whether the distinct values of the given field can be listed
+func IsDistinctField(field string) bool {
+	_, ok := distinctColumns[field]
+	return ok
+}
+
+// Distinct retrieves up to limit distinct values of a field, sorted alphabetically
+// It reports whether more values exist beyond the limit
+func Distinct(field string, filters map[string]any, limit int) ([]string, bool, error) {
+	column, ok := distinctColumns[field]
+	if !ok {
+		return nil, false, fmt.Errorf("unsupported distinct field %q", field)
+	}
+
+	queryBuilder := strings.Builder{}
+	args := []any{}
+
+	queryBuilder.WriteString(fmt.Sprintf("SELECT DISTINCT %s FROM %s", column, tableOf(filters)))
+
+	whereClause := buildWhereClause(filters, time.Time{}, "", &args)
+	if exclusion := exclusionCondition(field, column, &args); exclusion != "" {
+		if whereClause != "" {
+			whereClause += " AND "
+		}
+		whereClause += exclusion
+	}
+	if whereClause != "" {
+		queryBuilder.WriteString(" WHERE ")
+		queryBuilder.WriteString(whereClause)
+	}
+
+	// Fetch one more value to know whether the result is truncated
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s ASC LIMIT %d", column, limit+1))
+
+	rows, err := db.Query(queryBuilder.String(), args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("error querying distinct values: %v", err)
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, false, fmt.Errorf("error scanning distinct value: %v", err)
+		}
+		values = append(values, value)
+	}
+
+	if len(values) > limit {
+		return values[:limit], true, nil
+	}
+
+	return values, false, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestDistinct(t *testing.T) {
+	for i, hostname := range []string{"distinct-c", "distinct-a", "distinct-b", "distinct-a"} {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       hostname,
+			AppName:        "distinct-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Distinct message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "distinct-app"}
+
+	values, truncated, err := Distinct("hostname", filters, 10)
+	if err != nil {
+		t.Fatalf("Failed to get distinct values: %v", err)
+	}
+	if fmt.Sprint(values) != fmt.Sprint([]string{"distinct-a", "distinct-b", "distinct-c"}) || truncated {
+		t.Errorf("Unexpected distinct values: %v (truncated: %t)", values, truncated)
+	}
+
+	values, truncated, err = Distinct("hostname", filters, 2)
+	if err != nil {
+		t.Fatalf("Failed to get distinct values: %v", err)
+	}
+	if len(values) != 2 || !truncated {
+		t.Errorf("Expected 2 truncated values, got %v (truncated: %t)", values, truncated)
+	}
+
+	// Excluded values are omitted from the listed values, but still match filters
+	setFacetExclusions("hostname", " distinct-b, ")
+	defer setFacetExclusions("hostname", "")
+
+	values, _, err = Distinct("hostname", filters, 10)
+	if err != nil {
+		t.Fatalf("Failed to get distinct values: %v", err)
+	}
+	if fmt.Sprint(values) != fmt.Sprint([]string{"distinct-a", "distinct-c"}) {
+		t.Errorf("Expected the excluded hostname to be omitted, got %v", values)
+	}
+
+	values, _, err = Distinct("hostname", map[string]any{"appName": "distinct-app", "hostname": "distinct-b"}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get distinct values: %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("Expected no listed value for an excluded hostname, got %v", values)
+	}
+
+	var count int
+	if err := GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = 'distinct-b'").Scan(&count); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if count == 0 {
+		t.Error("Expected logs with an excluded value to still be stored")
+	}
+
+	if _, _, err := Distinct("msg", filters, 10); err == nil {
+		t.Error("Expected an error for an unsupported field")
+	}
+}
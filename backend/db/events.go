@@ -0,0 +1,72 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/models"
+	"time"
+)
+
+// EventHost counts the logs of an event type sent by a host
+type EventHost struct {
+	Hostname string    `json:"hostname"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// EventSummary correlates the logs sharing a MsgID across hosts
+type EventSummary struct {
+	Total  int64             // Logs of the event type, all hosts included
+	Hosts  []EventHost       // Hosts by decreasing count, up to the host limit
+	Sample []models.LogEntry // Most recent logs of the event type
+}
+
+// GetEvents counts the logs with the given MsgID per host and fetches the most recent ones
+// Up to hostLimit hosts are returned, the most active first, the filters apply on top of the MsgID
+func GetEvents(msgID string, filters map[string]any, hostLimit int, sampleSize int) (*EventSummary, error) {
+	eventFilters := make(map[string]any, len(filters)+1)
+	for k, v := range filters {
+		eventFilters[k] = v
+	}
+	eventFilters["msgId"] = msgID
+
+	args := []any{}
+	whereClause := buildWhereClause(eventFilters, time.Time{}, "", &args)
+	table := tableOf(eventFilters)
+
+	// The total over every host is computed alongside the groups, before the limit applies
+	query := fmt.Sprintf(`SELECT hostname, COUNT(*) AS count, MAX(timestamp) AS last_seen, CAST(SUM(COUNT(*)) OVER () AS BIGINT) AS total
+		FROM %s WHERE %s GROUP BY hostname ORDER BY count DESC, hostname ASC LIMIT %d`, table, whereClause, hostLimit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying event hosts: %v", err)
+	}
+	defer rows.Close()
+
+	summary := &EventSummary{Hosts: []EventHost{}}
+	for rows.Next() {
+		var host EventHost
+		if err := rows.Scan(&host.Hostname, &host.Count, &host.LastSeen, &summary.Total); err != nil {
+			return nil, fmt.Errorf("error scanning event host: %v", err)
+		}
+		summary.Hosts = append(summary.Hosts, host)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading event hosts: %v", err)
+	}
+
+	sampleQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY timestamp DESC, rowid DESC LIMIT %d", logColumns, table, whereClause, sampleSize)
+
+	sampleRows, err := db.Query(sampleQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying event sample: %v", err)
+	}
+	defer sampleRows.Close()
+
+	summary.Sample, err = scanLogEntries(sampleRows)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestGetEvents(t *testing.T) {
+	now := time.Now()
+	logs := []struct {
+		hostname string
+		msgID    string
+		age      time.Duration
+	}{
+		{"events-a", "EVT_LOGIN", 3 * time.Minute},
+		{"events-a", "EVT_LOGIN", 2 * time.Minute},
+		{"events-b", "EVT_LOGIN", time.Minute},
+		{"events-b", "EVT_LOGOUT", 0},
+		{"events-c", "EVT_LOGIN", 2 * time.Hour},
+	}
+
+	for i, l := range logs {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(-l.age),
+			Hostname:       l.hostname,
+			AppName:        "events-app",
+			ProcID:         "-",
+			MsgID:          l.msgID,
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Event %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "events-app", "startDate": now.Add(-time.Hour)}
+
+	summary, err := GetEvents("EVT_LOGIN", filters, 10, 2)
+	if err != nil {
+		t.Fatalf("Failed to get events: %v", err)
+	}
+
+	if summary.Total != 3 {
+		t.Errorf("Expected 3 events within the hour, got %d", summary.Total)
+	}
+	if len(summary.Hosts) != 2 || summary.Hosts[0].Hostname != "events-a" || summary.Hosts[0].Count != 2 || summary.Hosts[1].Count != 1 {
+		t.Errorf("Expected the hosts by decreasing count, got %+v", summary.Hosts)
+	}
+	if len(summary.Sample) != 2 || summary.Sample[0].Message != "Event 2" || summary.Sample[1].Message != "Event 1" {
+		t.Errorf("Expected the 2 most recent events, got %+v", summary.Sample)
+	}
+
+	// The total covers every host, beyond the host limit
+	summary, err = GetEvents("EVT_LOGIN", filters, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to get events: %v", err)
+	}
+	if summary.Total != 3 || len(summary.Hosts) != 1 || len(summary.Sample) != 0 {
+		t.Errorf("Expected 1 host and the total of every host, got %d hosts and a total of %d", len(summary.Hosts), summary.Total)
+	}
+
+	summary, err = GetEvents("EVT_UNKNOWN", filters, 10, 5)
+	if err != nil {
+		t.Fatalf("Failed to get events: %v", err)
+	}
+	if summary.Total != 0 || len(summary.Hosts) != 0 || len(summary.Sample) != 0 {
+		t.Errorf("Expected no events, got %+v", summary)
+	}
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxExpressionDepth bounds the nesting of parentheses and NOT operators in an expression
+const maxExpressionDepth = 32
+
+// Expression is a filter expression compiled into a parameterized SQL condition
+type Expression struct {
+	sql  string
+	args []any
+}
+
+// expressionField describes a field that can be referenced in an expression
+type expressionField struct {
+	column  string
+	numeric bool
+}
+
+// expressionFields maps the API field names usable in expressions to their database columns
+var expressionFields = map[string]expressionField{
+	"severity": {column: "severity", numeric: true},
+	"facility": {column: "facility", numeric: true},
+	"priority": {column: priorityColumn, numeric: true},
+	"hostname": {column: "hostname"},
+	"appName":  {column: "app_name"},
+	"procId":   {column: "procid"},
+	"msgId":    {column: "msgid"},
+	"message":  {column: "msg"},
+}
+
+// expressionOperators lists the supported comparison operators
+var expressionOperators = map[string]bool{
+	"=":  true,
+	"!=": true,
+	"<":  true,
+	"<=": true,
+	">":  true,
+	">=": true,
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// ParseExpression compiles a filter expression such as
+// "(severity<=3 AND appName=db) OR hostname=edge1" into a parameterized SQL condition
+// Only known fields, comparison operators, AND/OR/NOT and parentheses are accepted,
+// values are always passed as query arguments
+func ParseExpression(input string) (*Expression, error) {
+	tokens, err := tokenizeExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty expression")
+	}
+
+	parser := &expressionParser{tokens: tokens}
+
+	sql, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q", parser.tokens[parser.pos].value)
+	}
+
+	return &Expression{sql: sql, args: parser.args}, nil
+}
+
+// tokenizeExpression splits an expression into words, quoted strings, operators and parentheses
+func tokenizeExpression(input string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, value: ")"})
+			i++
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			operator := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				operator += "="
+			}
+			if !expressionOperators[operator] {
+				return nil, fmt.Errorf("unknown operator %q", operator)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: operator})
+			i += len(operator)
+		case r == '"' || r == '\'':
+			value := strings.Builder{}
+			closed := false
+
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					value.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == r {
+					closed = true
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+			}
+
+			if !closed {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value.String()})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()=!<>"'`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[start:i])})
+		}
+	}
+
+	return tokens, nil
+}
+
+// expressionParser is a recursive descent parser, NOT binds tighter than AND, which binds tighter than OR
+type expressionParser struct {
+	tokens []token
+	pos    int
+	args   []any
+}
+
+// peekKeyword reports whether the next token is the given case-insensitive keyword
+func (p *expressionParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].value, keyword)
+}
+
+func (p *expressionParser) parseOr(depth int) (string, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return "", err
+	}
+
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s OR %s)", left, right)
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd(depth int) (string, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return "", err
+	}
+
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s AND %s)", left, right)
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseNot(depth int) (string, error) {
+	if depth > maxExpressionDepth {
+		return "", errors.New("expression is nested too deeply")
+	}
+
+	if p.peekKeyword("not") {
+		p.pos++
+		operand, err := p.parseNot(depth + 1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(NOT %s)", operand), nil
+	}
+
+	return p.parsePrimary(depth)
+}
+
+func (p *expressionParser) parsePrimary(depth int) (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("unexpected end of expression")
+	}
+
+	current := p.tokens[p.pos]
+
+	switch current.kind {
+	case tokenOpen:
+		p.pos++
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return "", err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenClose {
+			return "", errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case tokenWord:
+		return p.parseComparison()
+	default:
+		return "", fmt.Errorf("unexpected %q", current.value)
+	}
+}
+
+// parseComparison parses "field operator value" into a parameterized condition
+func (p *expressionParser) parseComparison() (string, error) {
+	name := p.tokens[p.pos].value
+	p.pos++
+
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOpen {
+		return "", fmt.Errorf("functions are not supported: %q", name)
+	}
+
+	field, ok := expressionFields[name]
+	if !ok {
+		return "", fmt.Errorf("unknown field %q", name)
+	}
+
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", fmt.Errorf("expected an operator after %q", name)
+	}
+	operator := p.tokens[p.pos].value
+	p.pos++
+
+	if p.pos >= len(p.tokens) || (p.tokens[p.pos].kind != tokenWord && p.tokens[p.pos].kind != tokenString) {
+		return "", fmt.Errorf("expected a value after %s%s", name, operator)
+	}
+	value := p.tokens[p.pos].value
+	p.pos++
+
+	if field.numeric {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("%s expects an integer, got %q", name, value)
+		}
+		p.args = append(p.args, number)
+	} else {
+		p.args = append(p.args, value)
+	}
+
+	return fmt.Sprintf("%s %s ?", field.column, operator), nil
+}
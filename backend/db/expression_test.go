@@ -0,0 +1,156 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestParseExpression(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		expectedSQL  string
+		expectedArgs []any
+		shouldError  bool
+	}{
+		{
+			name:         "single comparison",
+			input:        "severity<=3",
+			expectedSQL:  "severity <= ?",
+			expectedArgs: []any{3},
+		},
+		{
+			name:         "compound expression",
+			input:        "(severity<=3 AND appName=db) OR hostname=edge1",
+			expectedSQL:  "((severity <= ? AND app_name = ?) OR hostname = ?)",
+			expectedArgs: []any{3, "db", "edge1"},
+		},
+		{
+			name:         "AND binds tighter than OR",
+			input:        "hostname=a or hostname=b and severity=0",
+			expectedSQL:  "(hostname = ? OR (hostname = ? AND severity = ?))",
+			expectedArgs: []any{"a", "b", 0},
+		},
+		{
+			name:         "NOT and quoted value",
+			input:        `NOT message = "disk full"`,
+			expectedSQL:  "(NOT msg = ?)",
+			expectedArgs: []any{"disk full"},
+		},
+		{
+			name:         "quoted value stays an argument",
+			input:        `appName = "x' OR 1=1 --"`,
+			expectedSQL:  "app_name = ?",
+			expectedArgs: []any{"x' OR 1=1 --"},
+		},
+		{
+			name:         "escaped quote in value",
+			input:        `appName != 'it\'s'`,
+			expectedSQL:  "app_name != ?",
+			expectedArgs: []any{"it's"},
+		},
+		{name: "unknown field", input: "app_name=db", shouldError: true},
+		{name: "function call", input: "lower(hostname)=edge1", shouldError: true},
+		{name: "non numeric severity", input: "severity=error", shouldError: true},
+		{name: "missing operator", input: "hostname edge1", shouldError: true},
+		{name: "missing value", input: "hostname=", shouldError: true},
+		{name: "unknown operator", input: "hostname ! edge1", shouldError: true},
+		{name: "unbalanced parentheses", input: "(severity=3", shouldError: true},
+		{name: "trailing tokens", input: "severity=3 hostname=edge1", shouldError: true},
+		{name: "unterminated string", input: `hostname="edge1`, shouldError: true},
+		{name: "empty expression", input: "   ", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expression, err := ParseExpression(tc.input)
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", expression.sql)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if expression.sql != tc.expectedSQL {
+				t.Errorf("SQL mismatch: got %q, want %q", expression.sql, tc.expectedSQL)
+			}
+			if !reflect.DeepEqual(expression.args, tc.expectedArgs) {
+				t.Errorf("args mismatch: got %v, want %v", expression.args, tc.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestParseExpressionDepth(t *testing.T) {
+	input := ""
+	for range maxExpressionDepth + 2 {
+		input += "("
+	}
+	input += "severity=3"
+	for range maxExpressionDepth + 2 {
+		input += ")"
+	}
+
+	if _, err := ParseExpression(input); err == nil {
+		t.Error("expected deeply nested expression to be rejected")
+	}
+}
+
+func TestGetLogsExpression(t *testing.T) {
+	now := time.Now()
+	entries := []struct {
+		severity uint8
+		hostname string
+		appName  string
+	}{
+		{2, "expr-host", "expr-db"},
+		{6, "expr-host", "expr-db"},
+		{6, "expr-edge", "expr-web"},
+		{3, "expr-host", "expr-web"},
+	}
+
+	for i, e := range entries {
+		err := StoreLog(models.LogEntry{
+			Severity:       e.severity,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(time.Duration(i) * time.Millisecond),
+			Hostname:       e.hostname,
+			AppName:        e.appName,
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Expression message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	expression, err := ParseExpression("(severity<=3 AND appName=expr-db) OR hostname=expr-edge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logs, _, _, err := GetLogs(10, time.Time{}, "next", map[string]any{"expression": expression}, "timestamp", "ASC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(logs))
+	}
+	if logs[0].Message != "Expression message 0" || logs[1].Message != "Expression message 2" {
+		t.Errorf("Unexpected logs: %q, %q", logs[0].Message, logs[1].Message)
+	}
+}
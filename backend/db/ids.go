@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IDRange reports the ids of the logs matching filters, to split them into chunks of ids
+type IDRange struct {
+	MinID *int64 `json:"minId"` // Nil without matching logs
+	MaxID *int64 `json:"maxId"` // Nil without matching logs
+	Count int64  `json:"count"`
+}
+
+// GetIDRange returns the lowest and highest ids of the logs matching the filters, and their count
+// Ids are row ids assigned in insertion order, chunks of ids are only stable between cleanups:
+// deleting logs through the retention or disk space trimming may renumber the remaining ones
+func GetIDRange(filters map[string]any) (IDRange, error) {
+	args := []any{}
+
+	queryBuilder := strings.Builder{}
+	queryBuilder.WriteString(fmt.Sprintf("SELECT MIN(rowid), MAX(rowid), COUNT(*) FROM %s", tableOf(filters)))
+
+	if whereClause := buildWhereClause(filters, time.Time{}, "", &args); whereClause != "" {
+		queryBuilder.WriteString(" WHERE ")
+		queryBuilder.WriteString(whereClause)
+	}
+
+	release := acquireQuery()
+	defer release()
+
+	var minID, maxID sql.NullInt64
+	var idRange IDRange
+	if err := db.QueryRow(queryBuilder.String(), args...).Scan(&minID, &maxID, &idRange.Count); err != nil {
+		return IDRange{}, fmt.Errorf("error querying id range: %v", err)
+	}
+
+	if minID.Valid && maxID.Valid {
+		idRange.MinID = &minID.Int64
+		idRange.MaxID = &maxID.Int64
+	}
+
+	return idRange, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestIDRange(t *testing.T) {
+	for i := range 5 {
+		err := StoreLog(models.LogEntry{
+			Severity:  6,
+			Facility:  1,
+			Version:   1,
+			Timestamp: time.Now().Add(time.Duration(i) * time.Second),
+			Hostname:  "chunk-host",
+			AppName:   "chunk-app",
+			ProcID:    "-",
+			MsgID:     "-",
+			Message:   "Chunk message",
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	idRange, err := GetIDRange(map[string]any{"hostname": "chunk-host"})
+	if err != nil {
+		t.Fatalf("Failed to get id range: %v", err)
+	}
+	if idRange.MinID == nil || idRange.MaxID == nil || idRange.Count != 5 || *idRange.MaxID-*idRange.MinID != 4 {
+		t.Fatalf("Expected the range of the 5 logs, got %+v", idRange)
+	}
+
+	// Two chunks cover every log exactly once
+	seen := map[int64]bool{}
+	for _, chunk := range [][2]int64{{*idRange.MinID, *idRange.MinID + 2}, {*idRange.MinID + 3, *idRange.MaxID}} {
+		filters := map[string]any{"hostname": "chunk-host", "fromId": chunk[0], "toId": chunk[1]}
+		logs, _, _, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "ASC")
+		if err != nil {
+			t.Fatalf("Failed to get logs: %v", err)
+		}
+
+		for _, entry := range logs {
+			if entry.RowID < chunk[0] || entry.RowID > chunk[1] || seen[entry.RowID] {
+				t.Errorf("Unexpected log %d in chunk %v", entry.RowID, chunk)
+			}
+			seen[entry.RowID] = true
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("Expected the chunks to cover the 5 logs, got %d", len(seen))
+	}
+
+	// No matching logs, no range
+	idRange, err = GetIDRange(map[string]any{"hostname": "chunk-missing"})
+	if err != nil {
+		t.Fatalf("Failed to get id range: %v", err)
+	}
+	if idRange.MinID != nil || idRange.MaxID != nil || idRange.Count != 0 {
+		t.Errorf("Expected an empty range, got %+v", idRange)
+	}
+}
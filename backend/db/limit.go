@@ -0,0 +1,44 @@
+package db
+
+import (
+	"expvar"
+	"sloggo/utils"
+)
+
+var (
+	// querySlots bounds the concurrent read queries to SLOGGO_MAX_DB_QUERIES, nil when unlimited
+	querySlots chan struct{}
+
+	// queriesInFlight counts the read queries running or waiting for a slot
+	queriesInFlight = expvar.NewInt("dbQueriesInFlight")
+)
+
+func init() {
+	setMaxQueries(utils.MaxDBQueries)
+}
+
+// setMaxQueries bounds the concurrent read queries, a non-positive limit removes the bound
+func setMaxQueries(limit int64) {
+	querySlots = nil
+	if limit > 0 {
+		querySlots = make(chan struct{}, limit)
+	}
+}
+
+// acquireQuery waits for a read query slot, the returned function releases it
+// Under a dashboard stampede queries queue here instead of thrashing DuckDB
+func acquireQuery() func() {
+	queriesInFlight.Add(1)
+
+	slots := querySlots
+	if slots != nil {
+		slots <- struct{}{}
+	}
+
+	return func() {
+		if slots != nil {
+			<-slots
+		}
+		queriesInFlight.Add(-1)
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireQuery(t *testing.T) {
+	defer setMaxQueries(0)
+	setMaxQueries(1)
+
+	inFlightBefore := queriesInFlight.Value()
+
+	release := acquireQuery()
+
+	acquired := make(chan struct{})
+	go func() {
+		defer acquireQuery()()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second query to wait for a slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if inFlight := queriesInFlight.Value() - inFlightBefore; inFlight != 2 {
+		t.Errorf("Expected 2 queries in flight, got %d", inFlight)
+	}
+
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the second query to run once the slot was released")
+	}
+
+	// Queries still run with the limit, one at a time
+	if _, _, _, err := GetLogs(10, time.Time{}, "next", map[string]any{}, "timestamp", "DESC"); err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+}
+
+func TestAcquireQueryUnlimited(t *testing.T) {
+	setMaxQueries(0)
+
+	releases := []func(){}
+	for range 10 {
+		releases = append(releases, acquireQuery())
+	}
+	for _, release := range releases {
+		release()
+	}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Compact reclaims the space left by deleted logs and refreshes the planner statistics
+// It is worth running after large retention purges
+func Compact() error {
+	startTime := time.Now()
+
+	if _, err := db.Exec("CHECKPOINT"); err != nil {
+		return fmt.Errorf("error running checkpoint: %v", err)
+	}
+
+	if _, err := db.Exec("ANALYZE logs"); err != nil {
+		return fmt.Errorf("error analyzing logs: %v", err)
+	}
+
+	log.Printf("Compacted database in %v", time.Since(startTime))
+	return nil
+}
+
+// ImportParquet inserts the logs of a Parquet file, such as an archive of the logs table, into the database
+// The file must have the columns of the logs table, with the same names, types and order
+// It returns the number of imported logs
+func ImportParquet(path string) (int64, error) {
+	startTime := time.Now()
+
+	expected, err := describeColumns("DESCRIBE logs")
+	if err != nil {
+		return 0, fmt.Errorf("error describing logs: %v", err)
+	}
+
+	actual, err := describeColumns("DESCRIBE SELECT * FROM read_parquet(?)", path)
+	if err != nil {
+		return 0, fmt.Errorf("error reading parquet file: %v", err)
+	}
+
+	if !slices.Equal(actual, expected) {
+		return 0, fmt.Errorf("parquet schema (%s) doesn't match the logs schema (%s)", strings.Join(actual, ", "), strings.Join(expected, ", "))
+	}
+
+	result, err := db.Exec("INSERT INTO logs SELECT * FROM read_parquet(?)", path)
+	if err != nil {
+		return 0, fmt.Errorf("error importing parquet file: %v", err)
+	}
+	dataVersion.Add(1)
+
+	imported, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting imported logs: %v", err)
+	}
+
+	log.Printf("Imported %d logs from %s in %v", imported, path, time.Since(startTime))
+	return imported, nil
+}
+
+// describeColumns returns the "name TYPE" columns of a DESCRIBE query
+func describeColumns(query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultColumns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	for rows.Next() {
+		// Only the name and type are compared, the other DESCRIBE columns are ignored
+		values := make([]sql.NullString, len(resultColumns))
+		pointers := make([]any, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		columns = append(columns, values[0].String+" "+values[1].String)
+	}
+
+	return columns, rows.Err()
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestImportParquet(t *testing.T) {
+	for i := range 3 {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "parquet-host",
+			AppName:        "parquet-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: `{"meta":{"archive":"true"}}`,
+			Message:        fmt.Sprintf("Archived message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	// Archive the logs then delete them, like a retention purge
+	archive := filepath.Join(t.TempDir(), "archive.parquet")
+	if _, err := db.Exec(fmt.Sprintf("COPY (SELECT * FROM logs WHERE hostname = 'parquet-host') TO '%s' (FORMAT PARQUET)", archive)); err != nil {
+		t.Fatalf("Failed to archive logs: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM logs WHERE hostname = 'parquet-host'"); err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+
+	imported, err := ImportParquet(archive)
+	if err != nil {
+		t.Fatalf("Failed to import parquet file: %v", err)
+	}
+	if imported != 3 {
+		t.Errorf("Expected 3 imported logs, got %d", imported)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = 'parquet-host' AND structured_data IS NOT NULL").Scan(&count); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the 3 archived logs back, got %d", count)
+	}
+
+	// A file with other columns is rejected without inserting anything
+	partial := filepath.Join(t.TempDir(), "partial.parquet")
+	if _, err := db.Exec(fmt.Sprintf("COPY (SELECT severity, msg FROM logs WHERE hostname = 'parquet-host') TO '%s' (FORMAT PARQUET)", partial)); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+	if _, err := ImportParquet(partial); err == nil {
+		t.Error("Expected a schema mismatch error")
+	}
+
+	if _, err := ImportParquet(filepath.Join(t.TempDir(), "missing.parquet")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"sloggo/models"
+)
+
+var (
+	// batchSpillPath is the file the pending batch is written to on shutdown with SLOGGO_BATCH_PERSIST=true
+	batchSpillPath string
+
+	// flushLock lets SpillBatch wait for in-flight flushes, flushes share it
+	flushLock sync.RWMutex
+)
+
+// SpillBatch writes the pending batch to disk so it can be restored by the next start
+// It waits for in-flight flushes so their entries are either stored or spilled
+func SpillBatch() error {
+	if batchSpillPath == "" {
+		return errors.New("no batch spill file configured")
+	}
+
+	flushLock.Lock()
+	defer flushLock.Unlock()
+
+	batchLogsMutex.Lock()
+	entries := batchLogs
+	batchLogs = make([]models.LogEntry, 0, maxBatchStoreLogsSize)
+	batchLogsMutex.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Write to a temporary file first so an interrupted spill never leaves a truncated file
+	tmpPath := batchSpillPath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("error creating batch spill file: %v", err)
+	}
+
+	if err := gob.NewEncoder(file).Encode(entries); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing batch spill file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error closing batch spill file: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, batchSpillPath); err != nil {
+		return fmt.Errorf("error renaming batch spill file: %v", err)
+	}
+
+	log.Printf("Persisted %d pending log entries to %s", len(entries), batchSpillPath)
+	return nil
+}
+
+// RestoreBatch re-enqueues the entries spilled by the previous shutdown and removes the spill file
+func RestoreBatch() error {
+	if batchSpillPath == "" {
+		return nil
+	}
+
+	file, err := os.Open(batchSpillPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening batch spill file: %v", err)
+	}
+	defer file.Close()
+
+	var entries []models.LogEntry
+	if err := gob.NewDecoder(file).Decode(&entries); err != nil {
+		return fmt.Errorf("error reading batch spill file: %v", err)
+	}
+
+	// Restored entries were received first, keep them ahead of anything already pending
+	batchLogsMutex.Lock()
+	batchLogs = append(entries, batchLogs...)
+	batchLogsMutex.Unlock()
+
+	if err := os.Remove(batchSpillPath); err != nil {
+		return fmt.Errorf("error removing batch spill file: %v", err)
+	}
+
+	log.Printf("Restored %d log entries persisted on shutdown", len(entries))
+	return nil
+}
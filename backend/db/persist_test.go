@@ -0,0 +1,182 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"sloggo/models"
+)
+
+func TestSpillAndRestoreBatch(t *testing.T) {
+	// Start from an empty batch
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	batchSpillPath = filepath.Join(t.TempDir(), "batch.gob")
+	defer func() { batchSpillPath = "" }()
+
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	entries := []models.LogEntry{
+		{
+			Severity:       3,
+			Facility:       16,
+			Version:        1,
+			Timestamp:      timestamp,
+			Hostname:       "spill-host",
+			AppName:        "spill-app",
+			ProcID:         "42",
+			MsgID:          "ID1",
+			StructuredData: `{"origin":{"ip":"10.0.0.1"}}`,
+			Message:        "Spilled message 1",
+			ReceivedAt:     timestamp,
+		},
+		{
+			Severity:       6,
+			Facility:       1,
+			Timestamp:      timestamp.Add(time.Second),
+			Hostname:       "spill-host",
+			AppName:        "spill-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        "Spilled message 2",
+			ReceivedAt:     timestamp.Add(time.Second),
+		},
+	}
+
+	for _, entry := range entries {
+		if err := StoreLog(entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := SpillBatch(); err != nil {
+		t.Fatalf("Failed to spill batch: %v", err)
+	}
+
+	if stats := GetBatchStats(); stats.PendingEntries != 0 {
+		t.Errorf("Expected an empty batch after spilling, got %d pending entries", stats.PendingEntries)
+	}
+	if _, err := os.Stat(batchSpillPath); err != nil {
+		t.Fatalf("Expected a spill file: %v", err)
+	}
+
+	if err := RestoreBatch(); err != nil {
+		t.Fatalf("Failed to restore batch: %v", err)
+	}
+
+	if _, err := os.Stat(batchSpillPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the spill file to be removed after restoring, got %v", err)
+	}
+
+	batchLogsMutex.Lock()
+	restored := append([]models.LogEntry{}, batchLogs...)
+	batchLogsMutex.Unlock()
+
+	if len(restored) != len(entries) {
+		t.Fatalf("Expected %d restored entries, got %d", len(entries), len(restored))
+	}
+	for i, entry := range entries {
+		if !restored[i].Timestamp.Equal(entry.Timestamp) {
+			t.Errorf("Entry %d timestamp mismatch: got %v, want %v", i, restored[i].Timestamp, entry.Timestamp)
+		}
+		if !restored[i].ReceivedAt.Equal(entry.ReceivedAt) {
+			t.Errorf("Entry %d received time mismatch: got %v, want %v", i, restored[i].ReceivedAt, entry.ReceivedAt)
+		}
+		restored[i].Timestamp = entry.Timestamp
+		restored[i].ReceivedAt = entry.ReceivedAt
+		if !reflect.DeepEqual(restored[i], entry) {
+			t.Errorf("Entry %d mismatch: got %+v, want %+v", i, restored[i], entry)
+		}
+	}
+
+	// Restored entries are stored by the next flush
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process restored batch: %v", err)
+	}
+
+	logs, _, _, err := GetLogs(10, time.Time{}, "next", map[string]any{"hostname": "spill-host"}, "timestamp", "ASC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(logs) != len(entries) {
+		t.Errorf("Expected %d stored logs, got %d", len(entries), len(logs))
+	}
+}
+
+func TestRestoreBatchWithoutSpillFile(t *testing.T) {
+	batchSpillPath = filepath.Join(t.TempDir(), "batch.gob")
+	defer func() { batchSpillPath = "" }()
+
+	if err := RestoreBatch(); err != nil {
+		t.Errorf("Expected no error without a spill file, got %v", err)
+	}
+}
+
+func TestConcurrentFlushAndSpill(t *testing.T) {
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	batchSpillPath = filepath.Join(t.TempDir(), "batch.gob")
+	defer func() { batchSpillPath = "" }()
+
+	const rounds, perRound = 20, 5
+	for round := range rounds {
+		for i := range perRound {
+			err := StoreLog(models.LogEntry{
+				Severity:       6,
+				Facility:       1,
+				Version:        1,
+				Timestamp:      time.Now(),
+				Hostname:       "race-host",
+				AppName:        "race-app",
+				ProcID:         "-",
+				MsgID:          "-",
+				StructuredData: "-",
+				Message:        fmt.Sprintf("Race message %d-%d", round, i),
+			})
+			if err != nil {
+				t.Fatalf("Failed to store log entry: %v", err)
+			}
+		}
+
+		// Each entry is either appended by the flush or spilled, never dropped in between
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := ProcessBatchStoreLogs(); err != nil {
+				t.Errorf("Failed to process batch: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := SpillBatch(); err != nil {
+				t.Errorf("Failed to spill batch: %v", err)
+			}
+		}()
+		wg.Wait()
+
+		if err := RestoreBatch(); err != nil {
+			t.Fatalf("Failed to restore batch: %v", err)
+		}
+		if err := ProcessBatchStoreLogs(); err != nil {
+			t.Fatalf("Failed to process restored batch: %v", err)
+		}
+	}
+
+	var count int
+	if err := GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE app_name = ?", "race-app").Scan(&count); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if count != rounds*perRound {
+		t.Errorf("Expected %d stored logs, got %d", rounds*perRound, count)
+	}
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retentionRule overrides the retention period of the logs of an app name or hostname
+type retentionRule struct {
+	column  string // app_name or hostname
+	value   string
+	minutes int64
+}
+
+// retentionRules holds the SLOGGO_RETENTION_APP and SLOGGO_RETENTION_HOST rules
+var retentionRules []retentionRule
+
+func init() {
+	if utils.RetentionApp != "" {
+		rules, err := parseRetentionRules("app_name", utils.RetentionApp)
+		if err != nil {
+			log.Printf("Invalid SLOGGO_RETENTION_APP, app names use the global retention: %v", err)
+		} else {
+			retentionRules = append(retentionRules, rules...)
+		}
+	}
+
+	if utils.RetentionHost != "" {
+		rules, err := parseRetentionRules("hostname", utils.RetentionHost)
+		if err != nil {
+			log.Printf("Invalid SLOGGO_RETENTION_HOST, hostnames use the global retention: %v", err)
+		} else {
+			retentionRules = append(retentionRules, rules...)
+		}
+	}
+}
+
+// parseRetentionRules parses a comma-separated list of "value:minutes" rules for a column
+// Example: "chatty-app:60" keeps the logs of chatty-app for an hour
+func parseRetentionRules(column string, config string) ([]retentionRule, error) {
+	rules := []retentionRule{}
+
+	for rule := range strings.SplitSeq(config, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		// Hostnames may be IPv6 addresses, the minutes follow the last colon
+		separator := strings.LastIndex(rule, ":")
+		if separator <= 0 {
+			return nil, fmt.Errorf("rule %q must be formatted as name:minutes", rule)
+		}
+
+		minutes, err := strconv.ParseInt(strings.TrimSpace(rule[separator+1:]), 10, 64)
+		if err != nil || minutes < 1 {
+			return nil, fmt.Errorf("rule %q: invalid minutes %q (must be a positive integer)", rule, rule[separator+1:])
+		}
+
+		rules = append(rules, retentionRule{
+			column:  column,
+			value:   strings.TrimSpace(rule[:separator]),
+			minutes: minutes,
+		})
+	}
+
+	return rules, nil
+}
+
+// retentionExclusions returns the condition excluding the logs covered by a rule from the global retention
+// A log matching several rules is deleted by the shortest one, the global retention only applies to the others
+func retentionExclusions(rules []retentionRule, args *[]any) string {
+	conditions := []string{}
+	for _, rule := range rules {
+		conditions = append(conditions, rule.column+" IS DISTINCT FROM ?")
+		*args = append(*args, rule.value)
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// cleanupRetentionRules deletes the logs of a table older than the retention period of their rule
+func cleanupRetentionRules(table string, now time.Time) error {
+	for _, rule := range retentionRules {
+		cutoffTime := now.Add(-time.Duration(rule.minutes) * time.Minute).UTC().Format(time.RFC3339Nano)
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND timestamp < ?", table, rule.column)
+
+		result, err := db.Exec(query, rule.value, cutoffTime)
+		if err != nil {
+			log.Printf("Failed to delete old logs of %s %s from %s: %v", rule.column, rule.value, table, err)
+			return err
+		}
+		dataVersion.Add(1)
+
+		// Log the number of deleted rows per rule
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			log.Printf("Failed to get rows affected by cleanup: %v", err)
+		} else if rowsAffected > 0 {
+			log.Printf("Cleaned up %d log entries of %s with %s %s older than %s", rowsAffected, table, rule.column, rule.value, cutoffTime)
+		}
+	}
+
+	return nil
+}
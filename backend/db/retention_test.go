@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/models"
+	"sloggo/utils"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionRules(t *testing.T) {
+	tests := []struct {
+		config      string
+		expected    []retentionRule
+		shouldError bool
+	}{
+		{config: "chatty-app:60", expected: []retentionRule{{column: "app_name", value: "chatty-app", minutes: 60}}},
+		{config: "chatty-app:60, noisy:1440", expected: []retentionRule{{column: "app_name", value: "chatty-app", minutes: 60}, {column: "app_name", value: "noisy", minutes: 1440}}},
+		{config: "fe80::1:30", expected: []retentionRule{{column: "app_name", value: "fe80::1", minutes: 30}}},
+		{config: "chatty-app", shouldError: true},
+		{config: ":60", shouldError: true},
+		{config: "chatty-app:0", shouldError: true},
+		{config: "chatty-app:1h", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		rules, err := parseRetentionRules("app_name", tt.config)
+		if tt.shouldError {
+			if err == nil {
+				t.Errorf("parseRetentionRules(%q): expected an error", tt.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRetentionRules(%q): unexpected error: %v", tt.config, err)
+			continue
+		}
+
+		if fmt.Sprint(rules) != fmt.Sprint(tt.expected) {
+			t.Errorf("parseRetentionRules(%q) = %v, want %v", tt.config, rules, tt.expected)
+		}
+	}
+}
+
+func TestCleanupWithRetentionRules(t *testing.T) {
+	originalRules, originalMinutes := retentionRules, utils.LogRetentionMinutes
+	defer func() {
+		retentionRules, utils.LogRetentionMinutes = originalRules, originalMinutes
+	}()
+
+	utils.LogRetentionMinutes = 30 * 24 * 60
+	retentionRules = []retentionRule{
+		{column: "app_name", value: "retention-short", minutes: 60},
+		{column: "app_name", value: "retention-long", minutes: 90 * 24 * 60},
+		{column: "hostname", value: "retention-host", minutes: 60},
+	}
+
+	now := time.Now()
+	entries := []struct {
+		appName  string
+		hostname string
+		age      time.Duration
+	}{
+		{"retention-short", "retention-a", 2 * time.Hour},       // Deleted by its app rule
+		{"retention-short", "retention-a", 10 * time.Minute},    // Kept, within its app rule
+		{"retention-long", "retention-a", 40 * 24 * time.Hour},  // Kept, beyond the global retention but within its app rule
+		{"retention-long", "retention-host", 2 * time.Hour},     // Deleted by the shorter host rule
+		{"retention-other", "retention-a", 2 * time.Hour},       // Kept, within the global retention
+		{"retention-other", "retention-a", 40 * 24 * time.Hour}, // Deleted by the global retention
+	}
+
+	for i, entry := range entries {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(-entry.age),
+			Hostname:       entry.hostname,
+			AppName:        entry.appName,
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Retention message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	if err := cleanupOldLogs(); err != nil {
+		t.Fatalf("Failed to clean up logs: %v", err)
+	}
+
+	rows, err := GetDBInstance().Query("SELECT msg FROM logs WHERE app_name LIKE 'retention-%' ORDER BY msg")
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	defer rows.Close()
+
+	remaining := []string{}
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			t.Fatalf("Failed to scan log: %v", err)
+		}
+		remaining = append(remaining, message)
+	}
+
+	expected := []string{"Retention message 1", "Retention message 2", "Retention message 4"}
+	if fmt.Sprint(remaining) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v to remain, got %v", expected, remaining)
+	}
+}
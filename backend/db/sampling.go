@@ -0,0 +1,103 @@
+package db
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"sloggo/formats"
+	"sloggo/models"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// sampleRule keeps one in rate logs of an app name and severity
+type sampleRule struct {
+	rate int64
+	seen atomic.Int64
+}
+
+// sampleRules holds the SLOGGO_SAMPLE rules, keyed by "appName:severity" or "appName:" for every severity
+var sampleRules map[string]*sampleRule
+
+// sampledOutLogs counts the logs dropped by sampling
+var sampledOutLogs = expvar.NewInt("sampledOut")
+
+func init() {
+	if utils.Sample == "" {
+		return
+	}
+
+	rules, err := parseSampleRules(utils.Sample)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_SAMPLE, logs are not sampled: %v", err)
+		return
+	}
+
+	sampleRules = rules
+}
+
+// parseSampleRules parses a comma-separated list of "appName[:severity]=rate" rules
+// Example: "chatty-app:6=10" keeps 1 in 10 informational logs of chatty-app
+func parseSampleRules(config string) (map[string]*sampleRule, error) {
+	rules := make(map[string]*sampleRule)
+
+	for rule := range strings.SplitSeq(config, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		source, rate, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q is missing the sampling rate", rule)
+		}
+
+		appName, severity, hasSeverity := strings.Cut(source, ":")
+		appName = strings.TrimSpace(appName)
+		if appName == "" {
+			return nil, fmt.Errorf("rule %q is missing the app name", rule)
+		}
+
+		key := appName + ":"
+		if hasSeverity {
+			parsed, err := formats.ParseSeverity(severity)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %v", rule, err)
+			}
+			key += strconv.Itoa(int(parsed))
+		}
+
+		parsedRate, err := strconv.ParseInt(strings.TrimSpace(rate), 10, 64)
+		if err != nil || parsedRate < 1 {
+			return nil, fmt.Errorf("rule %q: invalid rate %q (must be a positive integer)", rule, rate)
+		}
+
+		rules[key] = &sampleRule{rate: parsedRate}
+	}
+
+	return rules, nil
+}
+
+// sampledOut reports whether the entry is dropped by sampling, counting it if so
+// The first of every rate logs matching a rule is kept, rules for a severity take precedence
+func sampledOut(entry models.LogEntry) bool {
+	if len(sampleRules) == 0 {
+		return false
+	}
+
+	rule, ok := sampleRules[fmt.Sprintf("%s:%d", entry.AppName, entry.Severity)]
+	if !ok {
+		if rule, ok = sampleRules[entry.AppName+":"]; !ok {
+			return false
+		}
+	}
+
+	if (rule.seen.Add(1)-1)%rule.rate == 0 {
+		return false
+	}
+
+	sampledOutLogs.Add(1)
+	return true
+}
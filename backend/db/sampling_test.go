@@ -0,0 +1,84 @@
+package db
+
+import (
+	"sloggo/models"
+	"testing"
+)
+
+func TestParseSampleRules(t *testing.T) {
+	tests := []struct {
+		config      string
+		expected    map[string]int64
+		shouldError bool
+	}{
+		{config: "chatty-app:6=10", expected: map[string]int64{"chatty-app:6": 10}},
+		{config: "chatty-app:info=10, noisy=100", expected: map[string]int64{"chatty-app:6": 10, "noisy:": 100}},
+		{config: "chatty-app:6", shouldError: true},
+		{config: ":6=10", shouldError: true},
+		{config: "chatty-app:loud=10", shouldError: true},
+		{config: "chatty-app:6=0", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		rules, err := parseSampleRules(tt.config)
+		if tt.shouldError {
+			if err == nil {
+				t.Errorf("parseSampleRules(%q): expected an error", tt.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSampleRules(%q): unexpected error: %v", tt.config, err)
+			continue
+		}
+
+		if len(rules) != len(tt.expected) {
+			t.Errorf("parseSampleRules(%q): got %d rules, want %d", tt.config, len(rules), len(tt.expected))
+		}
+		for key, rate := range tt.expected {
+			if rule, ok := rules[key]; !ok || rule.rate != rate {
+				t.Errorf("parseSampleRules(%q): missing rule %s=%d", tt.config, key, rate)
+			}
+		}
+	}
+}
+
+func TestSampledOut(t *testing.T) {
+	originalRules := sampleRules
+	defer func() { sampleRules = originalRules }()
+
+	rules, err := parseSampleRules("chatty-app:6=10,noisy=2")
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	sampleRules = rules
+
+	sampledBefore := sampledOutLogs.Value()
+
+	tests := []struct {
+		entry    models.LogEntry
+		expected int
+	}{
+		{models.LogEntry{AppName: "chatty-app", Severity: 6}, 2},
+		// Other severities of the app are all kept
+		{models.LogEntry{AppName: "chatty-app", Severity: 3}, 20},
+		{models.LogEntry{AppName: "noisy", Severity: 3}, 10},
+		{models.LogEntry{AppName: "quiet-app", Severity: 6}, 20},
+	}
+
+	for _, tt := range tests {
+		kept := 0
+		for range 20 {
+			if !sampledOut(tt.entry) {
+				kept++
+			}
+		}
+		if kept != tt.expected {
+			t.Errorf("%s with severity %d: kept %d of 20 logs, want %d", tt.entry.AppName, tt.entry.Severity, kept, tt.expected)
+		}
+	}
+
+	if sampled := sampledOutLogs.Value() - sampledBefore; sampled != 28 {
+		t.Errorf("Expected 28 sampled out logs, got %d", sampled)
+	}
+}
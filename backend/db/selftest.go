@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"sloggo/models"
+	"sync/atomic"
+	"time"
+)
+
+// selfTestMsgID marks the synthetic log of the self-test, so it can't be mistaken for a real one
+const selfTestMsgID = "SLOGGO_SELFTEST"
+
+// selfTestFailed is set when a self-test required for readiness failed, see SLOGGO_SELFTEST_REQUIRED
+var selfTestFailed atomic.Bool
+
+// SelfTest stores a synthetic log in the default table, queries it back and deletes it
+// This checks the ingest, store and query path, catching schema or permission issues before real traffic.
+// When required, a failure keeps IsReady false.
+func SelfTest(required bool) error {
+	err := selfTest()
+	selfTestFailed.Store(required && err != nil)
+	return err
+}
+
+// selfTest runs the round trip of the self-test
+func selfTest() error {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	message := fmt.Sprintf("Sloggo self-test %d", now.UnixNano())
+
+	entry := models.LogEntry{
+		Facility:   16,
+		Severity:   6,
+		Timestamp:  now,
+		Hostname:   "localhost",
+		AppName:    "sloggo",
+		MsgID:      selfTestMsgID,
+		Message:    message,
+		ReceivedAt: now,
+	}
+
+	// The entry skips the batch, transforms, alerts and forwarding, it isn't a real log
+	if err := processBatchStoreLogsWithEntries([]models.LogEntry{entry}); err != nil {
+		return fmt.Errorf("error storing self-test log: %v", err)
+	}
+
+	// Delete the entry whatever the outcome, it must not show up in queries
+	defer func() {
+		db.Exec(fmt.Sprintf("DELETE FROM %s WHERE msgid = ?", defaultTable), selfTestMsgID)
+		dataVersion.Add(1)
+	}()
+
+	var stored string
+	query := fmt.Sprintf("SELECT msg FROM %s WHERE msgid = ? AND msg = ?", defaultTable)
+	if err := db.QueryRow(query, selfTestMsgID, message).Scan(&stored); err != nil {
+		return fmt.Errorf("error querying self-test log: %v", err)
+	}
+
+	return nil
+}
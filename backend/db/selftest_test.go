@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(true); err != nil {
+		t.Fatalf("Expected the self-test to pass, got %v", err)
+	}
+	if !IsReady() {
+		t.Error("Expected the database to be ready after a passing self-test")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs WHERE msgid = ?", selfTestMsgID).Scan(&count); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the self-test log to be deleted, got %d", count)
+	}
+}
+
+func TestSelfTestFailureFailsReadiness(t *testing.T) {
+	// A missing table makes the round trip fail
+	if _, err := db.Exec("ALTER TABLE logs RENAME TO logs_selftest"); err != nil {
+		t.Fatalf("Failed to rename table: %v", err)
+	}
+
+	if err := SelfTest(false); err == nil {
+		t.Error("Expected the self-test to fail without the logs table")
+	}
+	if !IsReady() {
+		t.Error("Expected a self-test that isn't required to keep the database ready")
+	}
+
+	if err := SelfTest(true); err == nil {
+		t.Error("Expected the self-test to fail without the logs table")
+	}
+	if IsReady() {
+		t.Error("Expected a required self-test failure to fail readiness")
+	}
+
+	if _, err := db.Exec("ALTER TABLE logs_selftest RENAME TO logs"); err != nil {
+		t.Fatalf("Failed to restore table: %v", err)
+	}
+	if err := SelfTest(true); err != nil {
+		t.Fatalf("Expected the self-test to pass once the table is restored, got %v", err)
+	}
+	if !IsReady() {
+		t.Error("Expected the database to be ready again after a passing self-test")
+	}
+}
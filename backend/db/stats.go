@@ -0,0 +1,46 @@
+package db
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// stalledFlushIntervals is the number of missed flush intervals after which the batch processor is considered stuck
+const stalledFlushIntervals = 3
+
+// skippedRows counts the invalid rows skipped with SLOGGO_BATCH_ON_ERROR=skip
+var skippedRows atomic.Int64
+
+// BatchStats describes the state of the batch processor
+type BatchStats struct {
+	PendingEntries int       `json:"pendingEntries"`
+	LastFlushTime  time.Time `json:"lastFlushTime"`
+	FlushInterval  string    `json:"flushInterval"`
+	Stalled        bool      `json:"stalled"`
+	SkippedRows    int64     `json:"skippedRows"`
+}
+
+func init() {
+	expvar.Publish("batch", expvar.Func(func() any {
+		return GetBatchStats()
+	}))
+}
+
+// GetBatchStats returns the number of pending entries and the time of the last successful flush
+// The processor is reported as stalled when entries are pending and no flush succeeded for several intervals
+func GetBatchStats() BatchStats {
+	batchLogsMutex.Lock()
+	pending := len(batchLogs)
+	batchLogsMutex.Unlock()
+
+	lastFlush := time.Unix(0, lastFlushTime.Load())
+
+	return BatchStats{
+		PendingEntries: pending,
+		LastFlushTime:  lastFlush.UTC(),
+		FlushInterval:  batchFlushInterval.String(),
+		Stalled:        pending > 0 && time.Since(lastFlush) > stalledFlushIntervals*batchFlushInterval,
+		SkippedRows:    skippedRows.Load(),
+	}
+}
@@ -4,18 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"sloggo/alerts"
 	"sloggo/models"
+	"sloggo/relay"
+	"sloggo/transform"
 	"sloggo/utils"
 
 	"github.com/marcboeker/go-duckdb/v2"
@@ -26,9 +35,17 @@ var (
 	batchLogsMutex        sync.Mutex
 	batchLogs             []models.LogEntry
 	maxBatchStoreLogsSize = 10000
+	batchFlushInterval    = 5 * time.Second
 	cleanupTick           = 30 * time.Minute
+	lastFlushTime         atomic.Int64
 )
 
+// readyTimeout bounds the query checking that the database answers for the readiness endpoint
+const readyTimeout = 2 * time.Second
+
+// logColumns lists the columns selected to build log entries, in the order expected by scanLogEntries
+const logColumns = "rowid, facility, severity, timestamp, hostname, app_name, procid, msgid, structured_data, msg, received_at"
+
 // ChartDataPoint represents a single point of log data for charts
 type ChartDataPoint struct {
 	Timestamp int64 `json:"timestamp"`
@@ -40,11 +57,76 @@ type ChartDataPoint struct {
 	Critical  int   `json:"critical"`
 	Alert     int   `json:"alert"`
 	Emergency int   `json:"emergency"`
+
+	// series is the bitmask of the severities computed for the point, all of them when 0
+	series uint8
+}
+
+// chartSeries lists the chart series by severity, in the order of the chart query columns
+var chartSeries = []struct {
+	severity int
+	name     string
+}{
+	{7, "debug"},
+	{6, "info"},
+	{5, "notice"},
+	{4, "warning"},
+	{3, "error"},
+	{2, "critical"},
+	{1, "alert"},
+	{0, "emergency"},
+}
+
+// count returns the count of the point for a severity
+func (p *ChartDataPoint) count(severity int) *int {
+	switch severity {
+	case 7:
+		return &p.Debug
+	case 6:
+		return &p.Info
+	case 5:
+		return &p.Notice
+	case 4:
+		return &p.Warning
+	case 3:
+		return &p.Error
+	case 2:
+		return &p.Critical
+	case 1:
+		return &p.Alert
+	default:
+		return &p.Emergency
+	}
+}
+
+// MarshalJSON omits the series which were not computed for the point
+func (p ChartDataPoint) MarshalJSON() ([]byte, error) {
+	// The alias type drops the methods to avoid recursing into MarshalJSON
+	type chartDataPoint ChartDataPoint
+
+	if p.series == 0 {
+		return json.Marshal(chartDataPoint(p))
+	}
+
+	point := map[string]int64{"timestamp": p.Timestamp}
+	for _, series := range chartSeries {
+		if p.series&(1<<series.severity) != 0 {
+			point[series.name] = int64(*p.count(series.severity))
+		}
+	}
+
+	return json.Marshal(point)
 }
 
 // FacetMetadata represents metadata for faceted search
 type FacetMetadata struct {
 	Rows []FacetRow `json:"rows"`
+
+	// Approximate is set when the totals are estimated from a sample of the table, see SLOGGO_FACET_SAMPLE_THRESHOLD
+	Approximate bool `json:"approximate,omitempty"`
+
+	// OthersCount is the number of logs with a value beyond the facet limit, only set for bounded facets
+	OthersCount int `json:"othersCount,omitempty"`
 }
 
 // FacetRow represents a single row in facet metadata
@@ -57,16 +139,34 @@ func init() {
 	// Set up database connection
 	setupDatabase()
 
-	// Initialize schema
-	setupDatabaseTable("logs")
+	// Initialize schema, older versions only had the default table to migrate
+	addTables(utils.Tables)
+	for _, table := range tables {
+		setupDatabaseTable(table)
+	}
+	migrateStructuredDataSentinel()
+	migrateReceivedAt()
 
 	batchLogs = make([]models.LogEntry, 0, maxBatchStoreLogsSize)
+	lastFlushTime.Store(time.Now().UnixNano())
 
-	// Start the batch processor
-	go processBatchPeriodically()
+	// Re-enqueue the batch persisted on the last shutdown before listeners accept traffic
+	if utils.BatchPersist {
+		if err := RestoreBatch(); err != nil {
+			log.Printf("Failed to restore the persisted batch: %v", err)
+		}
+	}
+}
+
+// StartBackgroundTasks starts the periodic batch processor and log cleanup, until the context is done
+// The pending batch is not flushed when they stop, shutdown stores or persists it
+func StartBackgroundTasks(ctx context.Context) {
+	go processBatchPeriodically(ctx, batchFlushInterval)
+	go performLogCleanupPeriodically(ctx, cleanupTick)
 
-	// Start the log cleanup process
-	go performLogCleanupPeriodically()
+	if utils.MinFreeDiskMB > 0 && databaseDir != "" {
+		go monitorDiskSpace(ctx, diskCheckInterval)
+	}
 }
 
 // setupDatabase initializes the database connections
@@ -80,14 +180,193 @@ func setupDatabase() {
 	}
 
 	dsn := filepath.Join(path.Dir(e), ".duckdb/logs.db")
+	batchSpillPath = filepath.Join(path.Dir(e), ".duckdb/batch.gob")
+
+	databaseDir = filepath.Dir(dsn)
 
 	if testing.Testing() {
 		dsn = ""
+		batchSpillPath = ""
+		databaseDir = ""
+	}
+
+	retries := max(int(utils.DbOpenRetries), 0)
+	db, err = openWithRetry(func() (*sql.DB, error) {
+		return openDatabase(dsn)
+	}, retries, time.Duration(utils.DbOpenRetrySeconds)*time.Second)
+	if err != nil {
+		log.Fatalf("Failed to open database %s after %d attempts, check that its volume is mounted and writable: %v", dsn, retries+1, err)
+	}
+
+	applyDatabaseSettings()
+	loadExtensions()
+	applyS3Settings()
+}
+
+// maxDbOpenRetryInterval caps the backoff between attempts to open the database
+const maxDbOpenRetryInterval = 30 * time.Second
+
+// openDatabase opens the database and checks that it's usable
+func openDatabase(dsn string) (*sql.DB, error) {
+	database, err := sql.Open("duckdb", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.Ping(); err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// openWithRetry calls open until it succeeds, up to retries more times, doubling the interval between attempts
+// Storage provisioned after the process starts, such as a late volume mount, doesn't crash the process
+func openWithRetry(open func() (*sql.DB, error), retries int, interval time.Duration) (*sql.DB, error) {
+	for attempt := 0; ; attempt++ {
+		database, err := open()
+		if err == nil || attempt >= retries {
+			return database, err
+		}
+
+		log.Printf("Failed to open database (attempt %d of %d), retrying in %v: %v", attempt+1, retries+1, interval, err)
+		time.Sleep(interval)
+		interval = min(interval*2, maxDbOpenRetryInterval)
+	}
+}
+
+// memoryLimitRegex validates DuckDB memory limits such as "512MB" or "2GiB"
+var memoryLimitRegex = regexp.MustCompile(`^\d+(\.\d+)?\s*(b|kb|mb|gb|tb|kib|mib|gib|tib)$`)
+
+// applyDatabaseSettings applies the configured DuckDB memory limit and threads
+// Invalid values are logged and ignored, DuckDB defaults are kept
+func applyDatabaseSettings() {
+	if utils.DuckDBMemoryLimit != "" {
+		if memoryLimitRegex.MatchString(utils.DuckDBMemoryLimit) {
+			if _, err := db.Exec(fmt.Sprintf("SET GLOBAL memory_limit = '%s'", utils.DuckDBMemoryLimit)); err != nil {
+				log.Printf("Failed to set DuckDB memory limit: %v", err)
+			}
+		} else {
+			log.Printf("Invalid SLOGGO_DUCKDB_MEMORY_LIMIT %q, expected a size such as 512MB or 2GB", utils.DuckDBMemoryLimit)
+		}
+	}
+
+	if utils.DuckDBThreads < 0 {
+		log.Printf("Invalid SLOGGO_DUCKDB_THREADS %d, expected a positive number", utils.DuckDBThreads)
+	} else if utils.DuckDBThreads > 0 {
+		if _, err := db.Exec(fmt.Sprintf("SET GLOBAL threads = %d", utils.DuckDBThreads)); err != nil {
+			log.Printf("Failed to set DuckDB threads: %v", err)
+		}
 	}
 
-	db, err = sql.Open("duckdb", dsn)
+	var memoryLimit, threads string
+	if err := db.QueryRow("SELECT current_setting('memory_limit'), CAST(current_setting('threads') AS VARCHAR)").Scan(&memoryLimit, &threads); err != nil {
+		log.Printf("Failed to read DuckDB settings: %v", err)
+		return
+	}
+
+	log.Printf("DuckDB settings: memory_limit=%s threads=%s", memoryLimit, threads)
+}
+
+// extensionNameRegex validates DuckDB extension names such as "httpfs"
+var extensionNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// extensionNames returns the valid extension names of a comma separated list, invalid ones are logged and ignored
+func extensionNames(value string) []string {
+	var names []string
+	for name := range strings.SplitSeq(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if !extensionNameRegex.MatchString(name) {
+			log.Printf("Invalid DuckDB extension %q in SLOGGO_DUCKDB_EXTENSIONS, expected a name such as httpfs", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// loadExtensions installs and loads the configured DuckDB extensions, such as httpfs to read and write S3 files
+// An extension that can't be installed or loaded, e.g. without network access, is logged and skipped
+func loadExtensions() {
+	for _, name := range extensionNames(utils.DuckDBExtensions) {
+		if _, err := db.Exec("INSTALL " + name); err != nil {
+			log.Printf("Warning: failed to install DuckDB extension %s: %v", name, err)
+			continue
+		}
+
+		if _, err := db.Exec("LOAD " + name); err != nil {
+			log.Printf("Warning: failed to load DuckDB extension %s: %v", name, err)
+			continue
+		}
+
+		log.Printf("Loaded DuckDB extension %s", name)
+	}
+}
+
+// applyS3Settings sets the configured S3 credentials, used by the httpfs extension to access s3:// paths
+// The settings are only available once httpfs is loaded, failing to set one is logged
+func applyS3Settings() {
+	settings := []struct {
+		name  string
+		value string
+	}{
+		{"s3_access_key_id", utils.S3AccessKeyID},
+		{"s3_secret_access_key", utils.S3SecretAccessKey},
+		{"s3_region", utils.S3Region},
+		{"s3_endpoint", utils.S3Endpoint},
+	}
+
+	for _, setting := range settings {
+		if setting.value == "" {
+			continue
+		}
+
+		// Global so that every connection of the pool uses the credentials, the value is never logged
+		if _, err := db.Exec(fmt.Sprintf("SET GLOBAL %s = %s", setting.name, quoteLiteral(setting.value))); err != nil {
+			log.Printf("Warning: failed to set DuckDB %s, is the httpfs extension loaded? %v", setting.name, err)
+		}
+	}
+}
+
+// quoteLiteral quotes a value as a SQL string literal, for statements such as SET that don't take parameters
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// migrateStructuredDataSentinel replaces the "-" stored for absent structured data by older versions with NULL
+func migrateStructuredDataSentinel() {
+	result, err := db.Exec("UPDATE logs SET structured_data = NULL WHERE structured_data = '-'")
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		log.Printf("Failed to migrate absent structured data: %v", err)
+		return
+	}
+
+	if migrated, err := result.RowsAffected(); err == nil && migrated > 0 {
+		log.Printf("Migrated the absent structured data of %d logs to NULL", migrated)
+	}
+}
+
+// migrateReceivedAt adds the received time column to databases created by older versions
+// Their logs get their event time as received time, the closest known value
+func migrateReceivedAt() {
+	if _, err := db.Exec("ALTER TABLE logs ADD COLUMN IF NOT EXISTS received_at TIMESTAMP"); err != nil {
+		log.Printf("Failed to add the received_at column: %v", err)
+		return
+	}
+
+	result, err := db.Exec("UPDATE logs SET received_at = timestamp WHERE received_at IS NULL")
+	if err != nil {
+		log.Printf("Failed to migrate the received time: %v", err)
+		return
+	}
+
+	if migrated, err := result.RowsAffected(); err == nil && migrated > 0 {
+		log.Printf("Set the received time of %d logs to their event time", migrated)
 	}
 }
 
@@ -104,7 +383,8 @@ func setupDatabaseTable(table string) {
 	    procid TEXT,
 	    msgid TEXT,
 	    structured_data TEXT,
-	    msg TEXT
+	    msg TEXT,
+	    received_at TIMESTAMP
 	);
 	`, table)
 
@@ -113,13 +393,65 @@ func setupDatabaseTable(table string) {
 	}
 }
 
+// IsReady reports whether the database answers a query, the required self-test passed,
+// and its volume isn't low on disk space
+// The query doesn't wait for a SLOGGO_MAX_DB_QUERIES slot, busy dashboards don't make the instance unready
+func IsReady() bool {
+	if selfTestFailed.Load() || lowDiskSpace.Load() {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		log.Printf("Database readiness check failed: %v", err)
+		return false
+	}
+
+	return true
+}
+
 // GetDBInstance returns the initialized DuckDB database instance.
 func GetDBInstance() *sql.DB {
 	return db
 }
 
 // StoreLog adds a log entry to the batch for efficient processing
+// The entry is stored in its Table, which must be one of the configured tables
 func StoreLog(entry models.LogEntry) error {
+	if !IsTable(entryTable(entry)) {
+		return fmt.Errorf("unknown table %q", entry.Table)
+	}
+
+	// Drop the logs sampled out by SLOGGO_SAMPLE before they're alerted on, forwarded or stored
+	if sampledOut(entry) {
+		return nil
+	}
+
+	// Keep room for the more severe logs while the database volume is nearly full
+	if droppedForDiskSpace(entry) {
+		return nil
+	}
+
+	if entry.ReceivedAt.IsZero() {
+		entry.ReceivedAt = time.Now()
+	}
+
+	// Normalize and redact the entry with SLOGGO_TRANSFORMS before anything sees it
+	transform.Apply(&entry)
+
+	// Fire webhooks of matching alert rules, this is asynchronous and never blocks ingestion
+	alerts.Evaluate(entry)
+
+	// Relay the log to the upstream server, if any, without waiting for it
+	defer relay.Forward(entry)
+
+	// The read lock keeps SpillBatch from running between the swap of a full batch and its append
+	flushLock.RLock()
+	defer flushLock.RUnlock()
+
 	batchLogsMutex.Lock()
 	batchLogs = append(batchLogs, entry)
 
@@ -132,19 +464,36 @@ func StoreLog(entry models.LogEntry) error {
 		batchLogsMutex.Unlock()
 
 		// Process the batch outside the lock
-		return processBatchStoreLogsWithEntries(entries)
+		return appendBatch(entries)
 	}
 
 	batchLogsMutex.Unlock()
 	return nil
 }
 
+// StoreLogsDirect stores log entries immediately with the appender, bypassing the batch, alerts and forwarding
+// It's meant for bulk loads, where waiting for the batch timer would only add latency
+func StoreLogsDirect(entries []models.LogEntry) error {
+	// Bulk loads are still transformed, redactions must apply to every stored log
+	for i := range entries {
+		transform.Apply(&entries[i])
+	}
+
+	return processBatchStoreLogsWithEntries(entries)
+}
+
 // ProcessBatchStoreLogs processes all pending log entries
 // This is called by the periodic batch processor
 func ProcessBatchStoreLogs() error {
+	// Held from the swap until the entries are appended, so SpillBatch either spills or waits for them
+	flushLock.RLock()
+	defer flushLock.RUnlock()
+
 	batchLogsMutex.Lock()
 	if len(batchLogs) == 0 {
 		batchLogsMutex.Unlock()
+		// Nothing is pending, the processor is alive and up to date
+		lastFlushTime.Store(time.Now().UnixNano())
 		return nil
 	}
 
@@ -152,12 +501,20 @@ func ProcessBatchStoreLogs() error {
 	batchLogs = make([]models.LogEntry, 0, maxBatchStoreLogsSize)
 	batchLogsMutex.Unlock()
 
-	return processBatchStoreLogsWithEntries(entries)
+	return appendBatch(entries)
 }
 
 // processBatchStoreLogsWithEntries processes a batch of log entries
 // This function does not touch the global batchLogs slice
 func processBatchStoreLogsWithEntries(entries []models.LogEntry) error {
+	flushLock.RLock()
+	defer flushLock.RUnlock()
+
+	return appendBatch(entries)
+}
+
+// appendBatch appends a batch of log entries, the caller holds flushLock for reading
+func appendBatch(entries []models.LogEntry) error {
 	if len(entries) == 0 {
 		return nil
 	}
@@ -178,9 +535,36 @@ func processBatchStoreLogsWithEntries(entries []models.LogEntry) error {
 		return err
 	}
 
-	appender, err := duckdb.NewAppenderFromConn(rawConn, "", "logs")
+	// Entries are appended table by table, most batches only hold the default table
+	entriesByTable := make(map[string][]models.LogEntry)
+	for _, entry := range entries {
+		table := entryTable(entry)
+		entriesByTable[table] = append(entriesByTable[table], entry)
+	}
+
+	for table, tableEntries := range entriesByTable {
+		if !IsTable(table) {
+			// Only entries restored from a batch persisted with other SLOGGO_TABLES can get here
+			skippedRows.Add(int64(len(tableEntries)))
+			log.Printf("Skipping %d rows of the unknown table %s", len(tableEntries), table)
+			continue
+		}
+
+		if err := appendLogEntries(rawConn, table, tableEntries); err != nil {
+			return err
+		}
+		dataVersion.Add(1)
+	}
+
+	lastFlushTime.Store(time.Now().UnixNano())
+	return nil
+}
+
+// appendLogEntries appends log entries to a table with the DuckDB appender, following the SLOGGO_BATCH_ON_ERROR policy
+func appendLogEntries(rawConn driver.Conn, table string, entries []models.LogEntry) error {
+	appender, err := duckdb.NewAppenderFromConn(rawConn, "", table)
 	if err != nil {
-		log.Printf("Failed to create appender: %v", err)
+		log.Printf("Failed to create appender for table %s: %v", table, err)
 		return err
 	}
 	defer func() {
@@ -191,6 +575,23 @@ func processBatchStoreLogsWithEntries(entries []models.LogEntry) error {
 
 	// Append each log entry directly from struct fields
 	for i, entry := range entries {
+		// Invalid rows would fail the whole flush, skip them or abort depending on the policy
+		if err := validateLogEntry(entry); err != nil {
+			if utils.BatchOnError == "skip" {
+				skippedRows.Add(1)
+				log.Printf("Skipping invalid row %d: %v", i+1, err)
+				continue
+			}
+			log.Printf("Invalid row %d: %v", i+1, err)
+			return err
+		}
+
+		// Entries stored without going through the batch are received now
+		receivedAt := entry.ReceivedAt
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+
 		if err := appender.AppendRow(
 			entry.Severity,
 			entry.Facility,
@@ -200,9 +601,15 @@ func processBatchStoreLogsWithEntries(entries []models.LogEntry) error {
 			entry.AppName,
 			entry.ProcID,
 			entry.MsgID,
-			entry.StructuredData,
+			structuredDataValue(entry.StructuredData),
 			entry.Message,
+			receivedAt,
 		); err != nil {
+			if utils.BatchOnError == "skip" {
+				skippedRows.Add(1)
+				log.Printf("Skipping row %d that failed to append: %v", i+1, err)
+				continue
+			}
 			log.Printf("Failed to append row %d: %v", i+1, err)
 			return err
 		}
@@ -213,67 +620,266 @@ func processBatchStoreLogsWithEntries(entries []models.LogEntry) error {
 		log.Printf("Failed to flush appender: %v", err)
 		return err
 	}
+
+	return nil
+}
+
+// structuredDataValue returns the stored value of the structured data, NULL when absent
+// The RFC5424 nil value "-" is accepted for entries built before absent structured data was stored as NULL
+func structuredDataValue(structuredData string) any {
+	if structuredData == "" || structuredData == "-" {
+		return nil
+	}
+	return structuredData
+}
+
+// validateLogEntry rejects entries the database would refuse
+func validateLogEntry(entry models.LogEntry) error {
+	if entry.Severity > 7 {
+		return fmt.Errorf("severity out of range (must be 0-7): %d", entry.Severity)
+	}
+	if entry.Facility > 23 {
+		return fmt.Errorf("facility out of range (must be 0-23): %d", entry.Facility)
+	}
+
+	for _, value := range []string{entry.Hostname, entry.AppName, entry.ProcID, entry.MsgID, entry.StructuredData, entry.Message} {
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("invalid UTF-8 in %q", value)
+		}
+	}
+
 	return nil
 }
 
 // processBatchPeriodically processes any pending logs on a timer
-func processBatchPeriodically() {
-	ticker := time.NewTicker(5 * time.Second)
+func processBatchPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := ProcessBatchStoreLogs(); err != nil {
-			log.Printf("Error in periodic batch processing: %v", err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ProcessBatchStoreLogs(); err != nil {
+				log.Printf("Error in periodic batch processing: %v", err)
+			}
 		}
 	}
 }
 
-// cleanupOldLogs deletes logs older than the retention period
+// cleanupOldLogs deletes logs older than the retention period from every table
+// Logs of the app names and hostnames with their own retention are deleted by their rules
 func cleanupOldLogs() error {
 	// Calculate the cutoff timestamp for deletion (current time - retention period)
-	cutoffTime := time.Now().Add(-time.Duration(utils.LogRetentionMinutes) * time.Minute).UTC().Format(time.RFC3339Nano)
+	now := time.Now()
+	cutoffTime := now.Add(-time.Duration(utils.LogRetentionMinutes) * time.Minute).UTC().Format(time.RFC3339Nano)
+
+	for _, table := range tables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE timestamp < ?", table)
+		args := []any{cutoffTime}
+		if exclusions := retentionExclusions(retentionRules, &args); exclusions != "" {
+			query += " AND " + exclusions
+		}
 
-	query := "DELETE FROM logs WHERE timestamp < ?"
+		result, err := db.Exec(query, args...)
+		if err != nil {
+			log.Printf("Failed to delete old logs from %s: %v", table, err)
+			return err
+		}
+		dataVersion.Add(1)
+
+		// Log the number of deleted rows
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			log.Printf("Failed to get rows affected by cleanup: %v", err)
+		} else if rowsAffected > 0 {
+			log.Printf("Cleaned up %d log entries of %s older than %s", rowsAffected, table, cutoffTime)
+		}
+
+		if err := cleanupRetentionRules(table, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trimExcessLogs deletes the oldest logs of each table holding more than the configured maximum rows
+func trimExcessLogs() error {
+	if utils.MaxRows <= 0 {
+		return nil
+	}
+
+	for _, table := range tables {
+		if err := trimExcessTableLogs(table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trimExcessTableLogs deletes the oldest logs of a table beyond the configured maximum rows
+func trimExcessTableLogs(table string) error {
+	var count int64
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+		log.Printf("Failed to count logs of %s: %v", table, err)
+		return err
+	}
 
-	result, err := db.Exec(query, cutoffTime)
+	excess := count - utils.MaxRows
+	if excess <= 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE rowid IN (SELECT rowid FROM %s ORDER BY timestamp ASC LIMIT %d)", table, table, excess)
+
+	result, err := db.Exec(query)
 	if err != nil {
-		log.Printf("Failed to delete old logs: %v", err)
+		log.Printf("Failed to trim excess logs: %v", err)
 		return err
 	}
+	dataVersion.Add(1)
 
-	// Log the number of deleted rows
+	// Log the number of trimmed rows
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("Failed to get rows affected by cleanup: %v", err)
+		log.Printf("Failed to get rows affected by trim: %v", err)
 	} else if rowsAffected > 0 {
-		log.Printf("Cleaned up %d log entries older than %s", rowsAffected, cutoffTime)
+		log.Printf("Trimmed %d oldest log entries of %s to stay within %d rows", rowsAffected, table, utils.MaxRows)
 	}
 
 	return nil
 }
 
 // performLogCleanupPeriodically runs log cleanup on a timer
-func performLogCleanupPeriodically() {
-	ticker := time.NewTicker(cleanupTick)
+// Age-based retention runs first, then the row cap trims whatever remains above the limit
+func performLogCleanupPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := cleanupOldLogs(); err != nil {
-			log.Printf("Error in periodic log cleanup: %v", err)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cleanupOldLogs(); err != nil {
+				log.Printf("Error in periodic log cleanup: %v", err)
+			}
+
+			if err := trimExcessLogs(); err != nil {
+				log.Printf("Error in periodic log trim: %v", err)
+			}
 		}
 	}
 }
 
 // GetLogs retrieves logs from the database based on filters
 func GetLogs(limit int, cursor time.Time, direction string, filters map[string]any, sortField string, sortOrder string) ([]models.LogEntry, int, int, error) {
+	logs, totalCount, filterCount, _, err := GetLogsPage(limit, cursor, direction, filters, sortField, sortOrder)
+	return logs, totalCount, filterCount, err
+}
+
+// GetLogsPage is GetLogs also reporting whether more logs follow the page in the requested direction
+// One more row than the limit is fetched to find out, then trimmed
+func GetLogsPage(limit int, cursor time.Time, direction string, filters map[string]any, sortField string, sortOrder string) ([]models.LogEntry, int, int, bool, error) {
+	query, countQuery, args := buildLogsQuery(limit+1, cursor, direction, filters, sortField, sortOrder)
+
+	release := acquireQuery()
+	defer release()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, 0, false, fmt.Errorf("error querying logs: %v", err)
+	}
+	defer rows.Close()
+
+	// Execute combined count query to get filtered and total counts
+	var filterCount, totalCount int
+	combinedCountQuery := fmt.Sprintf("SELECT (%s) as filtered_count, (SELECT COUNT(*) FROM %s) as total_count", countQuery, tableOf(filters))
+	err = db.QueryRow(combinedCountQuery, args...).Scan(&filterCount, &totalCount)
+	if err != nil {
+		return nil, 0, 0, false, fmt.Errorf("error counting logs: %v", err)
+	}
+
+	// Parse results
+	logs, err := scanLogEntries(rows)
+	if err != nil {
+		return nil, 0, 0, false, err
+	}
+
+	var hasMore bool
+	if direction == "tail" {
+		logs, hasMore = trimSplitMillisecond(logs, limit)
+	} else if hasMore = len(logs) > limit; hasMore {
+		logs = logs[:limit]
+	}
+
+	return logs, totalCount, filterCount, hasMore, nil
+}
+
+// HasLogsBehind reports whether logs matching the filters lie on the other side of the cursor than the direction,
+// the side a page requested from the cursor doesn't cover, or at or below sinceId for incremental reads
+// A single row is looked up, so it costs much less than counting them
+func HasLogsBehind(cursor time.Time, direction string, filters map[string]any) (bool, error) {
+	sinceId, incremental := filters["sinceId"].(int64)
+
+	pageFilters := make(map[string]any)
+	for k, v := range filters {
+		if k != "sinceId" {
+			pageFilters[k] = v
+		}
+	}
+
+	args := []any{}
+	conditions := []string{}
+	if whereClause := buildWhereClause(pageFilters, time.Time{}, "", &args); whereClause != "" {
+		conditions = append(conditions, whereClause)
+	}
+
+	switch {
+	case incremental:
+		conditions = append(conditions, "rowid <= ?")
+		args = append(args, sinceId)
+	case cursor.IsZero():
+		return false, nil
+	case direction == "prev":
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, cursor.Format(time.RFC3339Nano))
+	case direction == "tail":
+		// The cursor millisecond is skipped by the tail page, so it lies behind it
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, cursor.Truncate(time.Millisecond).Add(time.Millisecond).Format(time.RFC3339Nano))
+	default:
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, cursor.Format(time.RFC3339Nano))
+	}
+
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s LIMIT 1)", tableOf(filters), strings.Join(conditions, " AND "))
+
+	release := acquireQuery()
+	defer release()
+
+	var exists bool
+	if err := db.QueryRow(query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking for logs behind the cursor: %v", err)
+	}
+
+	return exists, nil
+}
+
+// buildLogsQuery builds the page and count queries of GetLogs, which share the same arguments
+func buildLogsQuery(limit int, cursor time.Time, direction string, filters map[string]any, sortField string, sortOrder string) (string, string, []any) {
 	// Build query
 	queryBuilder := strings.Builder{}
 	countQueryBuilder := strings.Builder{}
 	filterQueryBuilder := strings.Builder{}
 	args := []any{}
 
-	queryBuilder.WriteString("SELECT rowid, facility, severity, timestamp, hostname, app_name, procid, msgid, structured_data, msg FROM logs ")
-	countQueryBuilder.WriteString("SELECT COUNT(*) FROM logs ")
+	table := tableOf(filters)
+	queryBuilder.WriteString("SELECT " + logColumns + " FROM " + table + " ")
+	countQueryBuilder.WriteString("SELECT COUNT(*) FROM " + table + " ")
 
 	whereClause := buildWhereClause(filters, cursor, direction, &args)
 	if whereClause != "" {
@@ -284,7 +890,13 @@ func GetLogs(limit int, cursor time.Time, direction string, filters map[string]a
 	queryBuilder.WriteString(filterQueryBuilder.String())
 	countQueryBuilder.WriteString(filterQueryBuilder.String())
 
-	if sortField != "" && sortOrder != "" {
+	if _, ok := filters["sinceId"]; ok {
+		// Incremental reads return the rows in insertion order, so the last id is the next cursor
+		queryBuilder.WriteString(" ORDER BY rowid ASC")
+	} else if direction == "tail" {
+		// Tail mode always returns the oldest rows newer than the cursor first
+		queryBuilder.WriteString(" ORDER BY timestamp ASC")
+	} else if sortField != "" && sortOrder != "" {
 		queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s %s", sortField, sortOrder))
 	} else {
 		queryBuilder.WriteString(" ORDER BY timestamp DESC")
@@ -292,25 +904,44 @@ func GetLogs(limit int, cursor time.Time, direction string, filters map[string]a
 
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT %d", limit))
 
-	rows, err := db.Query(queryBuilder.String(), args...)
+	return queryBuilder.String(), countQueryBuilder.String(), args
+}
+
+// ExplainLogs returns the EXPLAIN ANALYZE output of the query GetLogs runs for the same parameters
+func ExplainLogs(limit int, cursor time.Time, direction string, filters map[string]any, sortField string, sortOrder string) (string, error) {
+	query, _, args := buildLogsQuery(limit, cursor, direction, filters, sortField, sortOrder)
+
+	rows, err := db.Query("EXPLAIN ANALYZE "+query, args...)
 	if err != nil {
-		return nil, 0, 0, fmt.Errorf("error querying logs: %v", err)
+		return "", fmt.Errorf("error explaining logs query: %v", err)
 	}
 	defer rows.Close()
 
-	// Execute combined count query to get filtered and total counts
-	var filterCount, totalCount int
-	combinedCountQuery := fmt.Sprintf("SELECT (%s) as filtered_count, (SELECT COUNT(*) FROM logs) as total_count", countQueryBuilder.String())
-	err = db.QueryRow(combinedCountQuery, args...).Scan(&filterCount, &totalCount)
-	if err != nil {
-		return nil, 0, 0, fmt.Errorf("error counting logs: %v", err)
+	// Each row is a plan type and its rendered plan
+	var plan strings.Builder
+	for rows.Next() {
+		var planType, planText string
+		if err := rows.Scan(&planType, &planText); err != nil {
+			return "", fmt.Errorf("error scanning query plan: %v", err)
+		}
+		plan.WriteString(planText)
 	}
 
-	// Parse results
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error reading query plan: %v", err)
+	}
+
+	return plan.String(), nil
+}
+
+// scanLogEntries scans rows selected with logColumns into log entries
+func scanLogEntries(rows *sql.Rows) ([]models.LogEntry, error) {
 	logs := []models.LogEntry{}
 	for rows.Next() {
 		var entry models.LogEntry
 		var timestampStr string
+		var structuredData sql.NullString
+		var receivedAt sql.NullTime
 
 		err := rows.Scan(
 			&entry.RowID,
@@ -321,27 +952,115 @@ func GetLogs(limit int, cursor time.Time, direction string, filters map[string]a
 			&entry.AppName,
 			&entry.ProcID,
 			&entry.MsgID,
-			&entry.StructuredData,
+			&structuredData,
 			&entry.Message,
+			&receivedAt,
 		)
 		if err != nil {
-			return nil, 0, 0, fmt.Errorf("error scanning log row: %v", err)
+			return nil, fmt.Errorf("error scanning log row: %v", err)
 		}
+		entry.StructuredData = structuredData.String
+		entry.ReceivedAt = receivedAt.Time
 
 		// Parse timestamp
 		entry.Timestamp, err = time.Parse(time.RFC3339Nano, timestampStr)
 		if err != nil {
-			return nil, 0, 0, fmt.Errorf("error parsing timestamp: %v", err)
+			return nil, fmt.Errorf("error parsing timestamp: %v", err)
 		}
 
 		logs = append(logs, entry)
 	}
 
-	return logs, totalCount, filterCount, nil
+	return logs, nil
+}
+
+// trimSplitMillisecond limits a tail page fetched with one row more than the limit, and reports whether rows follow it
+// The next page starts strictly after the last row's millisecond, so when the extra row shares it, the trailing rows
+// of that millisecond are left for the next page rather than skipped.
+// A page filled by a single millisecond is returned whole, the rest of that millisecond is skipped.
+func trimSplitMillisecond(logs []models.LogEntry, limit int) ([]models.LogEntry, bool) {
+	if len(logs) <= limit {
+		return logs, false
+	}
+
+	next := logs[limit].Timestamp.Truncate(time.Millisecond)
+	logs = logs[:limit]
+
+	i := limit
+	for i > 0 && logs[i-1].Timestamp.Truncate(time.Millisecond).Equal(next) {
+		i--
+	}
+
+	// A single millisecond fills the whole page, return it rather than nothing
+	if i == 0 {
+		return logs, true
+	}
+
+	return logs[:i], true
+}
+
+// priorityColumn computes the syslog priority, which isn't stored, from the facility and severity
+const priorityColumn = "(facility * 8 + severity)"
+
+// facetQuery describes a single facet computed by GetFacets
+type facetQuery struct {
+	key     string // Key of the facet in the returned map
+	column  string // Database column to group by
+	numeric bool   // Convert values to integers
+	bounded bool   // Only return the most frequent values, up to the facet limit
+	notNull bool   // Skip logs without a value, used for computed columns
+}
+
+// severityFacet counts logs per severity, also used for the severity distribution
+var severityFacet = facetQuery{key: "severity", column: "severity", numeric: true}
+
+// facetQueries lists the facets computed concurrently by GetFacets
+// High-cardinality fields are bounded to their most frequent values
+var facetQueries = []facetQuery{
+	severityFacet,
+	{key: "facility", column: "facility", numeric: true},
+	{key: "priority", column: priorityColumn, numeric: true, bounded: true},
+	{key: "procId", column: "procid", bounded: true},
+	{key: "msgId", column: "msgid", bounded: true},
+}
+
+// FacetKeys returns the keys of the facets computed by GetFacets, structured data facets included
+func FacetKeys() []string {
+	keys := make([]string, len(facetQueries))
+	for i, facet := range facetQueries {
+		keys[i] = facet.key
+	}
+
+	return keys
 }
 
+// defaultFacetLimit bounds the number of values returned for high-cardinality facets, unless SLOGGO_FACET_LIMIT is set
+const defaultFacetLimit = 50
+
+// facetLimit returns the requested facet limit, falling back to SLOGGO_FACET_LIMIT then to the default
+func facetLimit(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+	if utils.FacetLimit > 0 {
+		return int(utils.FacetLimit)
+	}
+
+	return defaultFacetLimit
+}
+
+// Facet values are sorted by descending count by default, or by ascending value for a stable order
+const (
+	FacetSortCount = "count"
+	FacetSortValue = "value"
+)
+
 // GetFacets retrieves facet metadata for filtering
-func GetFacets(filters map[string]any) (map[string]FacetMetadata, error) {
+// Bounded facets return up to limit values, a non-positive limit uses the configured default.
+// Bounded facets keep their most frequent values whatever the sort.
+func GetFacets(filters map[string]any, limit int, sortBy string) (map[string]FacetMetadata, error) {
+	limit = facetLimit(limit)
+
 	// For facets, exclude temporal filters (date range) to show total state
 	// This ensures live mode facets represent all logs, not just new ones
 	facetFilters := make(map[string]any)
@@ -351,131 +1070,174 @@ func GetFacets(filters map[string]any) (map[string]FacetMetadata, error) {
 		}
 	}
 
+	samplePercent := facetSamplePercent(tableOf(facetFilters))
+
+	cacheKey := fmt.Sprintf("%d|%t|%s|%s", limit, samplePercent > 0, sortBy, filtersCacheKey(facetFilters))
+	if cached, ok := facetCache.get(cacheKey); ok {
+		return cached.(map[string]FacetMetadata), nil
+	}
+	version := dataVersion.Load()
+
 	facets := make(map[string]FacetMetadata)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var globalErr error
 
 	// Fast direct queries in parallel
-	wg.Add(2)
-
-	// Get severity facets concurrently with highly optimized query
-	go func() {
-		defer wg.Done()
+	wg.Add(len(facetQueries))
 
-		query := "SELECT severity as value, COUNT(*) as total FROM logs"
-		args := []any{}
+	for _, facet := range facetQueries {
+		go func(facet facetQuery) {
+			defer wg.Done()
 
-		whereClause := buildWhereClause(facetFilters, time.Time{}, "", &args)
-		if whereClause != "" {
-			query += " WHERE " + whereClause
-		}
+			facetRows, othersCount, err := queryFacet(facet, facetFilters, limit, samplePercent, sortBy)
 
-		query += " GROUP BY severity"
-
-		rows, err := db.Query(query, args...)
-		if err != nil {
 			mu.Lock()
-			globalErr = fmt.Errorf("error querying severity facets: %v", err)
-			mu.Unlock()
-			return
-		}
-		defer rows.Close()
+			defer mu.Unlock()
 
-		facetRows := []FacetRow{}
-		for rows.Next() {
-			var row FacetRow
-			var valueStr string
-			err := rows.Scan(&valueStr, &row.Total)
 			if err != nil {
-				mu.Lock()
-				globalErr = fmt.Errorf("error scanning severity facet row: %v", err)
-				mu.Unlock()
+				globalErr = err
 				return
 			}
 
-			// Try to convert to integer if possible
-			if intVal, err := strconv.Atoi(valueStr); err == nil {
-				row.Value = intVal
-			} else {
-				row.Value = valueStr
+			facets[facet.key] = FacetMetadata{
+				Rows:        facetRows,
+				Approximate: samplePercent > 0,
+				OthersCount: othersCount,
 			}
+		}(facet)
+	}
 
-			facetRows = append(facetRows, row)
-		}
+	// Wait for all goroutines to complete
+	wg.Wait()
 
-		mu.Lock()
-		facets["severity"] = FacetMetadata{
-			Rows: facetRows,
-		}
-		mu.Unlock()
-	}()
+	// Check if any errors occurred
+	if globalErr != nil {
+		return nil, globalErr
+	}
+
+	facetCache.set(cacheKey, facets, version)
+
+	return facets, nil
+}
+
+// SeverityDistribution counts the logs per severity, sorted by severity
+// Unlike GetFacets, the date range filters apply
+func SeverityDistribution(filters map[string]any) ([]FacetRow, error) {
+	rows, _, err := queryFacet(severityFacet, filters, 0, 0, FacetSortValue)
+	return rows, err
+}
+
+// facetSamplePercent returns the percentage of the table facets are estimated from, 0 for exact facets
+// Tables above SLOGGO_FACET_SAMPLE_THRESHOLD rows are sampled down to about SLOGGO_FACET_SAMPLE_SIZE rows
+func facetSamplePercent(table string) float64 {
+	if utils.FacetSampleThreshold <= 0 || utils.FacetSampleSize <= 0 {
+		return 0
+	}
 
-	// Get facility facets concurrently
-	go func() {
-		defer wg.Done()
+	// The estimated size comes from the table metadata, without scanning it
+	var rows int64
+	if err := db.QueryRow("SELECT estimated_size FROM duckdb_tables() WHERE table_name = ?", table).Scan(&rows); err != nil {
+		log.Printf("Failed to estimate the size of %s, computing exact facets: %v", table, err)
+		return 0
+	}
 
-		query := "SELECT facility as value, COUNT(*) as total FROM logs"
-		args := []any{}
+	if rows <= utils.FacetSampleThreshold || rows <= utils.FacetSampleSize {
+		return 0
+	}
+	return float64(utils.FacetSampleSize) * 100 / float64(rows)
+}
+
+// queryFacet counts the logs per value of the facet column, and the logs of the values beyond the limit of bounded facets
+// With a sample percentage, the counts of the sampled logs are scaled up to estimate the totals
+func queryFacet(facet facetQuery, facetFilters map[string]any, limit int, samplePercent float64, sortBy string) ([]FacetRow, int, error) {
+	// The window total is computed over every value before the limit applies, so only the returned rows are held
+	query := fmt.Sprintf("SELECT %s as value, COUNT(*) as total, CAST(SUM(COUNT(*)) OVER () AS BIGINT) as all_total FROM %s", facet.column, tableOf(facetFilters))
+	if samplePercent > 0 {
+		// System sampling skips whole vectors of rows, which is much cheaper than a full scan
+		query += fmt.Sprintf(" TABLESAMPLE system(%.6f%%)", samplePercent)
+	}
+	args := []any{}
 
-		whereClause := buildWhereClause(facetFilters, time.Time{}, "", &args)
+	whereClause := buildWhereClause(facetFilters, time.Time{}, "", &args)
+	if facet.notNull {
 		if whereClause != "" {
-			query += " WHERE " + whereClause
+			whereClause += " AND "
 		}
+		whereClause += facet.column + " IS NOT NULL"
+	}
+	if exclusion := exclusionCondition(facet.key, facet.column, &args); exclusion != "" {
+		if whereClause != "" {
+			whereClause += " AND "
+		}
+		whereClause += exclusion
+	}
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
 
-		query += " GROUP BY facility"
+	query += fmt.Sprintf(" GROUP BY %s", facet.column)
 
-		rows, err := db.Query(query, args...)
-		if err != nil {
-			mu.Lock()
-			globalErr = fmt.Errorf("error querying facility facets: %v", err)
-			mu.Unlock()
-			return
-		}
-		defer rows.Close()
+	// Sort by frequency so bounded facets keep the most frequent values
+	query += " ORDER BY total DESC, value ASC"
+	if facet.bounded {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if sortBy == FacetSortValue {
+		query = "SELECT value, total, all_total FROM (" + query + ") ORDER BY value ASC"
+	}
 
-		facetRows := []FacetRow{}
-		for rows.Next() {
-			var row FacetRow
-			var valueStr string
-			err := rows.Scan(&valueStr, &row.Total)
-			if err != nil {
-				mu.Lock()
-				globalErr = fmt.Errorf("error scanning facility facet row: %v", err)
-				mu.Unlock()
-				return
-			}
+	release := acquireQuery()
+	defer release()
 
-			// Try to convert to integer if possible
-			if intVal, err := strconv.Atoi(valueStr); err == nil {
-				row.Value = intVal
-			} else {
-				row.Value = valueStr
-			}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying %s facets: %v", facet.key, err)
+	}
+	defer rows.Close()
 
-			facetRows = append(facetRows, row)
+	facetRows := []FacetRow{}
+	allTotal, returnedTotal := 0, 0
+	for rows.Next() {
+		var row FacetRow
+		var valueStr string
+		err := rows.Scan(&valueStr, &row.Total, &allTotal)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning %s facet row: %v", facet.key, err)
 		}
+		returnedTotal += row.Total
 
-		mu.Lock()
-		facets["facility"] = FacetMetadata{
-			Rows: facetRows,
+		if samplePercent > 0 {
+			row.Total = int(math.Round(float64(row.Total) * 100 / samplePercent))
 		}
-		mu.Unlock()
-	}()
 
-	// Wait for all goroutines to complete
-	wg.Wait()
+		// Try to convert to integer if possible
+		if intVal, err := strconv.Atoi(valueStr); facet.numeric && err == nil {
+			row.Value = intVal
+		} else {
+			row.Value = valueStr
+		}
 
-	// Check if any errors occurred
-	if globalErr != nil {
-		return nil, globalErr
+		facetRows = append(facetRows, row)
 	}
 
-	return facets, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading %s facet rows: %v", facet.key, err)
+	}
+
+	// The counts beyond the limit are summed in a single bucket, scaled like the other totals
+	othersCount := allTotal - returnedTotal
+	if samplePercent > 0 {
+		othersCount = int(math.Round(float64(othersCount) * 100 / samplePercent))
+	}
+
+	return facetRows, othersCount, nil
 }
 
 // GetChartData retrieves time-series data for charts
-func GetChartData(cursor time.Time, filters map[string]any) ([]ChartDataPoint, error) {
+// With the "delta" mode each point holds the difference from the previous point instead of absolute counts.
+// Only the series of the given severities are computed, all of them when none is given.
+func GetChartData(cursor time.Time, filters map[string]any, mode string, severities []int) ([]ChartDataPoint, error) {
 	chartFilters := make(map[string]any)
 	for k, v := range filters {
 		chartFilters[k] = v
@@ -490,40 +1252,44 @@ func GetChartData(cursor time.Time, filters map[string]any) ([]ChartDataPoint, e
 		chartFilters["startDate"] = startDate
 	}
 
+	var series uint8
+	for _, severity := range severities {
+		series |= 1 << severity
+	}
+
+	// The range is widened to whole buckets, so the requests made a few milliseconds apart share a cache key
 	startDate := chartFilters["startDate"].(time.Time)
 	endDate := chartFilters["endDate"].(time.Time)
-	duration := endDate.Sub(startDate)
+	truncateUnit := chartBucketUnit(endDate.Sub(startDate))
 
-	var truncateUnit string
+	startDate = truncateToBucket(startDate, truncateUnit)
+	if end := truncateToBucket(endDate, truncateUnit); end.Before(endDate) {
+		endDate = nextBucket(end, truncateUnit)
+	}
+	chartFilters["startDate"] = startDate
+	chartFilters["endDate"] = endDate
 
-	switch {
-	case duration <= 3*24*time.Hour: // Up to 3 days: group by hour (max 72 points)
-		truncateUnit = "hour"
-	case duration <= 21*24*time.Hour: // Up to 3 weeks: group by day (max 21 points)
-		truncateUnit = "day"
-	case duration <= 180*24*time.Hour: // Up to ~6 months: group by week (max 26 points)
-		truncateUnit = "week"
-	default: // More than 6 months: group by month
-		truncateUnit = "month"
+	// Absolute points are cached, deltas are derived from them
+	cacheKey := fmt.Sprintf("%sseries=%d;", filtersCacheKey(chartFilters), series)
+	if cached, ok := chartCache.get(cacheKey); ok {
+		return chartPoints(cached.([]ChartDataPoint), mode), nil
 	}
+	version := dataVersion.Load()
 
 	// Build query for chart data
 	queryBuilder := strings.Builder{}
 	args := []any{}
 
-	queryBuilder.WriteString(fmt.Sprintf(`
-		SELECT
-		    CAST(epoch(date_trunc('%s', timestamp)) * 1000 AS BIGINT) AS ts,
-			SUM(CASE WHEN severity = 7 THEN 1 ELSE 0 END) as debug,
-			SUM(CASE WHEN severity = 6 THEN 1 ELSE 0 END) as info,
-			SUM(CASE WHEN severity = 5 THEN 1 ELSE 0 END) as notice,
-			SUM(CASE WHEN severity = 4 THEN 1 ELSE 0 END) as warning,
-			SUM(CASE WHEN severity = 3 THEN 1 ELSE 0 END) as error,
-			SUM(CASE WHEN severity = 2 THEN 1 ELSE 0 END) as critical,
-			SUM(CASE WHEN severity = 1 THEN 1 ELSE 0 END) as alert,
-			SUM(CASE WHEN severity = 0 THEN 1 ELSE 0 END) as emergency
-		FROM logs
-	`, truncateUnit))
+	// Only the requested series are summed
+	selected := []int{}
+	queryBuilder.WriteString(fmt.Sprintf("SELECT CAST(epoch(date_trunc('%s', timestamp)) * 1000 AS BIGINT) AS ts", truncateUnit))
+	for _, column := range chartSeries {
+		if series == 0 || series&(1<<column.severity) != 0 {
+			selected = append(selected, column.severity)
+			queryBuilder.WriteString(fmt.Sprintf(", SUM(CASE WHEN severity = %d THEN 1 ELSE 0 END) as %s", column.severity, column.name))
+		}
+	}
+	queryBuilder.WriteString(" FROM " + tableOf(chartFilters))
 
 	// Add WHERE clause for filtering (excluding temporal constraints)
 	whereClause := buildWhereClause(chartFilters, time.Time{}, "", &args)
@@ -536,6 +1302,9 @@ func GetChartData(cursor time.Time, filters map[string]any) ([]ChartDataPoint, e
 	queryBuilder.WriteString(fmt.Sprintf(" GROUP BY date_trunc('%s', timestamp) ORDER BY ts ASC", truncateUnit))
 
 	// Execute query
+	release := acquireQuery()
+	defer release()
+
 	rows, err := db.Query(queryBuilder.String(), args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying chart data: %v", err)
@@ -545,26 +1314,110 @@ func GetChartData(cursor time.Time, filters map[string]any) ([]ChartDataPoint, e
 	// Parse results
 	chartData := []ChartDataPoint{}
 	for rows.Next() {
-		var point ChartDataPoint
-		err := rows.Scan(
-			&point.Timestamp,
-			&point.Debug,
-			&point.Info,
-			&point.Notice,
-			&point.Warning,
-			&point.Error,
-			&point.Critical,
-			&point.Alert,
-			&point.Emergency,
-		)
-		if err != nil {
+		point := ChartDataPoint{series: series}
+		destinations := []any{&point.Timestamp}
+		for _, severity := range selected {
+			destinations = append(destinations, point.count(severity))
+		}
+
+		if err := rows.Scan(destinations...); err != nil {
 			return nil, fmt.Errorf("error scanning chart data row: %v", err)
 		}
 
 		chartData = append(chartData, point)
 	}
 
-	return chartData, nil
+	chartCache.set(cacheKey, chartData, version)
+
+	return chartPoints(chartData, mode), nil
+}
+
+// chartBucketUnit returns the date_trunc unit grouping a chart range into a bounded number of points
+func chartBucketUnit(duration time.Duration) string {
+	switch {
+	case duration <= 3*time.Hour: // Up to 3 hours: group by minute (max 180 points)
+		return "minute"
+	case duration <= 3*24*time.Hour: // Up to 3 days: group by hour (max 72 points)
+		return "hour"
+	case duration <= 21*24*time.Hour: // Up to 3 weeks: group by day (max 21 points)
+		return "day"
+	case duration <= 180*24*time.Hour: // Up to ~6 months: group by week (max 26 points)
+		return "week"
+	default: // More than 6 months: group by month
+		return "month"
+	}
+}
+
+// truncateToBucket returns the start of the chart bucket holding the time, like date_trunc on the UTC timestamps
+// Weeks start on Monday
+func truncateToBucket(t time.Time, unit string) time.Time {
+	t = t.UTC()
+
+	switch unit {
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	}
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch unit {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// nextBucket returns the start of the chart bucket following the one starting at the time
+func nextBucket(start time.Time, unit string) time.Time {
+	switch unit {
+	case "minute":
+		return start.Add(time.Minute)
+	case "hour":
+		return start.Add(time.Hour)
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// chartPoints returns the points for the requested chart mode, without modifying the absolute points
+func chartPoints(points []ChartDataPoint, mode string) []ChartDataPoint {
+	if mode == "delta" {
+		return chartDeltas(points)
+	}
+
+	return slices.Clone(points)
+}
+
+// chartDeltas converts absolute counts to the per-severity difference from the previous point
+// The first point has a zero delta rather than a jump from nothing
+func chartDeltas(points []ChartDataPoint) []ChartDataPoint {
+	deltas := make([]ChartDataPoint, len(points))
+
+	for i, point := range points {
+		deltas[i].Timestamp = point.Timestamp
+		deltas[i].series = point.series
+		if i == 0 {
+			continue
+		}
+
+		previous := points[i-1]
+		deltas[i].Debug = point.Debug - previous.Debug
+		deltas[i].Info = point.Info - previous.Info
+		deltas[i].Notice = point.Notice - previous.Notice
+		deltas[i].Warning = point.Warning - previous.Warning
+		deltas[i].Error = point.Error - previous.Error
+		deltas[i].Critical = point.Critical - previous.Critical
+		deltas[i].Alert = point.Alert - previous.Alert
+		deltas[i].Emergency = point.Emergency - previous.Emergency
+	}
+
+	return deltas
 }
 
 // Helper function to build WHERE clause from filters
@@ -588,6 +1441,14 @@ func buildWhereClause(filters map[string]any, cursor time.Time, direction string
 				}
 				conditions = append(conditions, fmt.Sprintf("severity IN (%s)", strings.Join(placeholders, ",")))
 			}
+		case "minSeverity":
+			// Lower severities are more severe, a minimum keeps the less severe logs
+			conditions = append(conditions, "severity >= ?")
+			*args = append(*args, value.(int))
+		case "maxSeverity":
+			// A maximum keeps the more severe logs, e.g. 4 for warnings and worse
+			conditions = append(conditions, "severity <= ?")
+			*args = append(*args, value.(int))
 		case "facility":
 			facilities := value.([]int)
 
@@ -599,6 +1460,23 @@ func buildWhereClause(filters map[string]any, cursor time.Time, direction string
 				}
 				conditions = append(conditions, fmt.Sprintf("facility IN (%s)", strings.Join(placeholders, ",")))
 			}
+		case "priority":
+			priorities := value.([]int)
+
+			if len(priorities) > 0 {
+				placeholders := make([]string, len(priorities))
+				for i, p := range priorities {
+					placeholders[i] = "?"
+					*args = append(*args, p)
+				}
+				conditions = append(conditions, fmt.Sprintf("%s IN (%s)", priorityColumn, strings.Join(placeholders, ",")))
+			}
+		case "hasStructuredData":
+			if value.(bool) {
+				conditions = append(conditions, "structured_data IS NOT NULL")
+			} else {
+				conditions = append(conditions, "structured_data IS NULL")
+			}
 		case "hostname":
 			conditions = append(conditions, "hostname = ?")
 			*args = append(*args, value.(string))
@@ -611,19 +1489,78 @@ func buildWhereClause(filters map[string]any, cursor time.Time, direction string
 		case "msgId":
 			conditions = append(conditions, "msgid = ?")
 			*args = append(*args, value.(string))
+		case "search":
+			terms := value.([]string)
+			if len(terms) > 0 {
+				columns := []string{"msg"}
+				if fields, ok := filters["searchFields"].([]string); ok && len(fields) > 0 {
+					columns = columns[:0]
+					for _, field := range fields {
+						if column := searchColumns[field]; !slices.Contains(columns, column) {
+							columns = append(columns, column)
+						}
+					}
+				}
+
+				// Terms are ANDed unless the "any" search mode is requested
+				operator := " AND "
+				if mode, ok := filters["searchMode"].(string); ok && mode == "any" {
+					operator = " OR "
+				}
+				conditions = append(conditions, termsCondition(terms, columns, operator, args))
+			}
+		case "global":
+			if terms := value.([]string); len(terms) > 0 {
+				conditions = append(conditions, termsCondition(terms, globalColumns, " AND ", args))
+			}
+		case "hasMessage":
+			if value.(bool) {
+				conditions = append(conditions, "msg <> ''")
+			} else {
+				conditions = append(conditions, "(msg IS NULL OR msg = '')")
+			}
+		case "expression":
+			expression := value.(*Expression)
+			conditions = append(conditions, "("+expression.sql+")")
+			*args = append(*args, expression.args...)
+		case "structuredData":
+			for _, filter := range value.([]*StructuredDataFilter) {
+				conditions = append(conditions, fmt.Sprintf("json_extract_string(%s, ?) = ?", structuredDataColumn))
+				*args = append(*args, filter.jsonPath, filter.value)
+			}
 		case "startDate":
 			conditions = append(conditions, "timestamp >= ?")
 			*args = append(*args, value.(time.Time).Format(time.RFC3339Nano))
 		case "endDate":
 			conditions = append(conditions, "timestamp <= ?")
 			*args = append(*args, value.(time.Time).Format(time.RFC3339Nano))
+		case "receivedStartDate":
+			conditions = append(conditions, "received_at >= ?")
+			*args = append(*args, value.(time.Time).Format(time.RFC3339Nano))
+		case "receivedEndDate":
+			conditions = append(conditions, "received_at <= ?")
+			*args = append(*args, value.(time.Time).Format(time.RFC3339Nano))
+		case "sinceId":
+			conditions = append(conditions, "rowid > ?")
+			*args = append(*args, value.(int64))
+		case "fromId":
+			conditions = append(conditions, "rowid >= ?")
+			*args = append(*args, value.(int64))
+		case "toId":
+			conditions = append(conditions, "rowid <= ?")
+			*args = append(*args, value.(int64))
 		}
 	}
 
 	if !cursor.IsZero() {
-		if direction == "prev" {
+		switch direction {
+		case "prev":
 			conditions = append(conditions, "timestamp > ?")
-		} else {
+		case "tail":
+			// Cursors have a millisecond precision, skip the whole cursor millisecond
+			conditions = append(conditions, "timestamp >= ?")
+			cursor = cursor.Truncate(time.Millisecond).Add(time.Millisecond)
+		default:
 			conditions = append(conditions, "timestamp < ?")
 		}
 		*args = append(*args, cursor.Format(time.RFC3339Nano))
@@ -631,3 +1568,41 @@ func buildWhereClause(filters map[string]any, cursor time.Time, direction string
 
 	return strings.Join(conditions, " AND ")
 }
+
+// searchColumns maps the fields the search terms can be matched against to their database columns
+// Structured data is searched in its stored JSON form, keys included, "msg" is an alias of "message"
+var searchColumns = map[string]string{
+	"message":        "msg",
+	"msg":            "msg",
+	"structuredData": "structured_data",
+}
+
+// globalColumns lists the columns matched by the global search box, structured data is left out as it's slower to scan
+var globalColumns = []string{"msg", "hostname", "app_name", "procid", "msgid"}
+
+// termsCondition matches the terms with the operator, a term matching when any of the columns contains it
+func termsCondition(terms []string, columns []string, operator string, args *[]any) string {
+	termConditions := make([]string, len(terms))
+	for i, term := range terms {
+		columnConditions := make([]string, len(columns))
+		for j, column := range columns {
+			columnConditions[j] = column + ` ILIKE ? ESCAPE '\'`
+			*args = append(*args, "%"+escapeLikePattern(term)+"%")
+		}
+		termConditions[i] = "(" + strings.Join(columnConditions, " OR ") + ")"
+	}
+
+	return "(" + strings.Join(termConditions, operator) + ")"
+}
+
+// IsSearchField reports whether the search terms can be matched against a field
+func IsSearchField(field string) bool {
+	_, ok := searchColumns[field]
+	return ok
+}
+
+// escapeLikePattern escapes LIKE wildcards so search terms are matched literally
+func escapeLikePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
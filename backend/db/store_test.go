@@ -1,7 +1,18 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sloggo/formats"
 	"sloggo/models"
+	"sloggo/utils"
+	"strings"
 	"testing"
 	"time"
 )
@@ -16,7 +27,7 @@ func TestStoreLogEntry(t *testing.T) {
 		AppName:        "test-app",
 		ProcID:         "1234",
 		MsgID:          "5678",
-		StructuredData: "-",
+		StructuredData: "",
 		Message:        "Test message",
 	}
 
@@ -32,7 +43,7 @@ func TestStoreLogEntry(t *testing.T) {
 
 	db := GetDBInstance()
 	rows, err := db.Query(`
-		SELECT severity, facility, version, hostname, app_name, procid, msgid, structured_data, msg
+		SELECT severity, facility, version, hostname, app_name, procid, msgid, COALESCE(structured_data, ''), msg
 		FROM logs
 		WHERE hostname = ? AND app_name = ? AND msg = ?
 	`, entry.Hostname, entry.AppName, entry.Message)
@@ -160,3 +171,1205 @@ func TestBatchProcessing(t *testing.T) {
 		t.Errorf("Expected at least %d entries in database, got %d", len(entries), count)
 	}
 }
+
+func TestTrimExcessLogs(t *testing.T) {
+	originalMaxRows := utils.MaxRows
+	defer func() {
+		utils.MaxRows = originalMaxRows
+	}()
+
+	now := time.Now()
+	for i := range 5 {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(time.Duration(i) * time.Second),
+			Hostname:       "trim-host",
+			AppName:        "trim-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Trim message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	db := GetDBInstance()
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&total); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+
+	// Keep everything except the two oldest rows
+	utils.MaxRows = total - 2
+
+	if err := trimExcessLogs(); err != nil {
+		t.Fatalf("Failed to trim logs: %v", err)
+	}
+
+	var remaining int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&remaining); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+
+	if remaining != utils.MaxRows {
+		t.Errorf("Expected %d rows after trim, got %d", utils.MaxRows, remaining)
+	}
+
+	var newest int
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs WHERE msg = ?", "Trim message 4").Scan(&newest); err != nil {
+		t.Fatalf("Failed to query newest log: %v", err)
+	}
+
+	if newest != 1 {
+		t.Error("Expected newest log entry to survive the trim")
+	}
+}
+
+func TestSearchModes(t *testing.T) {
+	messages := []string{
+		"Connection timeout to upstream",
+		"Connection refused by upstream",
+		"Connection timeout and refused",
+		"Everything is fine",
+	}
+
+	for _, message := range messages {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "search-host",
+			AppName:        "search-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        message,
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected int
+	}{
+		{"all terms must match", "all", 1},
+		{"any term can match", "any", 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{
+				"appName":    "search-app",
+				"search":     []string{"TIMEOUT", "refused"},
+				"searchMode": tc.mode,
+			}
+
+			logs, _, filterCount, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			if filterCount != tc.expected || len(logs) != tc.expected {
+				t.Errorf("Expected %d logs, got %d (filtered count %d)", tc.expected, len(logs), filterCount)
+			}
+		})
+	}
+}
+
+func TestSearchStructuredData(t *testing.T) {
+	for i, structuredData := range []string{`{"request":{"id":"req-8f3a2c"}}`, "-"} {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "sd-search-host",
+			AppName:        "sd-search-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: structuredData,
+			Message:        fmt.Sprintf("Handled request %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		search   []string
+		fields   []string
+		expected int
+	}{
+		{"message only by default", []string{"8F3A2C"}, nil, 0},
+		{"structured data value", []string{"8F3A2C"}, []string{"structuredData"}, 1},
+		{"terms across fields", []string{"handled", "req-8f3a2c"}, []string{"message", "structuredData"}, 1},
+		{"message still searched", []string{"request"}, []string{"message", "structuredData"}, 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{
+				"appName":    "sd-search-app",
+				"search":     tc.search,
+				"searchMode": "all",
+			}
+			if tc.fields != nil {
+				filters["searchFields"] = tc.fields
+			}
+
+			logs, _, filterCount, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			if filterCount != tc.expected || len(logs) != tc.expected {
+				t.Errorf("Expected %d logs, got %d (filtered count %d)", tc.expected, len(logs), filterCount)
+			}
+		})
+	}
+}
+
+func TestGlobalSearch(t *testing.T) {
+	entries := []models.LogEntry{
+		{Hostname: "global-db-1", AppName: "global-app", ProcID: "-", MsgID: "-", Message: "Connection timeout"},
+		{Hostname: "global-web-1", AppName: "global-app", ProcID: "-", MsgID: "-", Message: "Timeout talking to global-db-1"},
+		{Hostname: "global-web-2", AppName: "global-app", ProcID: "4242", MsgID: "GLOBALID", Message: "Request served"},
+		{Hostname: "global-web-3", AppName: "global-app", ProcID: "-", MsgID: "-", StructuredData: `{"trace":{"id":"global-sd-only"}}`, Message: "Traced"},
+	}
+	for _, entry := range entries {
+		entry.Severity = 6
+		entry.Facility = 1
+		entry.Version = 1
+		entry.Timestamp = time.Now()
+		if err := StoreLog(entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		global   []string
+		expected int
+	}{
+		{"hostname or message", []string{"GLOBAL-DB-1"}, 2},
+		{"terms are ANDed", []string{"global-db-1", "connection"}, 1},
+		{"app name", []string{"global-app"}, 4},
+		{"process ID", []string{"4242"}, 1},
+		{"message ID", []string{"globalid"}, 1},
+		{"structured data not searched", []string{"global-sd-only"}, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{"appName": "global-app", "global": tc.global}
+
+			logs, _, filterCount, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			if filterCount != tc.expected || len(logs) != tc.expected {
+				t.Errorf("Expected %d logs, got %d (filtered count %d)", tc.expected, len(logs), filterCount)
+			}
+		})
+	}
+}
+
+func TestHasMessageFilter(t *testing.T) {
+	for _, message := range []string{"Header and body", "", "Another body"} {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "empty-host",
+			AppName:        "empty-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        message,
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		hasMessage bool
+		expected   int
+	}{
+		{"with a message", true, 2},
+		{"without a message", false, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{
+				"appName":    "empty-app",
+				"hasMessage": tc.hasMessage,
+			}
+
+			logs, _, filterCount, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			if filterCount != tc.expected || len(logs) != tc.expected {
+				t.Errorf("Expected %d logs, got %d (filtered count %d)", tc.expected, len(logs), filterCount)
+			}
+		})
+	}
+}
+
+func TestFacetsProcIdAndMsgId(t *testing.T) {
+	procIDs := []string{"facet-1", "facet-2", "facet-2", "facet-2", "facet-3", "facet-3"}
+
+	for _, procID := range procIDs {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "facet-host",
+			AppName:        "facet-app",
+			ProcID:         procID,
+			MsgID:          "FACET",
+			StructuredData: "-",
+			Message:        "Facet message",
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	facets, err := GetFacets(map[string]any{"appName": "facet-app"}, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	procIdRows := facets["procId"].Rows
+	if len(procIdRows) != 3 {
+		t.Fatalf("Expected 3 procId facet rows, got %d", len(procIdRows))
+	}
+
+	// Rows are sorted by frequency
+	if procIdRows[0].Value != "facet-2" || procIdRows[0].Total != 3 {
+		t.Errorf("Expected most frequent procId facet-2 with 3 logs, got %v with %d", procIdRows[0].Value, procIdRows[0].Total)
+	}
+
+	msgIdRows := facets["msgId"].Rows
+	if len(msgIdRows) != 1 || msgIdRows[0].Value != "FACET" || msgIdRows[0].Total != len(procIDs) {
+		t.Errorf("Unexpected msgId facet rows: %+v", msgIdRows)
+	}
+
+	if othersCount := facets["procId"].OthersCount; othersCount != 0 {
+		t.Errorf("Expected no others count when every value is returned, got %d", othersCount)
+	}
+
+	// A lower limit keeps the most frequent values
+	facets, err = GetFacets(map[string]any{"appName": "facet-app"}, 2, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	procIdRows = facets["procId"].Rows
+	if len(procIdRows) != 2 || procIdRows[0].Value != "facet-2" {
+		t.Errorf("Expected the 2 most frequent procId facet rows, got %+v", procIdRows)
+	}
+
+	// The logs of the values beyond the limit are summed, facet-1 here
+	if othersCount := facets["procId"].OthersCount; othersCount != 1 {
+		t.Errorf("Expected an others count of 1 beyond the limit, got %d", othersCount)
+	}
+
+	facets, err = GetFacets(map[string]any{"appName": "facet-app"}, 2, FacetSortValue)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	if othersCount := facets["procId"].OthersCount; othersCount != 1 {
+		t.Errorf("Expected the same others count sorted by value, got %d", othersCount)
+	}
+
+	// Sorting by value gives a stable order
+	facets, err = GetFacets(map[string]any{"appName": "facet-app"}, 0, FacetSortValue)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	procIdRows = facets["procId"].Rows
+	if len(procIdRows) != 3 || procIdRows[0].Value != "facet-1" || procIdRows[1].Value != "facet-2" || procIdRows[2].Value != "facet-3" {
+		t.Errorf("Expected procId facet rows sorted by value, got %+v", procIdRows)
+	}
+}
+
+func TestGetLogsTail(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	offsets := []time.Duration{
+		0,
+		time.Second,
+		2 * time.Second,
+		2*time.Second + 100*time.Microsecond, // Same millisecond as the previous row
+		3 * time.Second,
+	}
+
+	for i, offset := range offsets {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      base.Add(offset),
+			Hostname:       "tail-host",
+			AppName:        "tail-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Tail message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "tail-app"}
+
+	// Rows strictly newer than the cursor, oldest first
+	logs, _, _, err := GetLogs(10, base, "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	expected := []string{"Tail message 1", "Tail message 2", "Tail message 3", "Tail message 4"}
+	if len(logs) != len(expected) {
+		t.Fatalf("Expected %d logs, got %d", len(expected), len(logs))
+	}
+	for i, message := range expected {
+		if logs[i].Message != message {
+			t.Errorf("Row %d: got %q, want %q", i, logs[i].Message, message)
+		}
+	}
+
+	// A full page doesn't split the millisecond shared by messages 2 and 3
+	logs, _, _, hasMore, err := GetLogsPage(2, base, "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 1 || logs[0].Message != "Tail message 1" || !hasMore {
+		t.Fatalf("Expected only the first row before the shared millisecond with more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+
+	// Following with the last row's millisecond returns the next rows, a page ending
+	// with the last row of its millisecond is returned whole
+	logs, _, _, hasMore, err = GetLogsPage(2, logs[0].Timestamp.Truncate(time.Millisecond), "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 2 || logs[0].Message != "Tail message 2" || logs[1].Message != "Tail message 3" || !hasMore {
+		t.Fatalf("Expected the two rows sharing a millisecond with more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+
+	logs, _, _, hasMore, err = GetLogsPage(2, logs[1].Timestamp.Truncate(time.Millisecond), "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 1 || logs[0].Message != "Tail message 4" || hasMore {
+		t.Errorf("Expected the last row without more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+
+	// Exactly a page of new rows ending with the shared millisecond is returned whole
+	logs, _, _, hasMore, err = GetLogsPage(3, logs[0].Timestamp.Add(-2*time.Second).Truncate(time.Millisecond), "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 3 || logs[0].Message != "Tail message 2" || logs[2].Message != "Tail message 4" || hasMore {
+		t.Errorf("Expected the 3 last rows without more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+}
+
+func TestGetLogsTailPageBoundary(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	// The two last rows share their millisecond
+	for i, offset := range []time.Duration{time.Second, 2 * time.Second, 2*time.Second + 100*time.Microsecond} {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      base.Add(offset),
+			Hostname:       "tail-boundary-host",
+			AppName:        "tail-boundary-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Boundary message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "tail-boundary-app"}
+
+	// Exactly a page of new rows, nothing is held back
+	logs, _, _, hasMore, err := GetLogsPage(3, base, "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(logs) != 3 || hasMore {
+		t.Errorf("Expected the 3 rows without more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+
+	// One row short, the shared millisecond is held back and reported as more to come
+	logs, _, _, hasMore, err = GetLogsPage(2, base, "tail", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Message != "Boundary message 0" || !hasMore {
+		t.Errorf("Expected the first row with more to come, got %d rows and hasMore %v", len(logs), hasMore)
+	}
+}
+
+func TestGetLogsPageHasMore(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	for i := range 4 {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      base.Add(time.Duration(i) * time.Second),
+			Hostname:       "page-host",
+			AppName:        "page-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Page message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "page-app"}
+
+	// The first page of two leaves two more logs
+	logs, _, _, hasMore, err := GetLogsPage(2, time.Time{}, "next", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 2 || !hasMore {
+		t.Fatalf("Expected 2 logs with more to come, got %d logs and hasMore %v", len(logs), hasMore)
+	}
+
+	// The second page ends exactly on the last log, the extra row isn't returned
+	logs, _, _, hasMore, err = GetLogsPage(2, logs[1].Timestamp, "next", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 2 || hasMore {
+		t.Fatalf("Expected the 2 last logs without more to come, got %d logs and hasMore %v", len(logs), hasMore)
+	}
+
+	if logs[1].Message != "Page message 0" {
+		t.Errorf("Expected the oldest log last, got %q", logs[1].Message)
+	}
+
+	// The newer logs precede the second page, nothing follows the newest log
+	if behind, err := HasLogsBehind(logs[0].Timestamp.Add(time.Millisecond), "next", filters); err != nil || !behind {
+		t.Errorf("Expected logs behind the second page, got %v, %v", behind, err)
+	}
+	if behind, err := HasLogsBehind(base.Add(3*time.Second), "prev", filters); err != nil || !behind {
+		t.Errorf("Expected logs behind a previous page, got %v, %v", behind, err)
+	}
+	if behind, err := HasLogsBehind(base.Add(-time.Second), "prev", filters); err != nil || behind {
+		t.Errorf("Expected no logs behind the oldest log, got %v, %v", behind, err)
+	}
+	if behind, err := HasLogsBehind(time.Time{}, "next", filters); err != nil || behind {
+		t.Errorf("Expected no logs behind a page without a cursor, got %v, %v", behind, err)
+	}
+}
+
+func TestBatchStats(t *testing.T) {
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	err := StoreLog(models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "stats-host",
+		AppName:        "stats-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Pending message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+
+	stats := GetBatchStats()
+	if stats.PendingEntries != 1 {
+		t.Errorf("Expected 1 pending entry, got %d", stats.PendingEntries)
+	}
+	if stats.Stalled {
+		t.Error("Expected a recently flushed processor not to be stalled")
+	}
+
+	// Pretend the last flush happened several intervals ago
+	lastFlushTime.Store(time.Now().Add(-(stalledFlushIntervals + 1) * batchFlushInterval).UnixNano())
+	if !GetBatchStats().Stalled {
+		t.Error("Expected pending entries without a recent flush to be reported as stalled")
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	stats = GetBatchStats()
+	if stats.PendingEntries != 0 || stats.Stalled {
+		t.Errorf("Expected an empty, healthy batch after flushing, got %+v", stats)
+	}
+}
+
+func TestProcessBatchPeriodically(t *testing.T) {
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	entry := models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "periodic-host",
+		AppName:        "periodic-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Periodic message",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		processBatchPeriodically(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	if err := StoreLog(entry); err != nil {
+		cancel()
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for GetBatchStats().PendingEntries > 0 {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("Expected the running processor to flush the pending entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the processor to return once its context is canceled")
+	}
+
+	// Nothing flushes the batch once the processor stopped
+	if err := StoreLog(entry); err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if pending := GetBatchStats().PendingEntries; pending != 1 {
+		t.Errorf("Expected the entry to stay pending after stopping the processor, got %d pending", pending)
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+}
+
+func TestChartDeltas(t *testing.T) {
+	points := []ChartDataPoint{
+		{Timestamp: 1000, Info: 5, Error: 1},
+		{Timestamp: 2000, Info: 8, Error: 0},
+		{Timestamp: 3000, Info: 2, Error: 4},
+	}
+
+	deltas := chartDeltas(points)
+
+	expected := []ChartDataPoint{
+		{Timestamp: 1000},
+		{Timestamp: 2000, Info: 3, Error: -1},
+		{Timestamp: 3000, Info: -6, Error: 4},
+	}
+
+	if len(deltas) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(deltas))
+	}
+	for i := range expected {
+		if deltas[i] != expected[i] {
+			t.Errorf("Point %d: expected %+v, got %+v", i, expected[i], deltas[i])
+		}
+	}
+}
+
+func TestBatchOnErrorPolicy(t *testing.T) {
+	originalPolicy := utils.BatchOnError
+	defer func() {
+		utils.BatchOnError = originalPolicy
+	}()
+
+	newEntries := func(appName string) []models.LogEntry {
+		entries := make([]models.LogEntry, 3)
+		for i := range entries {
+			entries[i] = models.LogEntry{
+				Severity:       6,
+				Facility:       1,
+				Version:        1,
+				Timestamp:      time.Now(),
+				Hostname:       "policy-host",
+				AppName:        appName,
+				ProcID:         "-",
+				MsgID:          "-",
+				StructuredData: "-",
+				Message:        fmt.Sprintf("Policy message %d", i),
+			}
+		}
+
+		// The middle entry is deliberately invalid
+		entries[1].Message = "Invalid \xff UTF-8"
+		return entries
+	}
+
+	countRows := func(appName string) int {
+		var count int
+		if err := GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE app_name = ?", appName).Scan(&count); err != nil {
+			t.Fatalf("Failed to count logs: %v", err)
+		}
+		return count
+	}
+
+	// The skip policy keeps the valid rows
+	utils.BatchOnError = "skip"
+	skippedBefore := GetBatchStats().SkippedRows
+
+	if err := processBatchStoreLogsWithEntries(newEntries("policy-skip")); err != nil {
+		t.Fatalf("Expected the invalid row to be skipped, got error: %v", err)
+	}
+	if count := countRows("policy-skip"); count != 2 {
+		t.Errorf("Expected 2 stored rows, got %d", count)
+	}
+	if skipped := GetBatchStats().SkippedRows - skippedBefore; skipped != 1 {
+		t.Errorf("Expected 1 skipped row, got %d", skipped)
+	}
+
+	// The abort policy reports the error
+	utils.BatchOnError = "abort"
+	if err := processBatchStoreLogsWithEntries(newEntries("policy-abort")); err == nil {
+		t.Error("Expected an error with the abort policy")
+	}
+}
+
+func TestApplyDatabaseSettings(t *testing.T) {
+	originalMemoryLimit := utils.DuckDBMemoryLimit
+	originalThreads := utils.DuckDBThreads
+	defer func() {
+		utils.DuckDBMemoryLimit = originalMemoryLimit
+		utils.DuckDBThreads = originalThreads
+	}()
+
+	if !memoryLimitRegex.MatchString("512mb") || !memoryLimitRegex.MatchString("1.5 gib") {
+		t.Error("Expected valid memory limits to match")
+	}
+	if memoryLimitRegex.MatchString("512'; DROP TABLE logs; --") || memoryLimitRegex.MatchString("lots") {
+		t.Error("Expected invalid memory limits not to match")
+	}
+
+	utils.DuckDBMemoryLimit = "1gb"
+	utils.DuckDBThreads = 2
+	applyDatabaseSettings()
+
+	var threads int
+	if err := GetDBInstance().QueryRow("SELECT CAST(current_setting('threads') AS INTEGER)").Scan(&threads); err != nil {
+		t.Fatalf("Failed to read threads setting: %v", err)
+	}
+	if threads != 2 {
+		t.Errorf("Expected 2 threads, got %d", threads)
+	}
+}
+
+func TestExtensionNames(t *testing.T) {
+	names := extensionNames(" httpfs, ,json,bad;name,aws ")
+	if !slices.Equal(names, []string{"httpfs", "json", "aws"}) {
+		t.Errorf("Expected the valid extension names, got %v", names)
+	}
+
+	if quoted := quoteLiteral("it's"); quoted != "'it''s'" {
+		t.Errorf("Expected quotes to be escaped, got %s", quoted)
+	}
+
+	// Setting credentials without httpfs loaded is logged, not fatal
+	originalRegion := utils.S3Region
+	defer func() { utils.S3Region = originalRegion }()
+	utils.S3Region = "eu-west-1"
+	applyS3Settings()
+}
+
+func TestPriorityFilterAndFacet(t *testing.T) {
+	// <34> is facility 4 (auth) and severity 2 (critical)
+	for _, line := range []string{
+		"<34>Oct 11 22:14:15 priority-host su: 'su root' failed",
+		"<38>Oct 11 22:14:16 priority-host su: Session opened",
+		"<38>Oct 11 22:14:17 priority-host su: Session closed",
+	} {
+		entry, err := formats.ParseRFC3164ToLogEntry(line)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", line, err)
+		}
+		// Keep the logs within the retention period regardless of the test date
+		entry.Timestamp = time.Now()
+		if err := StoreLog(*entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{
+		"hostname": "priority-host",
+		"priority": []int{34},
+	}
+	logs, _, filterCount, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if filterCount != 1 || len(logs) != 1 || logs[0].Priority() != 34 {
+		t.Fatalf("Expected the log with priority 34, got %d logs (filtered count %d)", len(logs), filterCount)
+	}
+
+	facets, err := GetFacets(map[string]any{"hostname": "priority-host"}, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	expected := []FacetRow{{Value: 38, Total: 2}, {Value: 34, Total: 1}}
+	if !reflect.DeepEqual(facets["priority"].Rows, expected) {
+		t.Errorf("Expected priority facet rows %v, got %v", expected, facets["priority"].Rows)
+	}
+}
+
+func TestSeverityRangeFilters(t *testing.T) {
+	// One log for each severity, from emergency (0) to debug (7)
+	for severity := range uint8(8) {
+		err := StoreLog(models.LogEntry{
+			Severity:       severity,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "range-host",
+			AppName:        "range-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Severity %d message", severity),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filters  map[string]any
+		expected []uint8
+	}{
+		// Lower severities are more severe, so a maximum keeps the worst logs
+		{"warning and worse", map[string]any{"maxSeverity": 4}, []uint8{0, 1, 2, 3, 4}},
+		{"notice and less severe", map[string]any{"minSeverity": 5}, []uint8{5, 6, 7}},
+		{"between error and warning", map[string]any{"minSeverity": 3, "maxSeverity": 4}, []uint8{3, 4}},
+		{"combined with a severity list", map[string]any{"maxSeverity": 4, "severity": []int{1, 4, 6}}, []uint8{1, 4}},
+		{"empty range", map[string]any{"minSeverity": 5, "maxSeverity": 4}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{"hostname": "range-host"}
+			maps.Copy(filters, tc.filters)
+
+			logs, _, _, err := GetLogs(50, time.Time{}, "next", filters, "severity", "ASC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			var severities []uint8
+			for _, log := range logs {
+				severities = append(severities, log.Severity)
+			}
+			if !slices.Equal(severities, tc.expected) {
+				t.Errorf("Expected severities %v, got %v", tc.expected, severities)
+			}
+		})
+	}
+}
+
+func TestOpenWithRetry(t *testing.T) {
+	// The storage becomes available on the third attempt
+	attempts := 0
+	database, err := openWithRetry(func() (*sql.DB, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("read-only file system")
+		}
+		return GetDBInstance(), nil
+	}, 5, time.Millisecond)
+	if err != nil || database == nil {
+		t.Fatalf("Expected the database once available, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	// The last error is returned once the retries are exhausted
+	attempts = 0
+	_, err = openWithRetry(func() (*sql.DB, error) {
+		attempts++
+		return nil, errors.New("read-only file system")
+	}, 2, time.Millisecond)
+	if err == nil {
+		t.Error("Expected an error after the retries")
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestReceivedAtFilter(t *testing.T) {
+	now := time.Now()
+
+	entries := []models.LogEntry{
+		// A device with a clock two days late, received now
+		{Timestamp: now.Add(-48 * time.Hour), Message: "Late clock"},
+		// A log received three hours ago, whose event time is now
+		{Timestamp: now, ReceivedAt: now.Add(-3 * time.Hour), Message: "Early receive"},
+	}
+	for _, entry := range entries {
+		entry.Severity = 6
+		entry.Facility = 1
+		entry.Version = 1
+		entry.Hostname = "received-host"
+		entry.AppName = "received-app"
+		entry.ProcID = "-"
+		entry.MsgID = "-"
+		if err := StoreLog(entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filters  map[string]any
+		expected string
+	}{
+		{"received in the last hour", map[string]any{"receivedStartDate": now.Add(-time.Hour), "receivedEndDate": now.Add(time.Minute)}, "Late clock"},
+		{"event in the last hour", map[string]any{"startDate": now.Add(-time.Hour), "endDate": now.Add(time.Minute)}, "Early receive"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{"hostname": "received-host"}
+			maps.Copy(filters, tc.filters)
+
+			logs, _, _, err := GetLogs(50, time.Time{}, "next", filters, "received_at", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+			if len(logs) != 1 || logs[0].Message != tc.expected {
+				t.Fatalf("Expected only %q, got %+v", tc.expected, logs)
+			}
+			if logs[0].ReceivedAt.IsZero() {
+				t.Error("Expected the received time to be stored")
+			}
+		})
+	}
+}
+
+func TestExplainLogs(t *testing.T) {
+	filters := map[string]any{"hostname": "explain-host", "severity": []int{3, 4}}
+
+	plan, err := ExplainLogs(50, time.Now(), "next", filters, "timestamp", "DESC")
+	if err != nil {
+		t.Fatalf("Failed to explain logs query: %v", err)
+	}
+	if strings.TrimSpace(plan) == "" {
+		t.Error("Expected a query plan")
+	}
+}
+
+func TestChartSeverities(t *testing.T) {
+	now := time.Now()
+	for i, severity := range []uint8{3, 3, 4, 6} {
+		entry := models.LogEntry{
+			Severity:  severity,
+			Facility:  1,
+			Version:   1,
+			Timestamp: now.Add(-time.Duration(i) * time.Second),
+			Hostname:  "chart-series-host",
+			AppName:   "chart-series-app",
+			ProcID:    "-",
+			MsgID:     "-",
+			Message:   fmt.Sprintf("Chart series message %d", i),
+		}
+		if err := StoreLog(entry); err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "chart-series-app"}
+	points, err := GetChartData(now, filters, "absolute", []int{3, 4})
+	if err != nil {
+		t.Fatalf("Failed to get chart data: %v", err)
+	}
+
+	var errorCount, warningCount, infoCount int
+	for _, point := range points {
+		errorCount += point.Error
+		warningCount += point.Warning
+		infoCount += point.Info
+	}
+	if errorCount != 2 || warningCount != 1 || infoCount != 0 {
+		t.Errorf("Expected 2 errors, 1 warning and no computed info, got %d, %d and %d", errorCount, warningCount, infoCount)
+	}
+
+	// The unrequested series are omitted from the response
+	encoded, err := json.Marshal(points[0])
+	if err != nil {
+		t.Fatalf("Failed to encode chart point: %v", err)
+	}
+	var fields map[string]int64
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("Failed to decode chart point: %v", err)
+	}
+	if keys := slices.Sorted(maps.Keys(fields)); !slices.Equal(keys, []string{"error", "timestamp", "warning"}) {
+		t.Errorf("Expected only the requested series, got %v", keys)
+	}
+
+	// All series are computed without a selection
+	all, err := GetChartData(now, filters, "absolute", nil)
+	if err != nil {
+		t.Fatalf("Failed to get chart data: %v", err)
+	}
+	encoded, err = json.Marshal(all[0])
+	if err != nil {
+		t.Fatalf("Failed to encode chart point: %v", err)
+	}
+	fields = map[string]int64{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("Failed to decode chart point: %v", err)
+	}
+	if len(fields) != 9 {
+		t.Errorf("Expected the timestamp and the 8 series, got %v", fields)
+	}
+}
+
+func TestTables(t *testing.T) {
+	originalTables := tables
+	defer func() { tables = originalTables }()
+
+	addTables(" logs_tenant_a, Bad-Name,logs, logs_tenant_a")
+	if !slices.Equal(tables, []string{"logs", "logs_tenant_a"}) {
+		t.Fatalf("Expected the valid tables once, got %v", tables)
+	}
+	setupDatabaseTable("logs_tenant_a")
+
+	entry := models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "tenant-host",
+		AppName:        "tenant-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Tenant message",
+		Table:          "logs_tenant_a",
+	}
+	if err := StoreLog(entry); err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	tenantFilters := map[string]any{"hostname": "tenant-host", "table": "logs_tenant_a"}
+	logs, totalCount, _, err := GetLogs(10, time.Now().Add(time.Minute), "next", tenantFilters, "", "")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(logs) != 1 || totalCount != 1 {
+		t.Errorf("Expected the log in its table, got %d logs of %d", len(logs), totalCount)
+	}
+
+	logs, _, _, err = GetLogs(10, time.Now().Add(time.Minute), "next", map[string]any{"hostname": "tenant-host"}, "", "")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("Expected the default table not to hold the log, got %d logs", len(logs))
+	}
+
+	facets, err := GetFacets(tenantFilters, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+	if rows := facets["severity"].Rows; len(rows) != 1 || rows[0].Total != 1 {
+		t.Errorf("Expected the facets of the table, got %+v", rows)
+	}
+
+	entry.Table = "logs_unknown"
+	if err := StoreLog(entry); err == nil {
+		t.Error("Expected an error storing in an unknown table")
+	}
+}
+
+func TestFacetSampling(t *testing.T) {
+	originalThreshold, originalSize := utils.FacetSampleThreshold, utils.FacetSampleSize
+	defer func() {
+		utils.FacetSampleThreshold, utils.FacetSampleSize = originalThreshold, originalSize
+	}()
+
+	for i := range 3 {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "sample-host",
+			AppName:        "sample-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Sampled message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filters := map[string]any{"appName": "sample-app"}
+
+	// A sample at least as large as the table is the table itself
+	utils.FacetSampleThreshold, utils.FacetSampleSize = 1, 1000000
+	facets, err := GetFacets(filters, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+	if facets["severity"].Approximate {
+		t.Error("Expected exact facets when the sample covers the table")
+	}
+	if rows := facets["severity"].Rows; len(rows) != 1 || rows[0].Total != 3 {
+		t.Errorf("Expected exact counts, got %+v", rows)
+	}
+
+	utils.FacetSampleSize = 1
+	facets, err = GetFacets(filters, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get sampled facets: %v", err)
+	}
+	if !facets["severity"].Approximate || !facets["procId"].Approximate {
+		t.Error("Expected facets above the threshold to be flagged as approximate")
+	}
+}
+
+func TestIsReadyQueriesDatabase(t *testing.T) {
+	if !IsReady() {
+		t.Fatal("Expected the open database to be ready")
+	}
+
+	original := db
+	defer func() { db = original }()
+
+	closed, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	closed.Close()
+	db = closed
+
+	if IsReady() {
+		t.Error("Expected a database that can't be queried not to be ready")
+	}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sloggo/utils"
+)
+
+// maxStructuredDataPathDepth bounds the number of segments of a structured data path
+const maxStructuredDataPathDepth = 8
+
+// structuredDataSegmentRegex matches a single segment of a dotted path, SD-IDs such as "origin@32473" included
+var structuredDataSegmentRegex = regexp.MustCompile(`^[A-Za-z0-9_@-]+$`)
+
+// structuredDataColumn is the structured data as JSON, NULL for logs without structured data
+// Values that aren't valid JSON would make the JSON functions fail
+const structuredDataColumn = "CASE WHEN json_valid(structured_data) THEN structured_data END"
+
+// StructuredDataFilter matches logs whose structured data holds a value at a dotted path, e.g. "kubernetes.pod_name"
+type StructuredDataFilter struct {
+	jsonPath string
+	value    string
+}
+
+func init() {
+	addStructuredDataFacets(utils.StructuredDataFacets)
+}
+
+// addStructuredDataFacets adds a facet for each comma-separated dotted path, such as "exampleSDID@32473.iut"
+// Facets are keyed "sd.<path>" and bounded to their most frequent values, invalid and duplicate paths are ignored
+func addStructuredDataFacets(config string) {
+	if config == "" {
+		return
+	}
+
+	for _, path := range strings.Split(config, ",") {
+		path = strings.TrimSpace(path)
+
+		jsonPath, err := structuredDataJSONPath(path)
+		if err != nil {
+			log.Printf("Ignoring invalid SLOGGO_SD_FACETS entry %q: %v", path, err)
+			continue
+		}
+
+		key := "sd." + path
+		if slices.ContainsFunc(facetQueries, func(facet facetQuery) bool { return facet.key == key }) {
+			continue
+		}
+
+		facetQueries = append(facetQueries, facetQuery{
+			key:     key,
+			column:  fmt.Sprintf("json_extract_string(%s, '%s')", structuredDataColumn, jsonPath),
+			bounded: true,
+			notNull: true,
+		})
+	}
+}
+
+// NewStructuredDataFilter validates a dotted path and returns a filter matching logs with the given value at that path
+func NewStructuredDataFilter(path string, value string) (*StructuredDataFilter, error) {
+	jsonPath, err := structuredDataJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StructuredDataFilter{jsonPath: jsonPath, value: value}, nil
+}
+
+// structuredDataJSONPath converts a dotted path to a JSONPath with quoted keys
+// Segments are restricted to a safe character set so the result can be inlined in queries
+func structuredDataJSONPath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+
+	segments := strings.Split(path, ".")
+	if len(segments) > maxStructuredDataPathDepth {
+		return "", fmt.Errorf("path has more than %d segments", maxStructuredDataPathDepth)
+	}
+
+	jsonPath := strings.Builder{}
+	jsonPath.WriteString("$")
+
+	for _, segment := range segments {
+		if !structuredDataSegmentRegex.MatchString(segment) {
+			return "", fmt.Errorf("invalid path segment %q", segment)
+		}
+		jsonPath.WriteString(`."` + segment + `"`)
+	}
+
+	return jsonPath.String(), nil
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sloggo/models"
+)
+
+func TestStructuredDataJSONPath(t *testing.T) {
+	testCases := []struct {
+		path        string
+		expected    string
+		shouldError bool
+	}{
+		{path: "kubernetes.pod_name", expected: `$."kubernetes"."pod_name"`},
+		{path: "origin@32473.ip", expected: `$."origin@32473"."ip"`},
+		{path: "level", expected: `$."level"`},
+		{path: "", shouldError: true},
+		{path: "origin..ip", shouldError: true},
+		{path: ".origin", shouldError: true},
+		{path: "origin.", shouldError: true},
+		{path: `origin."ip`, shouldError: true},
+		{path: "origin.ip')--", shouldError: true},
+		{path: "a.b.c.d.e.f.g.h.i", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			jsonPath, err := structuredDataJSONPath(tc.path)
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %q", jsonPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if jsonPath != tc.expected {
+				t.Errorf("path mismatch: got %q, want %q", jsonPath, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetLogsStructuredData(t *testing.T) {
+	now := time.Now()
+	structuredData := []string{
+		`{"kubernetes":{"pod_name":"sd-web-1"}}`,
+		`{"kubernetes":{"pod_name":"sd-web-2"}}`,
+		"-",
+	}
+
+	for i, sd := range structuredData {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(time.Duration(i) * time.Millisecond),
+			Hostname:       "sd-host",
+			AppName:        "sd-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: sd,
+			Message:        fmt.Sprintf("Structured data message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	filter, err := NewStructuredDataFilter("kubernetes.pod_name", "sd-web-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	filters := map[string]any{"structuredData": []*StructuredDataFilter{filter}}
+	logs, _, _, err := GetLogs(10, time.Time{}, "next", filters, "timestamp", "ASC")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+
+	if len(logs) != 1 || logs[0].Message != "Structured data message 1" {
+		t.Fatalf("Expected only the sd-web-2 log, got %+v", logs)
+	}
+}
+
+func TestStructuredDataFacets(t *testing.T) {
+	originalFacets := facetQueries
+	defer func() { facetQueries = originalFacets }()
+	facetQueries = slices.Clone(facetQueries)
+
+	addStructuredDataFacets("exampleSDID@32473.iut, invalid..path, exampleSDID@32473.iut")
+
+	sdFacets := 0
+	for _, facet := range facetQueries {
+		if strings.HasPrefix(facet.key, "sd.") {
+			sdFacets++
+		}
+	}
+	if sdFacets != 1 {
+		t.Fatalf("Expected 1 structured data facet, got %d", sdFacets)
+	}
+
+	now := time.Now()
+	structuredData := []string{
+		`{"exampleSDID@32473":{"iut":"3"}}`,
+		`{"exampleSDID@32473":{"iut":"3"}}`,
+		`{"exampleSDID@32473":{"iut":"4"}}`,
+		`{"other@1":{"iut":"5"}}`,
+		"-",
+	}
+
+	for i, sd := range structuredData {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      now.Add(time.Duration(i) * time.Millisecond),
+			Hostname:       "sd-facet-host",
+			AppName:        "sd-facet-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: sd,
+			Message:        fmt.Sprintf("Structured data facet message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	facets, err := GetFacets(map[string]any{"hostname": "sd-facet-host"}, 0, FacetSortCount)
+	if err != nil {
+		t.Fatalf("Failed to get facets: %v", err)
+	}
+
+	rows := facets["sd.exampleSDID@32473.iut"].Rows
+	expected := []FacetRow{{Value: "3", Total: 2}, {Value: "4", Total: 1}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected facet rows %v, got %v", expected, rows)
+	}
+}
+
+func TestHasStructuredDataFilter(t *testing.T) {
+	// Absent structured data is stored as NULL, including the nil value "-" of older entries
+	for _, structuredData := range []string{`{"meta":{"env":"prod"}}`, "", "-"} {
+		err := StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now(),
+			Hostname:       "presence-host",
+			AppName:        "presence-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: structuredData,
+			Message:        "Structured data presence",
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var absent int
+	if err := db.QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = 'presence-host' AND structured_data IS NULL").Scan(&absent); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if absent != 2 {
+		t.Errorf("Expected 2 logs stored without structured data, got %d", absent)
+	}
+
+	tests := []struct {
+		name              string
+		hasStructuredData bool
+		expected          []string
+	}{
+		{"with structured data", true, []string{`{"meta":{"env":"prod"}}`}},
+		{"without structured data", false, []string{"", ""}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filters := map[string]any{
+				"hostname":          "presence-host",
+				"hasStructuredData": tc.hasStructuredData,
+			}
+
+			logs, _, _, err := GetLogs(50, time.Time{}, "next", filters, "timestamp", "DESC")
+			if err != nil {
+				t.Fatalf("Failed to get logs: %v", err)
+			}
+
+			structuredData := []string{}
+			for _, log := range logs {
+				structuredData = append(structuredData, log.StructuredData)
+			}
+			if !slices.Equal(structuredData, tc.expected) {
+				t.Errorf("Expected structured data %q, got %q", tc.expected, structuredData)
+			}
+		})
+	}
+}
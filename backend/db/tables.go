@@ -0,0 +1,62 @@
+package db
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sloggo/models"
+)
+
+// defaultTable holds the logs stored without a table, and is queried without a table filter
+const defaultTable = "logs"
+
+// tableNameRegex validates the names of SLOGGO_TABLES, which are inlined in queries
+var tableNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// tables lists the tables logs can be stored in and queried from, the default table first
+var tables = []string{defaultTable}
+
+// addTables adds the comma-separated tables of SLOGGO_TABLES, such as "logs_prod,logs_staging"
+// Invalid and duplicate names are logged and ignored
+func addTables(config string) {
+	for name := range strings.SplitSeq(config, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(tables, name) {
+			continue
+		}
+
+		if !tableNameRegex.MatchString(name) {
+			log.Printf("Ignoring invalid SLOGGO_TABLES entry %q, expected lowercase letters, digits and underscores", name)
+			continue
+		}
+		tables = append(tables, name)
+	}
+}
+
+// Tables returns the tables logs can be stored in and queried from, the default table first
+func Tables() []string {
+	return slices.Clone(tables)
+}
+
+// IsTable reports whether logs can be stored in and queried from a table
+func IsTable(name string) bool {
+	return slices.Contains(tables, name)
+}
+
+// tableOf returns the table selected by the "table" filter, the default table without it
+func tableOf(filters map[string]any) string {
+	if table, ok := filters["table"].(string); ok && table != "" {
+		return table
+	}
+	return defaultTable
+}
+
+// entryTable returns the table a log entry is stored in
+func entryTable(entry models.LogEntry) string {
+	if entry.Table != "" {
+		return entry.Table
+	}
+	return defaultTable
+}
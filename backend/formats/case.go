@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"log"
+	"sloggo/utils"
+	"strings"
+)
+
+// lowercaseFields holds the fields lowercased at ingest with SLOGGO_NORMALIZE_CASE, "appName" and "hostname"
+var lowercaseFields = map[string]bool{}
+
+func init() {
+	setLowercaseFields(utils.NormalizeCase)
+}
+
+// setLowercaseFields parses the comma-separated fields to lowercase, unknown fields are logged and ignored
+func setLowercaseFields(config string) {
+	clear(lowercaseFields)
+
+	for field := range strings.SplitSeq(config, ",") {
+		field = strings.TrimSpace(field)
+		switch field {
+		case "":
+		case "appName", "hostname":
+			lowercaseFields[field] = true
+		default:
+			log.Printf("Invalid SLOGGO_NORMALIZE_CASE field %q, expected appName or hostname", field)
+		}
+	}
+}
+
+// NormalizeCase lowercases the value of a field listed in SLOGGO_NORMALIZE_CASE, so "App" and "app" are the same app
+func NormalizeCase(field string, value string) string {
+	if !lowercaseFields[field] {
+		return value
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,32 @@
+package formats
+
+import "testing"
+
+func TestNormalizeCase(t *testing.T) {
+	defer setLowercaseFields("")
+
+	setLowercaseFields("appName, msgId")
+
+	if got := NormalizeCase("appName", "MyApp"); got != "myapp" {
+		t.Errorf("Expected the app name to be lowercased, got %q", got)
+	}
+	if got := NormalizeCase("hostname", "Web-1"); got != "Web-1" {
+		t.Errorf("Expected the hostname to be kept, got %q", got)
+	}
+
+	setLowercaseFields("hostname")
+
+	entry, err := ParseRFC3164ToLogEntry("<34>Oct 11 22:14:15 Web-1 MyApp[42]: Started")
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	if entry.Hostname != "web-1" || entry.AppName != "MyApp" {
+		t.Errorf("Expected only the hostname to be lowercased, got %q and %q", entry.Hostname, entry.AppName)
+	}
+
+	setLowercaseFields("")
+
+	if got := NormalizeCase("appName", "MyApp"); got != "MyApp" {
+		t.Errorf("Expected values to be kept by default, got %q", got)
+	}
+}
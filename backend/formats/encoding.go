@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// inputEncoding is the encoding incoming messages are transcoded from, nil when input is treated as UTF-8
+var inputEncoding encoding.Encoding
+
+func init() {
+	if utils.InputEncoding == "" || utils.InputEncoding == "utf-8" || utils.InputEncoding == "utf8" {
+		return
+	}
+
+	charset, err := parseInputEncoding(utils.InputEncoding)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_INPUT_ENCODING, input is treated as UTF-8: %v", err)
+		return
+	}
+
+	inputEncoding = charset
+}
+
+// parseInputEncoding looks up an encoding by its WHATWG name or label, latin1 is decoded as its windows-1252 superset
+// Only single-byte encodings are supported: multi-byte ones such as utf-16 or iso-2022-jp are often valid UTF-8
+// as well, so they couldn't be told apart from the UTF-8 messages senders mix with them
+func parseInputEncoding(name string) (encoding.Encoding, error) {
+	charset, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := charset.(*charmap.Charmap); !ok {
+		return nil, fmt.Errorf("%s is not a single-byte encoding", name)
+	}
+
+	return charset, nil
+}
+
+// DecodeInput transcodes a raw message from the configured input encoding to UTF-8
+// Messages that are already valid UTF-8 are kept as is, as senders often mix both
+func DecodeInput(message string) string {
+	if inputEncoding == nil || utf8.ValidString(message) {
+		return message
+	}
+
+	// Decoders hold the transformation state, each message gets its own as the listeners decode concurrently
+	decoded, err := inputEncoding.NewDecoder().String(message)
+	if err != nil {
+		return message
+	}
+
+	return decoded
+}
@@ -0,0 +1,73 @@
+package formats
+
+import (
+	"testing"
+)
+
+func TestParseInputEncoding(t *testing.T) {
+	for _, name := range []string{"latin1", "windows-1252", "iso-8859-15", "koi8-r"} {
+		if _, err := parseInputEncoding(name); err != nil {
+			t.Errorf("Expected %s to be supported, got %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"utf-16", "iso-2022-jp", "shift_jis", "unknown"} {
+		if _, err := parseInputEncoding(name); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestDecodeInput(t *testing.T) {
+	originalEncoding := inputEncoding
+	defer func() {
+		inputEncoding = originalEncoding
+	}()
+
+	latin1, err := parseInputEncoding("latin1")
+	if err != nil {
+		t.Fatalf("Failed to get the latin1 encoding: %v", err)
+	}
+	inputEncoding = latin1
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"latin1 accents", "<13>Oct 11 22:14:15 host app: D\xe9j\xe0 vu, \xe9t\xe9 \xe0 Z\xfcrich", "<13>Oct 11 22:14:15 host app: Déjà vu, été à Zürich"},
+		{"windows-1252 characters", "Price: \x8010 \x93quoted\x94", "Price: €10 “quoted”"},
+		{"valid UTF-8 unchanged", "Déjà vu", "Déjà vu"},
+		{"ASCII unchanged", "plain message", "plain message"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DecodeInput(tc.input); got != tc.expected {
+				t.Errorf("DecodeInput(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+
+	// The decoded message parses with its accents intact
+	entry, err := ParseRFC3164ToLogEntry(DecodeInput("<13>Oct 11 22:14:15 host app: caf\xe9 cr\xe8me"))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	if entry.Message != "café crème" {
+		t.Errorf("Expected the accented message, got %q", entry.Message)
+	}
+}
+
+func TestDecodeInputDisabled(t *testing.T) {
+	originalEncoding := inputEncoding
+	defer func() {
+		inputEncoding = originalEncoding
+	}()
+	inputEncoding = nil
+
+	// Input is treated as UTF-8 by default, invalid bytes are kept for the parsers to handle
+	if got := DecodeInput("caf\xe9"); got != "caf\xe9" {
+		t.Errorf("Expected the message unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package formats
+
+import (
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+)
+
+// facilityRemap normalizes the facility of senders abusing facility codes
+// Keys are "facility:appName", or "facility:" to remap the facility for every app
+var facilityRemap map[string]uint8
+
+func init() {
+	if utils.FacilityRemap == "" {
+		return
+	}
+
+	remap, err := parseFacilityRemap(utils.FacilityRemap)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_FACILITY_REMAP, facilities are not remapped: %v", err)
+		return
+	}
+
+	facilityRemap = remap
+}
+
+// parseFacilityRemap parses a comma-separated list of "from[:appName]=to" rules
+// Example: "16:appX=1,17=1" remaps local0 from appX and local1 from any app to user
+func parseFacilityRemap(config string) (map[string]uint8, error) {
+	remap := make(map[string]uint8)
+
+	for rule := range strings.SplitSeq(config, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		source, target, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q is missing the target facility", rule)
+		}
+
+		from, appName, _ := strings.Cut(source, ":")
+
+		fromFacility, err := parseFacility(from)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule, err)
+		}
+
+		toFacility, err := parseFacility(target)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", rule, err)
+		}
+
+		remap[fmt.Sprintf("%d:%s", fromFacility, strings.TrimSpace(appName))] = toFacility
+	}
+
+	return remap, nil
+}
+
+// parseFacility parses a facility code and validates its range
+func parseFacility(value string) (uint8, error) {
+	facility, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || facility < 0 || facility > 23 {
+		return 0, fmt.Errorf("invalid facility %q (must be 0-23)", value)
+	}
+	return uint8(facility), nil
+}
+
+// RemapFacility returns the canonical facility for the given facility and app name
+// Rules for a specific app take precedence over rules for any app, identity by default
+func RemapFacility(facility uint8, appName string) uint8 {
+	if len(facilityRemap) == 0 {
+		return facility
+	}
+
+	if remapped, ok := facilityRemap[fmt.Sprintf("%d:%s", facility, appName)]; ok {
+		return remapped
+	}
+
+	if remapped, ok := facilityRemap[fmt.Sprintf("%d:", facility)]; ok {
+		return remapped
+	}
+
+	return facility
+}
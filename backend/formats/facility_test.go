@@ -0,0 +1,75 @@
+package formats
+
+import (
+	"testing"
+)
+
+func TestParseFacilityRemap(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		shouldError bool
+	}{
+		{"app specific rule", "16:appX=1", false},
+		{"rule for any app", "17=1", false},
+		{"multiple rules", "16:appX=1, 17=1", false},
+		{"missing target", "16:appX", true},
+		{"invalid source facility", "abc:appX=1", true},
+		{"target out of range", "16:appX=24", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseFacilityRemap(tc.config)
+			if tc.shouldError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.shouldError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRemapFacility(t *testing.T) {
+	remap, err := parseFacilityRemap("16:appX=1,16:appY=3,17=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	originalRemap := facilityRemap
+	facilityRemap = remap
+	defer func() {
+		facilityRemap = originalRemap
+	}()
+
+	testCases := []struct {
+		name     string
+		facility uint8
+		appName  string
+		expected uint8
+	}{
+		{"app specific rule", 16, "appX", 1},
+		{"other app specific rule", 16, "appY", 3},
+		{"unmatched app keeps facility", 16, "appZ", 16},
+		{"rule for any app", 17, "appZ", 1},
+		{"unmatched facility keeps facility", 4, "appX", 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RemapFacility(tc.facility, tc.appName); got != tc.expected {
+				t.Errorf("RemapFacility(%d, %q): got %d, want %d", tc.facility, tc.appName, got, tc.expected)
+			}
+		})
+	}
+
+	// Remapping is applied by the parsers
+	entry, err := ParseRFC3164ToLogEntry("<134>Feb  1 11:37:00 host appX: remapped")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Facility != 1 || entry.Severity != 6 {
+		t.Errorf("facility/severity mismatch: got (%d,%d)", entry.Facility, entry.Severity)
+	}
+}
@@ -0,0 +1,159 @@
+package formats
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"sloggo/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// hostnameLookupTimeout bounds the DNS lookup resolving a short hostname to its FQDN
+	hostnameLookupTimeout = time.Second
+
+	// fqdnCacheSize bounds the number of hostnames cached, the least recently used ones are evicted first
+	fqdnCacheSize = 10000
+
+	// fqdnCacheTTL is how long a resolved FQDN, or a failed lookup, is used before resolving the host again
+	fqdnCacheTTL = time.Hour
+
+	// maxFQDNLookups bounds the lookups in flight, hosts seen while all are busy are resolved on a later log
+	maxFQDNLookups = 16
+)
+
+// lookupFQDN resolves a short hostname to its FQDN using the system resolver and search domains
+var lookupFQDN = func(hostname string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hostnameLookupTimeout)
+	defer cancel()
+
+	return net.DefaultResolver.LookupCNAME(ctx, hostname)
+}
+
+// fqdnEntry is a cached FQDN and its expiry
+type fqdnEntry struct {
+	hostname string
+	fqdn     string
+	expires  time.Time
+}
+
+// fqdnResolver resolves hostnames in the background, caching the results in a bounded LRU
+// Hostnames are sent by clients, so neither the cache nor the lookups in flight may grow without limit
+type fqdnResolver struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element // Elements hold *fqdnEntry, most recently used first
+	order   *list.List
+	pending map[string]bool
+	lookups chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newFQDNResolver creates a resolver caching up to size hostnames for ttl
+func newFQDNResolver(size int, ttl time.Duration) *fqdnResolver {
+	return &fqdnResolver{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		pending: make(map[string]bool),
+		lookups: make(chan struct{}, maxFQDNLookups),
+	}
+}
+
+// fqdns resolves the hostnames of SLOGGO_HOSTNAME_MODE=fqdn
+var fqdns = newFQDNResolver(fqdnCacheSize, fqdnCacheTTL)
+
+// resolve returns the cached FQDN of a hostname, starting a lookup when it's missing or expired
+// The hostname itself, or the expired FQDN, is returned until the lookup completes, ingest never waits for DNS
+func (r *fqdnResolver) resolve(hostname string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fqdn := hostname
+	if element, ok := r.entries[hostname]; ok {
+		entry := element.Value.(*fqdnEntry)
+		r.order.MoveToFront(element)
+		if time.Now().Before(entry.expires) {
+			return entry.fqdn
+		}
+		fqdn = entry.fqdn
+	}
+
+	if r.pending[hostname] {
+		return fqdn
+	}
+
+	select {
+	case r.lookups <- struct{}{}:
+	default:
+		// Every lookup slot is busy
+		return fqdn
+	}
+
+	r.pending[hostname] = true
+	r.wg.Add(1)
+	go r.lookup(hostname)
+
+	return fqdn
+}
+
+// lookup resolves a hostname and caches the result, failed lookups cache the hostname itself
+func (r *fqdnResolver) lookup(hostname string) {
+	defer r.wg.Done()
+
+	fqdn := hostname
+	if resolved, err := lookupFQDN(hostname); err == nil && resolved != "" {
+		fqdn = strings.TrimSuffix(strings.ToLower(resolved), ".")
+	}
+
+	<-r.lookups
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, hostname)
+
+	entry := &fqdnEntry{hostname: hostname, fqdn: fqdn, expires: time.Now().Add(r.ttl)}
+	if element, ok := r.entries[hostname]; ok {
+		element.Value = entry
+		r.order.MoveToFront(element)
+	} else {
+		r.entries[hostname] = r.order.PushFront(entry)
+	}
+
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*fqdnEntry).hostname)
+	}
+}
+
+// NormalizeHostname converts the hostname to the form selected by SLOGGO_HOSTNAME_MODE
+// "short" keeps the first label, "fqdn" resolves short names, "raw" keeps the hostname as sent
+// IP addresses and the nil value "-" are never changed
+func NormalizeHostname(hostname string) string {
+	if utils.HostnameMode != "short" && utils.HostnameMode != "fqdn" {
+		return hostname
+	}
+
+	if hostname == "-" || net.ParseIP(hostname) != nil {
+		return hostname
+	}
+
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+
+	if utils.HostnameMode == "short" {
+		short, _, _ := strings.Cut(hostname, ".")
+		return short
+	}
+
+	if strings.Contains(hostname, ".") {
+		return hostname
+	}
+
+	return fqdns.resolve(hostname)
+}
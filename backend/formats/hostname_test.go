@@ -0,0 +1,115 @@
+package formats
+
+import (
+	"errors"
+	"sloggo/utils"
+	"testing"
+	"time"
+)
+
+func TestNormalizeHostname(t *testing.T) {
+	originalMode := utils.HostnameMode
+	originalLookup := lookupFQDN
+	originalResolver := fqdns
+	defer func() {
+		utils.HostnameMode = originalMode
+		lookupFQDN = originalLookup
+		fqdns = originalResolver
+	}()
+	fqdns = newFQDNResolver(fqdnCacheSize, fqdnCacheTTL)
+
+	lookupFQDN = func(hostname string) (string, error) {
+		if hostname == "host1" {
+			return "host1.example.com.", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	testCases := []struct {
+		name     string
+		mode     string
+		hostname string
+		expected string
+	}{
+		{"raw keeps FQDN", "raw", "Host1.Example.com", "Host1.Example.com"},
+		{"short strips domain", "short", "host1.example.com", "host1"},
+		{"short lowercases", "short", "HOST1", "host1"},
+		{"short keeps IPv4", "short", "192.168.1.10", "192.168.1.10"},
+		{"short keeps IPv6", "short", "fe80::1", "fe80::1"},
+		{"short keeps nil value", "short", "-", "-"},
+		{"fqdn resolves short name", "fqdn", "host1", "host1.example.com"},
+		{"fqdn keeps FQDN", "fqdn", "host2.example.com.", "host2.example.com"},
+		{"fqdn keeps unresolvable name", "fqdn", "host3", "host3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			utils.HostnameMode = tc.mode
+
+			// Lookups run in the background, the first log of a host keeps its name
+			NormalizeHostname(tc.hostname)
+			fqdns.wg.Wait()
+
+			if got := NormalizeHostname(tc.hostname); got != tc.expected {
+				t.Errorf("NormalizeHostname(%q): got %q, want %q", tc.hostname, got, tc.expected)
+			}
+		})
+	}
+
+	// Normalization is applied by the parsers
+	utils.HostnameMode = "short"
+	entry, err := ParseRFC3164ToLogEntry("<134>Feb  1 11:37:00 host1.example.com app: message")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Hostname != "host1" {
+		t.Errorf("expected short hostname, got %q", entry.Hostname)
+	}
+}
+
+func TestFQDNResolver(t *testing.T) {
+	originalLookup := lookupFQDN
+	defer func() { lookupFQDN = originalLookup }()
+
+	lookups := 0
+	release := make(chan struct{})
+	lookupFQDN = func(hostname string) (string, error) {
+		<-release
+		lookups++
+		return hostname + ".example.com", nil
+	}
+
+	resolver := newFQDNResolver(2, time.Hour)
+
+	// The raw hostname is returned while the lookup is in flight, without starting another one
+	if got := resolver.resolve("host1"); got != "host1" {
+		t.Errorf("Expected the raw hostname during the lookup, got %q", got)
+	}
+	if got := resolver.resolve("host1"); got != "host1" {
+		t.Errorf("Expected the raw hostname during the lookup, got %q", got)
+	}
+	close(release)
+	resolver.wg.Wait()
+
+	if got := resolver.resolve("host1"); got != "host1.example.com" || lookups != 1 {
+		t.Errorf("Expected host1 to be resolved once, got %q after %d lookups", got, lookups)
+	}
+
+	// The least recently used hostname is evicted beyond the cache size
+	for _, hostname := range []string{"host2", "host3"} {
+		resolver.resolve(hostname)
+		resolver.wg.Wait()
+	}
+	if _, ok := resolver.entries["host1"]; ok || len(resolver.entries) != 2 {
+		t.Errorf("Expected host1 to be evicted, got %d cached hostnames", len(resolver.entries))
+	}
+
+	// Expired entries are still used while they're resolved again
+	expired := newFQDNResolver(2, -time.Second)
+	expired.resolve("host4")
+	expired.wg.Wait()
+	if got := expired.resolve("host4"); got != "host4.example.com" {
+		t.Errorf("Expected the expired FQDN while resolving again, got %q", got)
+	}
+	expired.wg.Wait()
+}
@@ -0,0 +1,181 @@
+package formats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sloggo/models"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxJournalFieldSize bounds the size of a journal export field, like the maximum TCP message size
+	maxJournalFieldSize = 1024 * 1024
+
+	// maxJournalRecordSize bounds the size of all the fields of a record, so a stream of fields without
+	// the empty line ending the record can't grow it without limit
+	maxJournalRecordSize = 4 * maxJournalFieldSize
+
+	// maxJournalFields bounds the number of fields of a record, journald itself writes a few dozens
+	maxJournalFields = 1024
+)
+
+// ReadJournalRecord reads the next record of a systemd journal export stream (journalctl -o export)
+// Records are blocks of fields ended by an empty line, text fields are written as NAME=value lines,
+// binary ones as the NAME line followed by the 64-bit little-endian size of the data, the data and a newline.
+// It returns io.EOF once the stream ends between records, and an error once a record exceeds the size or field limits.
+func ReadJournalRecord(reader *bufio.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	recordSize := 0
+
+	for {
+		line, err := readJournalLine(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) == 0 {
+				if len(fields) == 0 {
+					return nil, io.EOF
+				}
+				// The last record may miss its empty line
+				return fields, nil
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			// Skip the extra empty lines between records
+			if len(fields) == 0 {
+				continue
+			}
+			return fields, nil
+		}
+
+		if len(fields) >= maxJournalFields {
+			return nil, fmt.Errorf("journal record has more than %d fields", maxJournalFields)
+		}
+
+		recordSize += len(line)
+		if recordSize > maxJournalRecordSize {
+			return nil, fmt.Errorf("journal record is larger than %d bytes", maxJournalRecordSize)
+		}
+
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			continue
+		}
+
+		// Binary field, used for values containing newlines or control characters
+		var size uint64
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("error reading the size of field %s: %v", line, err)
+		}
+		if size > maxJournalFieldSize {
+			return nil, fmt.Errorf("field %s is too large: %d bytes", line, size)
+		}
+
+		recordSize += int(size)
+		if recordSize > maxJournalRecordSize {
+			return nil, fmt.Errorf("journal record is larger than %d bytes", maxJournalRecordSize)
+		}
+
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, fmt.Errorf("error reading field %s: %v", line, err)
+		}
+		if data[size] != '\n' {
+			return nil, fmt.Errorf("field %s is not terminated by a newline", line)
+		}
+
+		fields[string(line)] = string(data[:size])
+	}
+}
+
+// readJournalLine reads a line without its newline, up to the maximum field size
+func readJournalLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if len(line) > maxJournalFieldSize+1 {
+			return nil, errors.New("journal field is too large")
+		}
+
+		if err == nil {
+			return line[:len(line)-1], nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, err
+		}
+	}
+}
+
+// JournalRecordToLogEntry converts a journal export record to a LogEntry
+// PRIORITY, _HOSTNAME, SYSLOG_IDENTIFIER, _PID and MESSAGE map to the syslog fields,
+// the facility and timestamp come from SYSLOG_FACILITY and __REALTIME_TIMESTAMP when present
+func JournalRecordToLogEntry(fields map[string]string) (*models.LogEntry, error) {
+	message, ok := fields["MESSAGE"]
+	if !ok {
+		return nil, errors.New("journal record without MESSAGE")
+	}
+
+	// Records without a valid priority or facility get the defaults of messages without a PRI
+	severity := defaultSeverity
+	if priority, err := strconv.Atoi(fields["PRIORITY"]); err == nil && priority >= 0 && priority <= 7 {
+		severity = uint8(priority)
+	}
+
+	facility := defaultFacility
+	if parsed, err := parseFacility(fields["SYSLOG_FACILITY"]); err == nil {
+		facility = parsed
+	}
+
+	// The realtime timestamp is in microseconds since the epoch
+	now := time.Now()
+	ts := now
+	if micros, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+		ts = time.UnixMicro(micros)
+	}
+
+	hostname := fields["_HOSTNAME"]
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	// Services logging to stdout have no syslog identifier, their command name is used instead
+	appName := fields["SYSLOG_IDENTIFIER"]
+	if appName == "" {
+		appName = fields["_COMM"]
+	}
+	if appName == "" {
+		appName = "-"
+	}
+
+	procID := fields["_PID"]
+	if procID == "" {
+		procID = "-"
+	}
+
+	entry := &models.LogEntry{
+		Severity:       severity,
+		Facility:       facility,
+		Version:        1,
+		Timestamp:      ts,
+		Hostname:       hostname,
+		AppName:        appName,
+		ProcID:         procID,
+		MsgID:          "-",
+		StructuredData: "",
+		Message:        message,
+	}
+	normalizeEntry(entry, now)
+
+	return entry, nil
+}
@@ -0,0 +1,137 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// journalBinaryField encodes a field in the binary form of the export format
+func journalBinaryField(name string, value string) string {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	return name + "\n" + string(size) + value + "\n"
+}
+
+func TestReadJournalRecord(t *testing.T) {
+	stream := "__CURSOR=s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7\n" +
+		"__REALTIME_TIMESTAMP=1696163696123456\n" +
+		"PRIORITY=3\n" +
+		"_HOSTNAME=web-1\n" +
+		"SYSLOG_IDENTIFIER=nginx\n" +
+		"_PID=4242\n" +
+		"MESSAGE=upstream timed out\n" +
+		"\n" +
+		"PRIORITY=6\n" +
+		journalBinaryField("MESSAGE", "first line\nsecond line") +
+		"_HOSTNAME=web-2\n" +
+		"\n" +
+		"MESSAGE=last record without an empty line\n"
+
+	reader := bufio.NewReader(strings.NewReader(stream))
+
+	first, err := ReadJournalRecord(reader)
+	if err != nil {
+		t.Fatalf("Failed to read the first record: %v", err)
+	}
+	if first["MESSAGE"] != "upstream timed out" || first["_PID"] != "4242" || len(first) != 7 {
+		t.Errorf("Unexpected first record: %v", first)
+	}
+
+	second, err := ReadJournalRecord(reader)
+	if err != nil {
+		t.Fatalf("Failed to read the second record: %v", err)
+	}
+	if second["MESSAGE"] != "first line\nsecond line" || second["_HOSTNAME"] != "web-2" {
+		t.Errorf("Unexpected second record: %v", second)
+	}
+
+	third, err := ReadJournalRecord(reader)
+	if err != nil {
+		t.Fatalf("Failed to read the third record: %v", err)
+	}
+	if third["MESSAGE"] != "last record without an empty line" {
+		t.Errorf("Unexpected third record: %v", third)
+	}
+
+	if _, err := ReadJournalRecord(reader); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestReadJournalRecordErrors(t *testing.T) {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, maxJournalFieldSize+1)
+
+	var manyFields strings.Builder
+	for i := range maxJournalFields + 1 {
+		fmt.Fprintf(&manyFields, "FIELD_%d=value\n", i)
+	}
+
+	tests := map[string]string{
+		"truncated line":         "MESSAGE=cut",
+		"truncated binary field": "MESSAGE\n\x10\x00\x00\x00\x00\x00\x00\x00short",
+		"oversized binary field": "MESSAGE\n" + string(size),
+		"unterminated field":     "MESSAGE\n\x02\x00\x00\x00\x00\x00\x00\x00okX",
+		"too many fields":        manyFields.String(),
+		"oversized record":       strings.Repeat("FIELD="+strings.Repeat("x", maxJournalFieldSize-6)+"\n", 5),
+	}
+
+	for name, stream := range tests {
+		if _, err := ReadJournalRecord(bufio.NewReader(strings.NewReader(stream))); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("%s: expected an error, got %v", name, err)
+		}
+	}
+}
+
+func TestJournalRecordToLogEntry(t *testing.T) {
+	entry, err := JournalRecordToLogEntry(map[string]string{
+		"__REALTIME_TIMESTAMP": "1696163696123456",
+		"PRIORITY":             "3",
+		"SYSLOG_FACILITY":      "3",
+		"_HOSTNAME":            "web-1",
+		"SYSLOG_IDENTIFIER":    "nginx",
+		"_PID":                 "4242",
+		"MESSAGE":              "upstream timed out",
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+
+	if entry.Severity != 3 || entry.Facility != 3 {
+		t.Errorf("Expected severity 3 and facility 3, got %d and %d", entry.Severity, entry.Facility)
+	}
+	if entry.Hostname != "web-1" || entry.AppName != "nginx" || entry.ProcID != "4242" || entry.Message != "upstream timed out" {
+		t.Errorf("Unexpected fields: %+v", entry)
+	}
+	if expected := time.UnixMicro(1696163696123456); !entry.Timestamp.Equal(expected) {
+		t.Errorf("Expected timestamp %v, got %v", expected, entry.Timestamp)
+	}
+
+	// Services logging to stdout only have a command name, and records may miss the priority
+	entry, err = JournalRecordToLogEntry(map[string]string{
+		"_COMM":   "myservice",
+		"MESSAGE": "started",
+	})
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if entry.AppName != "myservice" || entry.Hostname != "-" || entry.ProcID != "-" {
+		t.Errorf("Unexpected fields: %+v", entry)
+	}
+	if entry.Severity != defaultSeverity || entry.Facility != defaultFacility {
+		t.Errorf("Expected the default priority, got severity %d and facility %d", entry.Severity, entry.Facility)
+	}
+	if time.Since(entry.Timestamp) > time.Minute {
+		t.Errorf("Expected the receive time without a realtime timestamp, got %v", entry.Timestamp)
+	}
+
+	if _, err := JournalRecordToLogEntry(map[string]string{"PRIORITY": "6"}); err == nil {
+		t.Error("Expected an error for a record without MESSAGE")
+	}
+}
@@ -0,0 +1,89 @@
+package formats
+
+import (
+	"encoding/json"
+	"maps"
+	"regexp"
+	"slices"
+	"sloggo/models"
+	"sloggo/utils"
+	"strings"
+)
+
+// leadingLevelRegex matches a level word at the start of a message, such as "ERROR ...", "[warn] ...",
+// "<error> ...", "Error: ..." or "level=error ..."
+// A lowercase or capitalized word needs a delimiter, so plain sentences like "Alert sent to user" don't match
+var leadingLevelRegex = regexp.MustCompile(`^(?:level=([A-Za-z]+)(?:\s|$)|[\[<(]([A-Za-z]+)[\]>)]|([A-Za-z]+):(?:\s|$)|([A-Z]+)(?:\s|$))`)
+
+// SeverityFromMessage returns the severity of the level word leading the message, if any
+func SeverityFromMessage(message string) (uint8, bool) {
+	m := leadingLevelRegex.FindStringSubmatch(strings.TrimLeft(message, " \t"))
+	if m == nil {
+		return 0, false
+	}
+
+	for _, level := range m[1:] {
+		if level != "" {
+			return ParseSeverityLevel(level)
+		}
+	}
+
+	return 0, false
+}
+
+// SeverityFromStructuredData returns the severity held by the SLOGGO_SD_SEVERITY_FIELD param, if any
+// The field is either a param name looked up in every element, such as "level", or an "SD-ID.param" pair.
+// Values are level names such as "error" or numeric severities.
+func SeverityFromStructuredData(structData map[string]map[string]string) (uint8, bool) {
+	field := utils.SdSeverityField
+	if field == "" || len(structData) == 0 {
+		return 0, false
+	}
+
+	var value string
+	var found bool
+
+	if sdID, param, ok := strings.Cut(field, "."); ok {
+		value, found = structData[sdID][param]
+	} else {
+		// Elements are visited in a stable order in case several carry the param
+		for _, sdID := range slices.Sorted(maps.Keys(structData)) {
+			if value, found = structData[sdID][field]; found {
+				break
+			}
+		}
+	}
+
+	if !found {
+		return 0, false
+	}
+
+	severity, err := ParseSeverity(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return severity, true
+}
+
+// ApplySeverityKeywords overrides the severity with the level word leading the message
+// when SLOGGO_SEVERITY_FROM_KEYWORDS is enabled, for senders always using the same PRI
+// A severity set through SLOGGO_SD_SEVERITY_FIELD takes precedence over the message
+func ApplySeverityKeywords(entry *models.LogEntry) {
+	if !utils.SeverityFromKeywords {
+		return
+	}
+
+	if utils.SdSeverityField != "" && entry.StructuredData != "" {
+		var structData map[string]map[string]string
+		if err := json.Unmarshal([]byte(entry.StructuredData), &structData); err == nil {
+			if _, ok := SeverityFromStructuredData(structData); ok {
+				return
+			}
+		}
+	}
+
+	if severity, ok := SeverityFromMessage(entry.Message); ok {
+		entry.Severity = severity
+	}
+}
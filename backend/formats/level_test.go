@@ -0,0 +1,141 @@
+package formats
+
+import (
+	"sloggo/models"
+	"sloggo/utils"
+	"testing"
+
+	"github.com/leodido/go-syslog/v4/rfc5424"
+)
+
+func TestSeverityFromMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		severity uint8
+		found    bool
+	}{
+		{"uppercase word", "ERROR disk full", 3, true},
+		{"uppercase short form", "WARN low memory", 4, true},
+		{"bracketed", "[warn] low memory", 4, true},
+		{"angle brackets", "<Error> disk full", 3, true},
+		{"parentheses", "(debug) cache hit", 7, true},
+		{"colon", "Error: disk full", 3, true},
+		{"logfmt", "level=fatal msg=crashed", 2, true},
+		{"leading spaces", "  CRIT overheating", 2, true},
+		{"uppercase word alone", "ERROR", 3, true},
+
+		// False positives
+		{"plain sentence", "Alert sent to user", 0, false},
+		{"lowercase word without delimiter", "error count is 0", 0, false},
+		{"word inside message", "Disk ERROR detected", 0, false},
+		{"longer word", "ERRORS: 3", 0, false},
+		{"unknown bracketed word", "[main] started", 0, false},
+		{"unknown uppercase word", "GET /index.html", 0, false},
+		{"level word prefix", "Information: started", 0, false},
+		{"empty message", "", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			severity, found := SeverityFromMessage(tc.message)
+			if found != tc.found || severity != tc.severity {
+				t.Errorf("SeverityFromMessage(%q): got (%d, %t), want (%d, %t)", tc.message, severity, found, tc.severity, tc.found)
+			}
+		})
+	}
+}
+
+func TestApplySeverityKeywords(t *testing.T) {
+	originalEnabled := utils.SeverityFromKeywords
+	defer func() {
+		utils.SeverityFromKeywords = originalEnabled
+	}()
+
+	// Off by default
+	utils.SeverityFromKeywords = false
+	entry := &models.LogEntry{Severity: 6, Message: "ERROR disk full"}
+	ApplySeverityKeywords(entry)
+	if entry.Severity != 6 {
+		t.Errorf("expected severity to be unchanged, got %d", entry.Severity)
+	}
+
+	utils.SeverityFromKeywords = true
+	ApplySeverityKeywords(entry)
+	if entry.Severity != 3 {
+		t.Errorf("expected severity 3, got %d", entry.Severity)
+	}
+
+	// No level word keeps the parsed severity
+	entry = &models.LogEntry{Severity: 6, Message: "User logged in"}
+	ApplySeverityKeywords(entry)
+	if entry.Severity != 6 {
+		t.Errorf("expected severity to be unchanged, got %d", entry.Severity)
+	}
+}
+
+func TestSeverityFromStructuredData(t *testing.T) {
+	originalField := utils.SdSeverityField
+	defer func() {
+		utils.SdSeverityField = originalField
+	}()
+
+	testCases := []struct {
+		name     string
+		field    string
+		input    string
+		severity uint8
+	}{
+		{"disabled by default", "", `<14>1 2023-10-01T12:34:56Z host app - - [meta level="error"] Message`, 6},
+		{"level name overrides PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - [meta level="error"] Message`, 3},
+		{"numeric level overrides PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - [meta level="2"] Message`, 2},
+		{"SD-ID and param", "meta.level", `<14>1 2023-10-01T12:34:56Z host app - - [other level="debug"][meta level="warn"] Message`, 4},
+		{"SD-ID and param of another element", "meta.level", `<14>1 2023-10-01T12:34:56Z host app - - [other level="debug"] Message`, 6},
+		{"unknown level keeps PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - [meta level="loud"] Message`, 6},
+		{"out of range level keeps PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - [meta level="9"] Message`, 6},
+		{"missing field keeps PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - [meta other="error"] Message`, 6},
+		{"no structured data keeps PRI", "level", `<14>1 2023-10-01T12:34:56Z host app - - - Message`, 6},
+	}
+
+	parser := rfc5424.NewParser(rfc5424.WithBestEffort())
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			utils.SdSeverityField = tc.field
+
+			msg, err := parser.Parse([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			entry := SyslogMessageToLogEntry(msg.(*rfc5424.SyslogMessage))
+			if entry.Severity != tc.severity {
+				t.Errorf("expected severity %d, got %d", tc.severity, entry.Severity)
+			}
+			if entry.Facility != 1 {
+				t.Errorf("expected facility to be unchanged, got %d", entry.Facility)
+			}
+		})
+	}
+}
+
+func TestStructuredDataSeverityPrecedesKeywords(t *testing.T) {
+	originalField, originalEnabled := utils.SdSeverityField, utils.SeverityFromKeywords
+	defer func() {
+		utils.SdSeverityField, utils.SeverityFromKeywords = originalField, originalEnabled
+	}()
+	utils.SdSeverityField, utils.SeverityFromKeywords = "level", true
+
+	entry := &models.LogEntry{Severity: 4, StructuredData: `{"meta":{"level":"warn"}}`, Message: "ERROR disk full"}
+	ApplySeverityKeywords(entry)
+	if entry.Severity != 4 {
+		t.Errorf("expected the structured data severity to be kept, got %d", entry.Severity)
+	}
+
+	// Without the field the message keyword applies
+	entry = &models.LogEntry{Severity: 6, StructuredData: `{"meta":{"other":"x"}}`, Message: "ERROR disk full"}
+	ApplySeverityKeywords(entry)
+	if entry.Severity != 3 {
+		t.Errorf("expected severity 3, got %d", entry.Severity)
+	}
+}
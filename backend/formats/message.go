@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"log"
+	"regexp"
+	"sloggo/utils"
+)
+
+// msgStripRegex matches a redundant prefix to remove from messages, nil when disabled
+var msgStripRegex *regexp.Regexp
+
+func init() {
+	if utils.MsgStripRegex == "" {
+		return
+	}
+
+	regex, err := regexp.Compile(utils.MsgStripRegex)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_MSG_STRIP_REGEX, messages are not stripped: %v", err)
+		return
+	}
+
+	msgStripRegex = regex
+}
+
+// StripMessage removes the configured prefix from a message
+// Only a match at the start of the message is removed, the message is unchanged otherwise
+func StripMessage(message string) string {
+	if msgStripRegex == nil {
+		return message
+	}
+
+	if loc := msgStripRegex.FindStringIndex(message); loc != nil && loc[0] == 0 {
+		return message[loc[1]:]
+	}
+
+	return message
+}
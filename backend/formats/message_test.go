@@ -0,0 +1,49 @@
+package formats
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestStripMessage(t *testing.T) {
+	originalRegex := msgStripRegex
+	defer func() {
+		msgStripRegex = originalRegex
+	}()
+
+	// Disabled by default
+	msgStripRegex = nil
+	if got := StripMessage("2025-08-04T12:00:00Z link up"); got != "2025-08-04T12:00:00Z link up" {
+		t.Errorf("expected message to be unchanged, got %q", got)
+	}
+
+	msgStripRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\S+\s*`)
+
+	testCases := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"prefix is removed", "2025-08-04T12:00:00Z link up", "link up"},
+		{"match not at the start is kept", "link up at 2025-08-04T12:00:00Z", "link up at 2025-08-04T12:00:00Z"},
+		{"no match", "link up", "link up"},
+		{"empty message", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := StripMessage(tc.message); got != tc.expected {
+				t.Errorf("StripMessage(%q): got %q, want %q", tc.message, got, tc.expected)
+			}
+		})
+	}
+
+	// Stripping is applied by the parsers
+	entry, err := ParseRFC3164ToLogEntry("<134>Feb  1 11:37:00 host app: 2025-08-04T12:00:00Z link up")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.Message != "link up" {
+		t.Errorf("expected stripped message, got %q", entry.Message)
+	}
+}
@@ -0,0 +1,23 @@
+package formats
+
+import (
+	"sloggo/models"
+	"time"
+)
+
+// normalizeEntry applies the configured ingest normalizations to an entry parsed from a message received at now
+// The facility is remapped before the app name case is normalized, as remap rules match the app name as sent
+func normalizeEntry(entry *models.LogEntry, now time.Time) {
+	// Senders with a bad clock can be overridden by the receive time
+	entry.Timestamp = ResolveTimestamp(entry.Timestamp, now)
+
+	// Normalize hostnames to the configured short or FQDN form and case
+	entry.Hostname = NormalizeCase("hostname", NormalizeHostname(entry.Hostname))
+
+	// Normalize vendor specific facility codes
+	entry.Facility = RemapFacility(entry.Facility, entry.AppName)
+	entry.AppName = NormalizeCase("appName", entry.AppName)
+
+	// Remove the configured redundant prefix from the message
+	entry.Message = StripMessage(entry.Message)
+}
@@ -0,0 +1,44 @@
+package formats
+
+import (
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestNormalizeEntry(t *testing.T) {
+	remap, err := parseFacilityRemap("16:MyApp=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	originalRemap := facilityRemap
+	facilityRemap = remap
+	defer func() {
+		facilityRemap = originalRemap
+	}()
+
+	defer setLowercaseFields("")
+	setLowercaseFields("appName")
+
+	now := time.Now()
+	entry := &models.LogEntry{
+		Facility:  16,
+		Hostname:  "web-1",
+		AppName:   "MyApp",
+		Timestamp: now,
+		Message:   "Started",
+	}
+	normalizeEntry(entry, now)
+
+	// The facility is remapped on the app name as sent, before its case is normalized
+	if entry.Facility != 1 {
+		t.Errorf("Expected the facility to be remapped, got %d", entry.Facility)
+	}
+	if entry.AppName != "myapp" {
+		t.Errorf("Expected the app name to be lowercased, got %q", entry.AppName)
+	}
+	if entry.Hostname != "web-1" || entry.Message != "Started" || !entry.Timestamp.Equal(now) {
+		t.Errorf("Expected the other fields to be kept, got %+v", entry)
+	}
+}
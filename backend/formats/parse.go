@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"errors"
+	"sloggo/models"
+
+	"github.com/leodido/go-syslog/v4"
+	"github.com/leodido/go-syslog/v4/rfc5424"
+)
+
+// ParseSyslogMessage parses a single message according to the configured log format
+// Shared by the TCP and UDP listeners so both accept exactly the same formats
+func ParseSyslogMessage(parser syslog.Machine, message string, logFormat string) (*models.LogEntry, error) {
+	var lastErr error
+
+	// Transcode legacy encodings such as latin1 before parsing, they would be stored as mojibake otherwise
+	message = DecodeInput(message)
+
+	// Some senders skip the PRI entirely, neither format parses without it
+	message = AddMissingPriority(message)
+
+	// Try RFC5424 if enabled, forwarded Windows events may use either syslog envelope
+	if logFormat == "rfc5424" || logFormat == "auto" || logFormat == "winevt" {
+		if syslogMsg, err := parser.Parse([]byte(message)); err == nil {
+			if rfc5424Msg, ok := syslogMsg.(*rfc5424.SyslogMessage); ok {
+				if logEntry := SyslogMessageToLogEntry(rfc5424Msg); logEntry != nil {
+					return applyLogFormat(logEntry, logFormat), nil
+				}
+			}
+		} else {
+			lastErr = err
+		}
+	}
+
+	// Try RFC3164 if enabled and not yet parsed
+	if logFormat == "rfc3164" || logFormat == "auto" || logFormat == "winevt" {
+		logEntry, err := ParseRFC3164ToLogEntry(message)
+		if err == nil {
+			return applyLogFormat(logEntry, logFormat), nil
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("unsupported message")
+	}
+
+	return nil, lastErr
+}
+
+// applyLogFormat applies the format specific enrichments to a parsed entry
+func applyLogFormat(logEntry *models.LogEntry, logFormat string) *models.LogEntry {
+	if logFormat == "winevt" {
+		ApplyWinEvt(logEntry)
+	}
+
+	// Derive the severity from the message for senders that don't set it
+	ApplySeverityKeywords(logEntry)
+
+	return logEntry
+}
@@ -0,0 +1,42 @@
+package formats
+
+import (
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"strings"
+)
+
+// The facility and severity of messages without a PRI, user.notice by default as relays do per RFC 3164
+var (
+	defaultFacility uint8 = 1
+	defaultSeverity uint8 = 5
+)
+
+func init() {
+	if utils.DefaultFacility != "" {
+		if facility, err := parseFacility(utils.DefaultFacility); err == nil {
+			defaultFacility = facility
+		} else {
+			log.Printf("Invalid SLOGGO_DEFAULT_FACILITY, using %d: %v", defaultFacility, err)
+		}
+	}
+
+	if utils.DefaultSeverity != "" {
+		if severity, err := ParseSeverity(utils.DefaultSeverity); err == nil {
+			defaultSeverity = severity
+		} else {
+			log.Printf("Invalid SLOGGO_DEFAULT_SEVERITY, using %d: %v", defaultSeverity, err)
+		}
+	}
+}
+
+// AddMissingPriority prefixes a message without a PRI with the default priority
+// Such messages would be rejected otherwise, or read as kernel emergencies with a priority of 0
+func AddMissingPriority(message string) string {
+	if strings.HasPrefix(message, "<") {
+		return message
+	}
+
+	return fmt.Sprintf("<%d>%s", int(defaultFacility)*8+int(defaultSeverity), message)
+}
@@ -0,0 +1,64 @@
+package formats
+
+import (
+	"testing"
+
+	"github.com/leodido/go-syslog/v4/rfc5424"
+)
+
+func TestAddMissingPriority(t *testing.T) {
+	originalFacility, originalSeverity := defaultFacility, defaultSeverity
+	defer func() {
+		defaultFacility, defaultSeverity = originalFacility, originalSeverity
+	}()
+	defaultFacility, defaultSeverity = 16, 6
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"1 2023-10-01T12:34:56Z host app - - - No PRI", "<134>1 2023-10-01T12:34:56Z host app - - - No PRI"},
+		{"Oct 11 22:14:15 host su: No PRI", "<134>Oct 11 22:14:15 host su: No PRI"},
+		{"<11>1 2023-10-01T12:34:56Z host app - - - With PRI", "<11>1 2023-10-01T12:34:56Z host app - - - With PRI"},
+	}
+
+	for _, tc := range testCases {
+		if got := AddMissingPriority(tc.input); got != tc.expected {
+			t.Errorf("AddMissingPriority(%q) = %q, want %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestMissingPriorityUsesDefaults(t *testing.T) {
+	originalFacility, originalSeverity := defaultFacility, defaultSeverity
+	defer func() {
+		defaultFacility, defaultSeverity = originalFacility, originalSeverity
+	}()
+	defaultFacility, defaultSeverity = 16, 6
+
+	// A message without a PRI is parsed with the configured default, not as an emergency
+	parser := rfc5424.NewParser(rfc5424.WithBestEffort())
+	parsed, err := parser.Parse([]byte(AddMissingPriority("1 2023-10-01T12:34:56Z host app - - - No PRI")))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	entry := SyslogMessageToLogEntry(parsed.(*rfc5424.SyslogMessage))
+	if entry.Facility != 16 || entry.Severity != 6 {
+		t.Errorf("Expected the default local0.info, got facility %d and severity %d", entry.Facility, entry.Severity)
+	}
+
+	// Same for a parsed message missing its priority
+	entry = SyslogMessageToLogEntry(&rfc5424.SyslogMessage{})
+	if entry.Facility != 16 || entry.Severity != 6 {
+		t.Errorf("Expected the default local0.info without a priority, got facility %d and severity %d", entry.Facility, entry.Severity)
+	}
+
+	entry, err = ParseRFC3164ToLogEntry(AddMissingPriority("Oct 11 22:14:15 host su: No PRI"))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	if entry.Facility != 16 || entry.Severity != 6 {
+		t.Errorf("Expected the default local0.info for RFC3164, got facility %d and severity %d", entry.Facility, entry.Severity)
+	}
+}
@@ -83,8 +83,6 @@ func ParseRFC3164ToLogEntry(line string) (*models.LogEntry, error) {
         procID = "-"
     }
 
-    msg := groups["msg"]
-
     entry := &models.LogEntry{
         Severity:       severity,
         Facility:       facility,
@@ -94,9 +92,10 @@ func ParseRFC3164ToLogEntry(line string) (*models.LogEntry, error) {
         AppName:        appName,
         ProcID:         procID,
         MsgID:          "-",
-        StructuredData: "-",
-        Message:        msg,
+        StructuredData: "",
+        Message:        groups["msg"],
     }
+    normalizeEntry(entry, now)
 
     return entry, nil
 }
@@ -2,13 +2,21 @@ package formats
 
 import (
 	"encoding/json"
+	"expvar"
 	"log"
 	"sloggo/models"
+	"strings"
 	"time"
 
 	"github.com/leodido/go-syslog/v4/rfc5424"
 )
 
+// utf8BOM is the byte order mark starting the UTF-8 messages of RFC5424 (MSG-UTF8)
+const utf8BOM = "\xef\xbb\xbf"
+
+// utf8Messages counts the messages marked as UTF-8 by a BOM
+var utf8Messages = expvar.NewInt("utf8Messages")
+
 // GetFacilityFromPriority extracts the facility from a syslog priority value
 func GetFacilityFromPriority(priority *uint8) uint8 {
 	if priority == nil {
@@ -31,20 +39,18 @@ func SyslogMessageToLogEntry(msg *rfc5424.SyslogMessage) *models.LogEntry {
 		return nil
 	}
 
-	// Calculate facility and severity from priority
-	var facility, severity uint8
+	// Calculate facility and severity from priority, messages without one get the configured defaults
+	facility, severity := defaultFacility, defaultSeverity
 	if msg.Priority != nil {
 		facility = GetFacilityFromPriority(msg.Priority)
 		severity = GetSeverityFromPriority(msg.Priority)
 	}
 
 	// Use timestamp from message or current time
-	var timestamp time.Time
+	now := time.Now()
+	timestamp := now
 	if msg.Timestamp != nil {
 		timestamp = *msg.Timestamp
-	} else {
-		// Use current time if timestamp is missing
-		timestamp = time.Now()
 	}
 
 	// Use default values for nil pointers
@@ -69,9 +75,14 @@ func SyslogMessageToLogEntry(msg *rfc5424.SyslogMessage) *models.LogEntry {
 	}
 
 	// Format structured data
-	structuredData := "-"
+	structuredData := ""
 	if msg.StructuredData != nil && len(*msg.StructuredData) > 0 {
 		structuredData = formatStructuredData(*msg.StructuredData)
+
+		// Senders unable to set the PRI can carry the severity in structured data
+		if sdSeverity, ok := SeverityFromStructuredData(*msg.StructuredData); ok {
+			severity = sdSeverity
+		}
 	}
 
 	// Get message content
@@ -80,6 +91,12 @@ func SyslogMessageToLogEntry(msg *rfc5424.SyslogMessage) *models.LogEntry {
 		msgContent = *msg.Message
 	}
 
+	// The BOM only marks the message as UTF-8, it is not part of the content
+	if content, ok := strings.CutPrefix(msgContent, utf8BOM); ok {
+		msgContent = content
+		utf8Messages.Add(1)
+	}
+
 	// Create the entry
 	entry := &models.LogEntry{
 		Severity:       severity,
@@ -93,6 +110,7 @@ func SyslogMessageToLogEntry(msg *rfc5424.SyslogMessage) *models.LogEntry {
 		StructuredData: structuredData,
 		Message:        msgContent,
 	}
+	normalizeEntry(entry, now)
 
 	return entry
 }
@@ -1,6 +1,7 @@
 package formats
 
 import (
+	"encoding/json"
 	"sloggo/models"
 	"testing"
 
@@ -24,7 +25,7 @@ func TestSyslogMessageToLogEntry(t *testing.T) {
 				AppName:        "example-app",
 				ProcID:         "1234",
 				MsgID:          "5678",
-				StructuredData: "-",
+				StructuredData: "",
 				Message:        "Test log message",
 			},
 		},
@@ -39,7 +40,7 @@ func TestSyslogMessageToLogEntry(t *testing.T) {
 				AppName:        "kernel",
 				ProcID:         "0",
 				MsgID:          "-",
-				StructuredData: "-",
+				StructuredData: "",
 				Message:        "Kernel panic",
 			},
 		},
@@ -58,6 +59,21 @@ func TestSyslogMessageToLogEntry(t *testing.T) {
 				Message:        "Message with structured data",
 			},
 		},
+		{
+			name:  "UTF-8 message with a BOM",
+			input: "<14>1 2023-10-01T12:34:56Z host1 app1 - - - \xef\xbb\xbfcaf\u00e9 ouvert",
+			expected: models.LogEntry{
+				Severity:       6,
+				Facility:       1,
+				Version:        1,
+				Hostname:       "host1",
+				AppName:        "app1",
+				ProcID:         "-",
+				MsgID:          "-",
+				StructuredData: "",
+				Message:        "caf\u00e9 ouvert",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -171,3 +187,49 @@ func TestGetSeverityFromPriority(t *testing.T) {
 func uint8Ptr(v uint8) *uint8 {
 	return &v
 }
+
+func TestPriorityRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		priority int
+	}{
+		{"<0>1 2023-10-01T12:34:56Z host2 kernel 0 - - Kernel panic", 0},
+		{"<13>1 2023-10-01T12:34:56Z example-host example-app 1234 5678 - Test log message", 13},
+		{"<165>1 2023-10-01T12:34:56Z host1 app1 2345 ID01 - Message", 165},
+		{"<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8", 34},
+		{"<191>Nov  6 09:01:02 esphome-device esphome[1234]: Sensor reading: 42", 191},
+	}
+
+	parser := rfc5424.NewParser(rfc5424.WithBestEffort())
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var entry *models.LogEntry
+			if syslogMsg, err := parser.Parse([]byte(tt.input)); err == nil {
+				entry = SyslogMessageToLogEntry(syslogMsg.(*rfc5424.SyslogMessage))
+			} else {
+				entry, err = ParseRFC3164ToLogEntry(tt.input)
+				if err != nil {
+					t.Fatalf("Failed to parse message: %v", err)
+				}
+			}
+
+			jsonBytes, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatalf("Failed to marshal entry: %v", err)
+			}
+
+			var result map[string]any
+			if err := json.Unmarshal(jsonBytes, &result); err != nil {
+				t.Fatalf("Failed to unmarshal entry: %v", err)
+			}
+
+			if result["priority"] != float64(tt.priority) {
+				t.Errorf("Priority: got %v, want %d", result["priority"], tt.priority)
+			}
+			if result["hostname"] != entry.Hostname {
+				t.Errorf("Hostname: got %v, want %q", result["hostname"], entry.Hostname)
+			}
+		})
+	}
+}
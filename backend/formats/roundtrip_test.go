@@ -0,0 +1,157 @@
+package formats
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sloggo/models"
+	"testing"
+
+	"github.com/leodido/go-syslog/v4/rfc5424"
+)
+
+// parseAuto parses a raw line with the listeners' parsing in auto mode
+func parseAuto(line string) (*models.LogEntry, error) {
+	entry, err := ParseSyslogMessage(rfc5424.NewParser(rfc5424.WithBestEffort()), line, "auto")
+	if err == nil && entry == nil {
+		return nil, errors.New("nil entry without error")
+	}
+	return entry, err
+}
+
+// checkEntryInvariants asserts the properties every parsed entry must satisfy to be stored and displayed
+func checkEntryInvariants(t *testing.T, line string, entry *models.LogEntry) {
+	t.Helper()
+
+	if entry.Severity > 7 {
+		t.Errorf("%q: severity out of range: %d", line, entry.Severity)
+	}
+	if entry.Facility > 23 {
+		t.Errorf("%q: facility out of range: %d", line, entry.Facility)
+	}
+	if entry.Timestamp.IsZero() {
+		t.Errorf("%q: zero timestamp", line)
+	}
+	if entry.Hostname == "" || entry.AppName == "" || entry.ProcID == "" || entry.MsgID == "" {
+		t.Errorf("%q: empty header field instead of the nil value \"-\": %+v", line, entry)
+	}
+	if entry.StructuredData != "" && !json.Valid([]byte(entry.StructuredData)) {
+		t.Errorf("%q: structured data is not valid JSON: %q", line, entry.StructuredData)
+	}
+}
+
+func TestParseAutoRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		parses   bool
+		severity uint8
+		facility uint8
+		hostname string
+		message  string
+	}{
+		{"RFC5424 basic", "<13>1 2023-10-01T12:34:56Z host app 1234 ID1 - Test message", true, 5, 1, "host", "Test message"},
+		{"RFC5424 all nil values", "<13>1 - - - - - -", true, 5, 1, "-", ""},
+		{"RFC5424 empty message", "<13>1 2023-10-01T12:34:56Z host app - - -", true, 5, 1, "host", ""},
+		{"RFC5424 fractional timestamp with offset", "<13>1 2023-10-01T12:34:56.123456+02:00 host app - - - Offset", true, 5, 1, "host", "Offset"},
+		{"RFC5424 multiple SD elements", `<13>1 2023-10-01T12:34:56Z host app - - [a@1 k="v"][b@1 x="y"] Multi SD`, true, 5, 1, "host", "Multi SD"},
+		{"RFC5424 highest priority value", "<191>1 2023-10-01T12:34:56Z host app - - - Local7 debug", true, 7, 23, "host", "Local7 debug"},
+		{"RFC5424 lowest priority value", "<0>1 2023-10-01T12:34:56Z host kernel - - - Panic", true, 0, 0, "host", "Panic"},
+		{"RFC5424 BOM in message", "<13>1 2023-10-01T12:34:56Z host app - - - \xEF\xBB\xBFWith BOM", true, 5, 1, "host", "With BOM"},
+		{"RFC3164 basic", "<34>Oct 11 22:14:15 mymachine su: 'su root' failed", true, 2, 4, "mymachine", "'su root' failed"},
+		{"RFC3164 single digit day", "<34>Oct  1 22:14:15 mymachine su: Single digit day", true, 2, 4, "mymachine", "Single digit day"},
+		{"RFC3164 with pid and trailing spaces", "<190>Nov  6 09:01:02 esphome-device esphome[1234]: Sensor reading: 42   ", true, 6, 23, "esphome-device", "Sensor reading: 42"},
+		{"RFC3164 empty message", "<34>Oct 11 22:14:15 mymachine su:", true, 2, 4, "mymachine", ""},
+		{"RFC3164 without priority", "Oct 11 22:14:15 mymachine su: No PRI", true, 5, 1, "mymachine", "No PRI"},
+		{"RFC5424 without priority", "1 2023-10-01T12:34:56Z host app - - - No PRI", true, 5, 1, "host", "No PRI"},
+		{"priority out of range", "<192>Oct 11 22:14:15 mymachine su: Too high", false, 0, 0, "", ""},
+		{"empty line", "", false, 0, 0, "", ""},
+		{"whitespace only", "   ", false, 0, 0, "", ""},
+		{"no priority", "just some text", false, 0, 0, "", ""},
+		{"truncated after priority", "<13>", false, 0, 0, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := parseAuto(tc.line)
+			if !tc.parses {
+				if err == nil {
+					t.Errorf("expected %q to be rejected, got %+v", tc.line, entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected %q to parse, got error: %v", tc.line, err)
+			}
+
+			checkEntryInvariants(t, tc.line, entry)
+
+			if entry.Severity != tc.severity || entry.Facility != tc.facility {
+				t.Errorf("severity/facility mismatch: got (%d,%d), want (%d,%d)", entry.Severity, entry.Facility, tc.severity, tc.facility)
+			}
+			if entry.Hostname != tc.hostname {
+				t.Errorf("hostname mismatch: got %q, want %q", entry.Hostname, tc.hostname)
+			}
+			if entry.Message != tc.message {
+				t.Errorf("message mismatch: got %q, want %q", entry.Message, tc.message)
+			}
+		})
+	}
+}
+
+// FuzzParseAuto checks that arbitrary input never panics and that parsed entries always satisfy the invariants
+func FuzzParseAuto(f *testing.F) {
+	seeds := []string{
+		"<13>1 2023-10-01T12:34:56Z host app 1234 ID1 - Test message",
+		"<13>1 - - - - - -",
+		`<13>1 2023-10-01T12:34:56Z host app - - [a@1 k="v\"q"] SD`,
+		"<34>Oct 11 22:14:15 mymachine su: 'su root' failed",
+		"<190>Nov  6 09:01:02 esphome-device esphome[1234]: Sensor reading: 42",
+		"<999>Oct 11 22:14:15 host app: Invalid priority",
+		"<13>",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		entry, err := parseAuto(line)
+		if err != nil {
+			return
+		}
+		checkEntryInvariants(t, line, entry)
+	})
+}
+
+func TestToRFC5424RoundTrip(t *testing.T) {
+	lines := []string{
+		"<13>1 2023-10-01T12:34:56Z host app 1234 ID1 - Test message",
+		"<13>1 2023-10-01T12:34:56.123456+02:00 host app - - - Offset",
+		`<13>1 2023-10-01T12:34:56Z host app - - [a@1 k="v\"q" z="\]\\"][b@1 x="y"] Multi SD`,
+		"<11>1 2023-10-01T12:34:56Z host app - - - java.lang.Exception: boom\n\tat A.b(A.java:1)",
+		"<13>1 2023-10-01T12:34:56Z host app - - -",
+		"<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed",
+	}
+
+	for _, line := range lines {
+		entry, err := parseAuto(line)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", line, err)
+		}
+
+		message := entry.ToRFC5424()
+		reparsed, err := parseAuto(message)
+		if err != nil {
+			t.Fatalf("Failed to parse the reconstructed message %q: %v", message, err)
+		}
+
+		if !reparsed.Timestamp.Equal(entry.Timestamp) {
+			t.Errorf("%q: timestamp mismatch: got %v, want %v", message, reparsed.Timestamp, entry.Timestamp)
+		}
+		reparsed.Timestamp = entry.Timestamp
+		if !reflect.DeepEqual(reparsed, entry) {
+			t.Errorf("%q: got %+v, want %+v", message, *reparsed, *entry)
+		}
+	}
+}
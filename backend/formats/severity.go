@@ -0,0 +1,47 @@
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// severityLevels maps the common level names to syslog severities
+var severityLevels = map[string]uint8{
+	"emerg":     0,
+	"emergency": 0,
+	"panic":     0,
+	"alert":     1,
+	"crit":      2,
+	"critical":  2,
+	"fatal":     2,
+	"err":       3,
+	"error":     3,
+	"warn":      4,
+	"warning":   4,
+	"notice":    5,
+	"info":      6,
+	"debug":     7,
+	"trace":     7,
+}
+
+// ParseSeverity parses a syslog severity given as a number (0-7) or a level name
+func ParseSeverity(value string) (uint8, error) {
+	value = strings.TrimSpace(value)
+
+	if severity, ok := ParseSeverityLevel(value); ok {
+		return severity, nil
+	}
+
+	severity, err := strconv.Atoi(value)
+	if err != nil || severity < 0 || severity > 7 {
+		return 0, fmt.Errorf("invalid severity %q (must be 0-7 or a level name)", value)
+	}
+	return uint8(severity), nil
+}
+
+// ParseSeverityLevel returns the syslog severity of a level name such as "error" or "WARN"
+func ParseSeverityLevel(name string) (uint8, bool) {
+	severity, ok := severityLevels[strings.ToLower(name)]
+	return severity, ok
+}
@@ -0,0 +1,41 @@
+package formats
+
+import "testing"
+
+func TestParseSeverity(t *testing.T) {
+	testCases := []struct {
+		value    string
+		severity uint8
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"7", 7, true},
+		{" 3 ", 3, true},
+		{"error", 3, true},
+		{"WARN", 4, true},
+		{"emergency", 0, true},
+		{"trace", 7, true},
+		{"-1", 0, false},
+		{"8", 0, false},
+		{"verbose", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tc := range testCases {
+		severity, err := ParseSeverity(tc.value)
+		if (err == nil) != tc.valid || severity != tc.severity {
+			t.Errorf("ParseSeverity(%q): got (%d, %v), want %d valid %t", tc.value, severity, err, tc.severity, tc.valid)
+		}
+	}
+}
+
+func TestParseSeverityLevel(t *testing.T) {
+	if severity, ok := ParseSeverityLevel("Critical"); !ok || severity != 2 {
+		t.Errorf("Expected critical to be severity 2, got (%d, %t)", severity, ok)
+	}
+
+	// Numbers are not level names
+	if _, ok := ParseSeverityLevel("3"); ok {
+		t.Error("Expected a number not to be a level name")
+	}
+}
@@ -0,0 +1,28 @@
+package formats
+
+import (
+	"expvar"
+	"sloggo/utils"
+	"time"
+)
+
+// timestampsClamped counts the message timestamps replaced by the receive time in clamp mode
+var timestampsClamped = expvar.NewInt("timestampsClamped")
+
+// ResolveTimestamp picks the stored timestamp according to SLOGGO_TIMESTAMP_SOURCE
+// "message" keeps the message time, "receive" always uses the receive time, and "clamp" uses the
+// receive time only when the message time is more than SLOGGO_TIMESTAMP_TOLERANCE_SECONDS away from it
+func ResolveTimestamp(messageTime, receiveTime time.Time) time.Time {
+	switch utils.TimestampSource {
+	case "receive":
+		return receiveTime
+	case "clamp":
+		tolerance := time.Duration(utils.TimestampToleranceSeconds) * time.Second
+		if skew := messageTime.Sub(receiveTime).Abs(); skew > tolerance {
+			timestampsClamped.Add(1)
+			return receiveTime
+		}
+	}
+
+	return messageTime
+}
@@ -0,0 +1,70 @@
+package formats
+
+import (
+	"sloggo/utils"
+	"testing"
+	"time"
+
+	"github.com/leodido/go-syslog/v4/rfc5424"
+)
+
+func TestResolveTimestamp(t *testing.T) {
+	originalSource := utils.TimestampSource
+	originalTolerance := utils.TimestampToleranceSeconds
+	defer func() {
+		utils.TimestampSource = originalSource
+		utils.TimestampToleranceSeconds = originalTolerance
+	}()
+	utils.TimestampToleranceSeconds = 3600
+
+	received := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	skewed := received.Add(-30 * time.Minute)
+	farPast := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	farFuture := received.Add(48 * time.Hour)
+
+	tests := []struct {
+		source   string
+		message  time.Time
+		expected time.Time
+		clamped  int64
+	}{
+		{"message", farPast, farPast, 0},
+		{"receive", skewed, received, 0},
+		{"clamp", skewed, skewed, 0},
+		{"clamp", farPast, received, 1},
+		{"clamp", farFuture, received, 1},
+	}
+
+	for _, tt := range tests {
+		utils.TimestampSource = tt.source
+		clampedBefore := timestampsClamped.Value()
+
+		if got := ResolveTimestamp(tt.message, received); !got.Equal(tt.expected) {
+			t.Errorf("ResolveTimestamp(%s, %v) = %v, want %v", tt.source, tt.message, got, tt.expected)
+		}
+		if clamped := timestampsClamped.Value() - clampedBefore; clamped != tt.clamped {
+			t.Errorf("ResolveTimestamp(%s, %v) counted %d clamps, want %d", tt.source, tt.message, clamped, tt.clamped)
+		}
+	}
+}
+
+func TestSyslogMessageTimestampClamped(t *testing.T) {
+	originalSource := utils.TimestampSource
+	defer func() {
+		utils.TimestampSource = originalSource
+	}()
+	utils.TimestampSource = "clamp"
+
+	// A device whose clock was reset to the epoch
+	parser := rfc5424.NewParser(rfc5424.WithBestEffort())
+	msg, err := parser.Parse([]byte("<13>1 1970-01-01T00:00:05Z host app - - - Bad clock"))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	before := time.Now()
+	entry := SyslogMessageToLogEntry(msg.(*rfc5424.SyslogMessage))
+	if entry.Timestamp.Before(before) || entry.Timestamp.After(time.Now()) {
+		t.Errorf("Expected the receive time, got %v", entry.Timestamp)
+	}
+}
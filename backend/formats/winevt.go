@@ -0,0 +1,76 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"sloggo/models"
+	"strings"
+)
+
+// WinEvtSDID is the structured data element holding the lifted Windows event fields
+const WinEvtSDID = "winevt"
+
+// ApplyWinEvt lifts the Windows event fields of an nxlog JSON payload into the entry
+// Example message: {"EventID":4624,"Channel":"Security","SourceName":"Microsoft-Windows-Security-Auditing",...}
+// EventID and Channel go into structured data, the provider becomes the app name
+// It returns false when the message doesn't carry such a payload
+func ApplyWinEvt(entry *models.LogEntry) bool {
+	if entry == nil {
+		return false
+	}
+
+	message := strings.TrimSpace(entry.Message)
+	if !strings.HasPrefix(message, "{") {
+		return false
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		return false
+	}
+
+	eventID := winEvtField(payload, "EventID")
+	if eventID == "" {
+		return false
+	}
+
+	params := map[string]string{"EventID": eventID}
+
+	if channel := winEvtField(payload, "Channel"); channel != "" {
+		params["Channel"] = channel
+	}
+
+	// nxlog names the provider SourceName, other forwarders use ProviderName or Provider
+	if provider := winEvtField(payload, "ProviderName", "Provider", "SourceName"); provider != "" {
+		params["Provider"] = provider
+		entry.AppName = provider
+	}
+
+	// Keep any structured data already present in the syslog envelope
+	structData := make(map[string]map[string]string)
+	if entry.StructuredData != "" {
+		if err := json.Unmarshal([]byte(entry.StructuredData), &structData); err != nil {
+			structData = make(map[string]map[string]string)
+		}
+	}
+	structData[WinEvtSDID] = params
+
+	entry.StructuredData = formatStructuredData(structData)
+
+	return true
+}
+
+// winEvtField returns the first non-empty value among the given keys as a string
+func winEvtField(payload map[string]any, keys ...string) string {
+	for _, key := range keys {
+		switch value := payload[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", value)
+		}
+	}
+	return ""
+}
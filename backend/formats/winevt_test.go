@@ -0,0 +1,69 @@
+package formats
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyWinEvt(t *testing.T) {
+	line := `<14>Jan 15 10:30:00 WINHOST Microsoft-Windows-Security-Auditing[4]: {"EventTime":"2024-01-15 10:30:00","Hostname":"WINHOST","Keywords":-9214364837600034816,"EventType":"AUDIT_SUCCESS","SeverityValue":2,"Severity":"INFO","EventID":4624,"SourceName":"Microsoft-Windows-Security-Auditing","ProviderGuid":"{54849625-5478-4994-A5BA-3E3B0328C30D}","Version":2,"Task":12544,"OpcodeValue":0,"RecordNumber":123456,"ProcessID":4,"ThreadID":5,"Channel":"Security","Message":"An account was successfully logged on."}`
+
+	entry, err := ParseRFC3164ToLogEntry(line)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !ApplyWinEvt(entry) {
+		t.Fatal("expected nxlog payload to be recognized")
+	}
+
+	if entry.AppName != "Microsoft-Windows-Security-Auditing" {
+		t.Errorf("appname: got %q", entry.AppName)
+	}
+	if entry.Hostname != "WINHOST" {
+		t.Errorf("hostname: got %q", entry.Hostname)
+	}
+
+	var structData map[string]map[string]string
+	if err := json.Unmarshal([]byte(entry.StructuredData), &structData); err != nil {
+		t.Fatalf("invalid structured data %q: %v", entry.StructuredData, err)
+	}
+
+	expected := map[string]string{
+		"EventID":  "4624",
+		"Channel":  "Security",
+		"Provider": "Microsoft-Windows-Security-Auditing",
+	}
+	for key, value := range expected {
+		if structData[WinEvtSDID][key] != value {
+			t.Errorf("%s: got %q, want %q", key, structData[WinEvtSDID][key], value)
+		}
+	}
+}
+
+func TestApplyWinEvt_NotWinEvt(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message string
+	}{
+		{"plain text", "Sensor reading: 42"},
+		{"invalid json", `{"EventID":`},
+		{"json without event id", `{"Channel":"Security"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ParseRFC3164ToLogEntry("<14>Jan 15 10:30:00 host app: " + tc.message)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ApplyWinEvt(entry) {
+				t.Error("expected message not to be recognized as a Windows event")
+			}
+			if entry.AppName != "app" || entry.StructuredData != "" {
+				t.Errorf("entry should be left untouched, got appname %q and structured data %q", entry.AppName, entry.StructuredData)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ go 1.24
 require (
 	github.com/leodido/go-syslog/v4 v4.2.0
 	github.com/marcboeker/go-duckdb/v2 v2.3.5
+	golang.org/x/text v0.26.0
 )
 
 require (
@@ -0,0 +1,91 @@
+package listener
+
+import (
+	"expvar"
+	"log"
+	"net"
+	"slices"
+	"strings"
+
+	"sloggo/utils"
+)
+
+// droppedMessages counts the messages dropped by SLOGGO_ALLOWED_HOSTS and SLOGGO_ALLOWED_IPS
+var droppedMessages = expvar.NewInt("droppedNotAllowed")
+
+var (
+	// allowedHosts holds the lowercase hostnames of SLOGGO_ALLOWED_HOSTS, nil to accept every hostname
+	allowedHosts map[string]bool
+
+	// allowedNetworks holds the networks of SLOGGO_ALLOWED_IPS, nil to accept every source IP
+	allowedNetworks []*net.IPNet
+)
+
+func init() {
+	allowedHosts = parseAllowedHosts(utils.AllowedHosts)
+	allowedNetworks = parseAllowedNetworks(utils.AllowedIPs)
+}
+
+// parseAllowedHosts parses a comma-separated list of hostnames, nil when empty
+func parseAllowedHosts(config string) map[string]bool {
+	if strings.TrimSpace(config) == "" {
+		return nil
+	}
+
+	hosts := make(map[string]bool)
+	for host := range strings.SplitSeq(config, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts[host] = true
+		}
+	}
+	return hosts
+}
+
+// parseAllowedNetworks parses a comma-separated list of CIDRs or IPs, nil when empty
+// Invalid entries are logged and ignored, a list without any valid entry accepts no IP rather than every IP
+func parseAllowedNetworks(config string) []*net.IPNet {
+	if strings.TrimSpace(config) == "" {
+		return nil
+	}
+
+	networks := []*net.IPNet{}
+	for entry := range strings.SplitSeq(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Ignoring invalid SLOGGO_ALLOWED_IPS entry %q, expected a CIDR such as 10.0.0.0/8 or an IP", entry)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isAllowedSource reports whether the source IP passes SLOGGO_ALLOWED_IPS, an unset list accepts every IP
+// It's checked once per connection or datagram, before anything is read or parsed
+func isAllowedSource(source string) bool {
+	if allowedNetworks == nil {
+		return true
+	}
+
+	ip := net.ParseIP(source)
+	return ip != nil && slices.ContainsFunc(allowedNetworks, func(network *net.IPNet) bool { return network.Contains(ip) })
+}
+
+// isAllowedHostname reports whether the hostname of a message passes SLOGGO_ALLOWED_HOSTS, an unset list accepts every hostname
+func isAllowedHostname(hostname string) bool {
+	return allowedHosts == nil || allowedHosts[strings.ToLower(hostname)]
+}
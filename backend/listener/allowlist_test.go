@@ -0,0 +1,135 @@
+package listener
+
+import (
+	"net"
+	"sloggo/db"
+	"testing"
+	"time"
+)
+
+func TestIsAllowedSourceAndHostname(t *testing.T) {
+	originalHosts, originalNetworks := allowedHosts, allowedNetworks
+	defer func() {
+		allowedHosts, allowedNetworks = originalHosts, originalNetworks
+	}()
+
+	testCases := []struct {
+		name     string
+		hosts    string
+		ips      string
+		source   string
+		hostname string
+		allowed  bool
+	}{
+		{"no allowlist", "", "", "203.0.113.9", "any-host", true},
+		{"allowed hostname", "web-1, DB-1", "", "203.0.113.9", "db-1", true},
+		{"unknown hostname", "web-1,db-1", "", "203.0.113.9", "web-2", false},
+		{"allowed network", "", "10.0.0.0/8, 192.0.2.1", "10.1.2.3", "any-host", true},
+		{"allowed IP", "", "10.0.0.0/8,192.0.2.1", "192.0.2.1", "any-host", true},
+		{"allowed IPv6 network", "", "2001:db8::/32", "2001:db8::1", "any-host", true},
+		{"unknown IP", "", "10.0.0.0/8,192.0.2.1", "192.0.2.2", "any-host", false},
+		{"unknown source", "", "10.0.0.0/8", "unknown", "any-host", false},
+		{"both allowed", "web-1", "10.0.0.0/8", "10.1.2.3", "web-1", true},
+		{"allowed IP unknown hostname", "web-1", "10.0.0.0/8", "10.1.2.3", "web-2", false},
+		{"only invalid networks", "", "not-a-network", "10.1.2.3", "any-host", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowedHosts = parseAllowedHosts(tc.hosts)
+			allowedNetworks = parseAllowedNetworks(tc.ips)
+
+			if allowed := isAllowedSource(tc.source) && isAllowedHostname(tc.hostname); allowed != tc.allowed {
+				t.Errorf("isAllowedSource(%q) && isAllowedHostname(%q) = %v, want %v", tc.source, tc.hostname, allowed, tc.allowed)
+			}
+		})
+	}
+}
+
+func TestTCPConnectionDropsUnknownHosts(t *testing.T) {
+	originalHosts := allowedHosts
+	defer func() {
+		allowedHosts = originalHosts
+	}()
+	allowedHosts = parseAllowedHosts("allowed-host")
+
+	serverConn, clientConn := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	droppedBefore := droppedMessages.Value()
+
+	messages := "<13>1 2023-10-01T12:34:56Z allowed-host allowlist-app - - - Allowed message\n" +
+		"<13>1 2023-10-01T12:34:56Z denied-host allowlist-app - - - Denied message\n"
+	if _, err := clientConn.Write([]byte(messages)); err != nil {
+		t.Fatalf("Failed to send log message: %v", err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after the connection was closed")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var allowed, denied int
+	query := "SELECT COUNT(*) FILTER (WHERE hostname = 'allowed-host'), COUNT(*) FILTER (WHERE hostname = 'denied-host') FROM logs WHERE app_name = ?"
+	if err := db.GetDBInstance().QueryRow(query, "allowlist-app").Scan(&allowed, &denied); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if allowed != 1 || denied != 0 {
+		t.Errorf("Expected only the allowed host to be stored, got %d allowed and %d denied", allowed, denied)
+	}
+
+	if dropped := droppedMessages.Value() - droppedBefore; dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", dropped)
+	}
+}
+
+func TestTCPConnectionFromUnknownIPNotRead(t *testing.T) {
+	originalNetworks := allowedNetworks
+	defer func() {
+		allowedNetworks = originalNetworks
+	}()
+	allowedNetworks = parseAllowedNetworks("10.0.0.0/8")
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	droppedBefore := droppedByReason.Get(dropAllowlist)
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	// The pipe source isn't an IP in the allowed network, the connection is closed without reading
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not reject the connection")
+	}
+
+	if _, err := clientConn.Write([]byte("<13>1 2023-10-01T12:34:56Z host app - - - Never read\n")); err == nil {
+		t.Error("Expected the rejected connection to be closed")
+	}
+
+	if dropped := droppedByReason.Get(dropAllowlist); dropped == nil || (droppedBefore != nil && dropped.String() == droppedBefore.String()) {
+		t.Error("Expected the rejected connection to be counted as an allowlist drop")
+	}
+
+	for _, source := range receivedSources.list() {
+		if source.Address == sourceAddress(serverConn.RemoteAddr()) {
+			t.Errorf("Expected the rejected connection not to be tracked as a source, got %+v", source)
+		}
+	}
+}
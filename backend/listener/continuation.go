@@ -0,0 +1,55 @@
+package listener
+
+import (
+	"regexp"
+	"strings"
+)
+
+// syslogPriRegex matches the PRI starting every syslog message
+var syslogPriRegex = regexp.MustCompile(`^<\d{1,3}>`)
+
+// isContinuationLine reports whether a frame continues the previous message, like the lines of a stack trace
+// Continuation lines start with whitespace or don't start with a syslog PRI
+func isContinuationLine(frame string) bool {
+	if strings.HasPrefix(frame, " ") || strings.HasPrefix(frame, "\t") {
+		return true
+	}
+
+	return !syslogPriRegex.MatchString(frame)
+}
+
+// continuationJoiner joins the continuation lines of a connection to the message they follow
+// A message is complete once the next message starts, or when the connection ends
+type continuationJoiner struct {
+	pending string
+	started bool
+}
+
+// add adds a frame and returns the previous message once the frame starts a new one
+func (j *continuationJoiner) add(frame string) (string, bool) {
+	frame = strings.TrimSuffix(frame, "\r")
+
+	if j.started && isContinuationLine(frame) {
+		j.pending += "\n" + frame
+		return "", false
+	}
+
+	message, complete := j.flush()
+	j.pending = frame
+	j.started = true
+
+	return message, complete
+}
+
+// flush returns the pending message, if any
+func (j *continuationJoiner) flush() (string, bool) {
+	if !j.started {
+		return "", false
+	}
+
+	message := j.pending
+	j.pending = ""
+	j.started = false
+
+	return message, true
+}
@@ -0,0 +1,91 @@
+package listener
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// drainIdleTimeout is how long a draining connection waits for more data before it's considered drained
+const drainIdleTimeout = 250 * time.Millisecond
+
+// connectionTracker tracks the active TCP connections so they can be drained on shutdown
+type connectionTracker struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	draining bool
+}
+
+// tcpConnections holds the connections served by handleTCPConnection
+var tcpConnections = &connectionTracker{conns: make(map[net.Conn]struct{})}
+
+// add starts tracking a connection, done must be called once it's closed
+func (t *connectionTracker) add(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.conns[conn] = struct{}{}
+	t.wg.Add(1)
+}
+
+// done stops tracking a connection
+func (t *connectionTracker) done(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.conns, conn)
+	t.wg.Done()
+}
+
+// readDeadline returns the deadline of the next read of a connection
+// While draining, connections only wait briefly for more data so idle ones are released quickly
+func (t *connectionTracker) readDeadline(readTimeout time.Duration) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		readTimeout = min(readTimeout, drainIdleTimeout)
+	}
+
+	return time.Now().Add(readTimeout)
+}
+
+// drain lets the active connections read and store the data already on the wire for up to the grace period,
+// then force-closes the remaining ones. It reports whether every connection finished in time.
+func (t *connectionTracker) drain(grace time.Duration) bool {
+	t.mu.Lock()
+	t.draining = true
+	for conn := range t.conns {
+		// Wake up reads waiting for the regular read timeout
+		conn.SetReadDeadline(time.Now().Add(min(drainIdleTimeout, grace)))
+	}
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.draining = false
+		t.mu.Unlock()
+	}()
+
+	drained := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return true
+	case <-time.After(grace):
+	}
+
+	t.mu.Lock()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mu.Unlock()
+
+	<-drained
+	return false
+}
@@ -0,0 +1,103 @@
+package listener
+
+import (
+	"cmp"
+	"expvar"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Reasons the listeners drop connections or messages
+const (
+	dropCapacity  = "capacity"  // All processors are busy
+	dropAllowlist = "allowlist" // Not in SLOGGO_ALLOWED_HOSTS or SLOGGO_ALLOWED_IPS
+)
+
+// dropReportInterval is how often the drops are logged, aggregated per source and reason
+const dropReportInterval = time.Minute
+
+// droppedByReason counts the dropped connections and messages per reason, to alert on sustained drops
+var droppedByReason = expvar.NewMap("listenerDrops")
+
+// dropKey identifies the drops of a source for a reason
+type dropKey struct {
+	source string
+	reason string
+}
+
+// dropTracker aggregates the drops until they're reported, so a flood of drops logs one line per source and reason
+type dropTracker struct {
+	mu      sync.Mutex
+	pending map[dropKey]int64
+	once    sync.Once
+}
+
+// listenerDrops holds the drops of the TCP and UDP listeners
+var listenerDrops = &dropTracker{pending: make(map[dropKey]int64)}
+
+// recordDrop counts a connection or message dropped from a source for a reason
+func recordDrop(source string, reason string) {
+	droppedByReason.Add(reason, 1)
+	listenerDrops.record(source, reason)
+}
+
+// record adds a drop to the next report, starting the periodic report on the first drop
+func (t *dropTracker) record(source string, reason string) {
+	t.once.Do(func() {
+		go t.reportPeriodically(dropReportInterval)
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := dropKey{source: source, reason: reason}
+	if _, ok := t.pending[key]; !ok && len(t.pending) >= maxSources {
+		// Under a flood from many sources, keep counting without growing the report
+		key.source = "other"
+	}
+	t.pending[key]++
+}
+
+// reportPeriodically logs the drops on a timer
+func (t *dropTracker) reportPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.report(interval)
+	}
+}
+
+// report logs the drops since the last report, one line per source and reason, and resets them
+func (t *dropTracker) report(interval time.Duration) {
+	for _, drop := range t.flush() {
+		log.Printf("Listener drops: source=%s reason=%s count=%d interval=%v", drop.source, drop.reason, drop.count, interval)
+	}
+}
+
+// droppedCount is the number of drops of a source for a reason since the last report
+type droppedCount struct {
+	dropKey
+	count int64
+}
+
+// flush returns the drops since the last report, most frequent first, and resets them
+func (t *dropTracker) flush() []droppedCount {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[dropKey]int64)
+	t.mu.Unlock()
+
+	drops := make([]droppedCount, 0, len(pending))
+	for key, count := range pending {
+		drops = append(drops, droppedCount{dropKey: key, count: count})
+	}
+
+	slices.SortFunc(drops, func(a, b droppedCount) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.source, b.source), cmp.Compare(a.reason, b.reason))
+	})
+
+	return drops
+}
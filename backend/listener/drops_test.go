@@ -0,0 +1,45 @@
+package listener
+
+import (
+	"expvar"
+	"reflect"
+	"testing"
+)
+
+func TestDropTracker(t *testing.T) {
+	tracker := &dropTracker{pending: make(map[dropKey]int64)}
+
+	for range 3 {
+		tracker.record("198.51.100.7", dropCapacity)
+	}
+	tracker.record("203.0.113.9", dropAllowlist)
+	tracker.record("198.51.100.7", dropAllowlist)
+
+	expected := []droppedCount{
+		{dropKey{"198.51.100.7", dropCapacity}, 3},
+		{dropKey{"198.51.100.7", dropAllowlist}, 1},
+		{dropKey{"203.0.113.9", dropAllowlist}, 1},
+	}
+	if drops := tracker.flush(); !reflect.DeepEqual(drops, expected) {
+		t.Errorf("got %+v, want %+v", drops, expected)
+	}
+
+	// Each report only covers the drops since the previous one
+	if drops := tracker.flush(); len(drops) != 0 {
+		t.Errorf("Expected no drops after a report, got %+v", drops)
+	}
+}
+
+func TestRecordDropCountsReasons(t *testing.T) {
+	before := int64(0)
+	if value, ok := droppedByReason.Get(dropCapacity).(*expvar.Int); ok {
+		before = value.Value()
+	}
+
+	recordDrop("192.0.2.1", dropCapacity)
+
+	after := droppedByReason.Get(dropCapacity).(*expvar.Int).Value()
+	if after-before != 1 {
+		t.Errorf("Expected 1 capacity drop, got %d", after-before)
+	}
+}
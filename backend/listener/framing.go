@@ -0,0 +1,131 @@
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"sloggo/utils"
+	"strconv"
+)
+
+const (
+	// maxTCPMessageSize is the largest TCP frame read, octet counts above it aren't taken as length prefixes
+	maxTCPMessageSize = 1024 * 1024
+
+	// maxOctetCountDigits bounds the length prefix of octet-counted frames, enough for the max message size
+	maxOctetCountDigits = 7
+)
+
+// tcpDelimiter is the byte terminating non octet-counted TCP frames
+var tcpDelimiter byte = '\n'
+
+func init() {
+	delimiter, err := parseDelimiter(utils.TcpDelimiter)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_TCP_DELIMITER, using newline: %v", err)
+		return
+	}
+
+	tcpDelimiter = delimiter
+}
+
+// parseDelimiter parses a frame delimiter given by name ("lf", "cr", "nul"), escape sequence or single character
+func parseDelimiter(value string) (byte, error) {
+	switch value {
+	case "", "lf", `\n`:
+		return '\n', nil
+	case "cr", `\r`:
+		return '\r', nil
+	case "nul", `\0`, `\x00`:
+		return 0, nil
+	}
+
+	if len(value) == 1 {
+		return value[0], nil
+	}
+
+	return 0, fmt.Errorf("unsupported delimiter %q", value)
+}
+
+// splitSyslogFrames returns a split function reading RFC 6587 frames from a TCP stream
+// Octet-counted frames ("<length> <PRI>message") take precedence, other frames end with the delimiter.
+// The PRI is required after the length, plain lines starting with a number such as "100 requests failed"
+// or RFC5424 messages without a PRI ("1 2023-10-01T...") are read up to the delimiter.
+func splitSyslogFrames(delimiter byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if length, prefixLen, ok := parseOctetCount(data); ok {
+			if len(data) >= prefixLen+length {
+				return prefixLen + length, data[prefixLen : prefixLen+length], nil
+			}
+			if atEOF {
+				// Truncated frame, keep what was received
+				return len(data), data[prefixLen:], nil
+			}
+			// Request more data
+			return 0, nil, nil
+		}
+
+		if !atEOF && isPartialOctetCount(data) {
+			// The length prefix may be split across reads
+			return 0, nil, nil
+		}
+
+		if i := bytes.IndexByte(data, delimiter); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+
+		// Final frame without a trailing delimiter
+		if atEOF {
+			return len(data), data, nil
+		}
+
+		// Request more data
+		return 0, nil, nil
+	}
+}
+
+// parseOctetCount detects an octet-counting length prefix at the start of the data, followed by the PRI of the message
+// It returns the message length and the length of the prefix including the space
+func parseOctetCount(data []byte) (length int, prefixLen int, ok bool) {
+	if len(data) == 0 || data[0] < '1' || data[0] > '9' {
+		return 0, 0, false
+	}
+
+	space := bytes.IndexByte(data, ' ')
+	if space < 0 || space > maxOctetCountDigits || len(data) <= space+1 || data[space+1] != '<' {
+		return 0, 0, false
+	}
+
+	length, err := strconv.Atoi(string(data[:space]))
+	if err != nil || length > maxTCPMessageSize {
+		return 0, 0, false
+	}
+
+	return length, space + 1, true
+}
+
+// isPartialOctetCount reports whether the data could be the beginning of a length prefix,
+// digits possibly followed by the space, the PRI not being received yet
+func isPartialOctetCount(data []byte) bool {
+	if len(data) == 0 || data[0] < '1' || data[0] > '9' {
+		return false
+	}
+
+	digits := bytes.TrimSuffix(data, []byte(" "))
+	if len(digits) > maxOctetCountDigits {
+		return false
+	}
+
+	for _, b := range digits {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+
+	return true
+}
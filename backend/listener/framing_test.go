@@ -0,0 +1,147 @@
+package listener
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDelimiter(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expected    byte
+		shouldError bool
+	}{
+		{"", '\n', false},
+		{"lf", '\n', false},
+		{"cr", '\r', false},
+		{`\r`, '\r', false},
+		{"nul", 0, false},
+		{`\x00`, 0, false},
+		{"|", '|', false},
+		{"tab", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			delimiter, err := parseDelimiter(tc.value)
+			if tc.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if delimiter != tc.expected {
+				t.Errorf("got %q, want %q", delimiter, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSplitSyslogFrames(t *testing.T) {
+	testCases := []struct {
+		name      string
+		delimiter byte
+		stream    string
+		expected  []string
+	}{
+		{
+			name:      "newline delimited frames",
+			delimiter: '\n',
+			stream:    "<13>1 - host app - - - first\n<13>1 - host app - - - second\n",
+			expected:  []string{"<13>1 - host app - - - first", "<13>1 - host app - - - second"},
+		},
+		{
+			name:      "NUL delimited frames",
+			delimiter: 0,
+			stream:    "<34>Oct 11 22:14:15 host su: first\x00<34>Oct 11 22:14:15 host su: second\x00",
+			expected:  []string{"<34>Oct 11 22:14:15 host su: first", "<34>Oct 11 22:14:15 host su: second"},
+		},
+		{
+			name:      "NUL delimited frames keep newlines",
+			delimiter: 0,
+			stream:    "<13>1 - host app - - - multi\nline\x00<13>1 - host app - - - last",
+			expected:  []string{"<13>1 - host app - - - multi\nline", "<13>1 - host app - - - last"},
+		},
+		{
+			name:      "octet counting takes precedence",
+			delimiter: 0,
+			stream:    "10 <13>1 a\x00bc5 <13>x",
+			expected:  []string{"<13>1 a\x00bc", "<13>x"},
+		},
+		{
+			name:      "RFC5424 without PRI is not octet counted",
+			delimiter: '\n',
+			stream:    "1 2023-10-01T12:34:56Z host app - - - No PRI\n2 errors occurred\n<13>ok\n",
+			expected:  []string{"1 2023-10-01T12:34:56Z host app - - - No PRI", "2 errors occurred", "<13>ok"},
+		},
+		{
+			name:      "plain line starting with a number",
+			delimiter: '\n',
+			stream:    "100 requests failed\n<13>next\n",
+			expected:  []string{"100 requests failed", "<13>next"},
+		},
+		{
+			name:      "octet count above the max message size",
+			delimiter: '\n',
+			stream:    "9999999 <13>too large\n<13>next\n",
+			expected:  []string{"9999999 <13>too large", "<13>next"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// A tiny buffer forces frames and length prefixes to be split across reads
+			scanner := bufio.NewScanner(bufio.NewReaderSize(strings.NewReader(tc.stream), 16))
+			scanner.Split(splitSyslogFrames(tc.delimiter))
+
+			frames := []string{}
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(frames, tc.expected) {
+				t.Errorf("got %q, want %q", frames, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSplitSyslogFramesBackToBack(t *testing.T) {
+	// Pipelining senders deliver several frames in a single read, all of them must be split out
+	stream := "<13>1 - host app - - - first\r<13>1 - host app - - - second\r" +
+		"28 <13>1 - host app - - - third<13>1 - host app - - - fourth\r"
+
+	data := []byte(stream)
+	split := splitSyslogFrames('\r')
+
+	frames := []string{}
+	for len(data) > 0 {
+		advance, token, err := split(data, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if advance == 0 {
+			t.Fatalf("expected every frame to be complete in the buffer, %q left", data)
+		}
+		frames = append(frames, string(token))
+		data = data[advance:]
+	}
+
+	expected := []string{
+		"<13>1 - host app - - - first",
+		"<13>1 - host app - - - second",
+		"<13>1 - host app - - - third",
+		"<13>1 - host app - - - fourth",
+	}
+	if !reflect.DeepEqual(frames, expected) {
+		t.Errorf("got %q, want %q", frames, expected)
+	}
+}
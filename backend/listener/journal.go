@@ -0,0 +1,140 @@
+package listener
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sloggo/db"
+	"sloggo/formats"
+	"sloggo/utils"
+	"sync"
+	"time"
+)
+
+// journalReadTimeout closes the journal connections idle for longer, a followed journal can stay quiet for a while
+const journalReadTimeout = time.Hour
+
+var (
+	// journalListener is the bound journal export listener, closed on shutdown
+	journalListener   net.Listener
+	journalListenerMu sync.Mutex
+)
+
+// StartJournalListener accepts systemd journal export streams over TCP
+// For example: journalctl -o export -f | nc sloggo 6515
+func StartJournalListener() {
+	port := utils.JournalPort
+
+	intPort, err := net.LookupPort("tcp", port)
+	if err != nil {
+		log.Fatalf("Invalid journal port %s: %v", port, err)
+	}
+
+	listener, boundPort, err := listenWithPortAuto("Journal", intPort, func(port int) (net.Listener, error) {
+		return net.Listen("tcp", fmt.Sprintf(":%d", port))
+	})
+	if err != nil {
+		log.Fatalf("Failed to start journal listener on port %s: %v", port, err)
+	}
+	defer listener.Close()
+
+	journalListenerMu.Lock()
+	journalListener = listener
+	journalListenerMu.Unlock()
+
+	log.Printf("Journal listener is running on port :%d", boundPort)
+
+	// Journal connections share the TCP processor limit
+	semaphore := getTCPSemaphore()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				// The listener is shut down
+				return
+			}
+			log.Printf("Error accepting journal connection: %v", err)
+			continue
+		}
+
+		if source := sourceAddress(conn.RemoteAddr()); !isAllowedSource(source) {
+			recordDrop(source, dropAllowlist)
+			conn.Close()
+			continue
+		}
+
+		select {
+		case semaphore <- struct{}{}:
+			go func(c net.Conn) {
+				defer func() { <-semaphore }()
+				handleJournalConnection(c, journalReadTimeout)
+			}(conn)
+		default:
+			recordDrop(sourceAddress(conn.RemoteAddr()), dropCapacity)
+			conn.Close()
+		}
+	}
+}
+
+// closeJournalListener stops accepting journal connections, the active ones are drained with the TCP connections
+func closeJournalListener() {
+	journalListenerMu.Lock()
+	defer journalListenerMu.Unlock()
+
+	if journalListener != nil {
+		journalListener.Close()
+	}
+}
+
+// handleJournalConnection reads journal export records from a connection until it's closed
+func handleJournalConnection(conn net.Conn, readTimeout time.Duration) {
+	tcpConnections.add(conn)
+	defer tcpConnections.done(conn)
+	defer conn.Close()
+
+	source := sourceAddress(conn.RemoteAddr())
+	reader := bufio.NewReader(conn)
+
+	for {
+		conn.SetReadDeadline(tcpConnections.readDeadline(readTimeout))
+
+		fields, err := formats.ReadJournalRecord(reader)
+		if err != nil {
+			if netErr, ok := err.(net.Error); !errors.Is(err, io.EOF) && (!ok || !netErr.Timeout()) {
+				log.Printf("Journal connection from %s closed: %v", source, err)
+			}
+			return
+		}
+
+		if storeJournalRecord(fields, source) {
+			receivedSources.record(source, 0, 1)
+		}
+	}
+}
+
+// storeJournalRecord converts and stores a journal record received from the source IP, and reports whether it was stored
+func storeJournalRecord(fields map[string]string, source string) bool {
+	logEntry, err := formats.JournalRecordToLogEntry(fields)
+	if err != nil {
+		if utils.Debug {
+			log.Printf("Skipped journal record from %s: %v", source, err)
+		}
+		return false
+	}
+
+	if !isAllowedHostname(logEntry.Hostname) {
+		droppedMessages.Add(1)
+		recordDrop(source, dropAllowlist)
+		return false
+	}
+
+	if err := db.StoreLog(*logEntry); err != nil {
+		log.Printf("Error storing log: %v", err)
+	}
+
+	return true
+}
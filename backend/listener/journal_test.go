@@ -0,0 +1,44 @@
+package listener
+
+import (
+	"net"
+	"sloggo/db"
+	"testing"
+	"time"
+)
+
+func TestJournalConnectionStoresRecords(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		handleJournalConnection(serverConn, time.Second)
+		close(done)
+	}()
+
+	records := "__REALTIME_TIMESTAMP=1696163696123456\nPRIORITY=4\n_HOSTNAME=journal-host\nSYSLOG_IDENTIFIER=journal-app\n_PID=12\nMESSAGE=First journal record\n\n" +
+		"PRIORITY=6\n_HOSTNAME=journal-host\nSYSLOG_IDENTIFIER=journal-app\nMESSAGE=Second journal record\n\n"
+	if _, err := clientConn.Write([]byte(records)); err != nil {
+		t.Fatalf("Failed to send journal records: %v", err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Journal connection handler did not return after the connection was closed")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var count, warnings int
+	query := "SELECT COUNT(*), COUNT(*) FILTER (WHERE severity = 4 AND procid = '12') FROM logs WHERE hostname = ? AND app_name = ?"
+	if err := db.GetDBInstance().QueryRow(query, "journal-host", "journal-app").Scan(&count, &warnings); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 2 || warnings != 1 {
+		t.Errorf("Expected 2 stored records including 1 warning, got %d and %d", count, warnings)
+	}
+}
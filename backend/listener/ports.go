@@ -0,0 +1,57 @@
+package listener
+
+import (
+	"log"
+	"slices"
+	"sloggo/utils"
+	"sync/atomic"
+)
+
+// maxPortAttempts bounds how many consecutive ports are tried when SLOGGO_PORT_AUTO is enabled
+const maxPortAttempts = 10
+
+// boundListeners counts the syslog listeners that successfully bound their port
+var boundListeners atomic.Int32
+
+// IsReady reports whether at least one syslog listener is bound, or none is configured
+func IsReady() bool {
+	if boundListeners.Load() > 0 {
+		return true
+	}
+
+	return !slices.Contains(utils.Listeners, "tcp") && !slices.Contains(utils.Listeners, "udp")
+}
+
+// listenWithPortAuto binds the configured port, or the next available one when SLOGGO_PORT_AUTO is enabled
+// It returns the listener along with the port that was actually bound
+func listenWithPortAuto[T any](protocol string, port int, listen func(port int) (T, error)) (T, int, error) {
+	attempts := 1
+	if utils.PortAuto {
+		attempts = maxPortAttempts
+	}
+
+	var lastErr error
+	for i := range attempts {
+		candidate := port + i
+		if candidate > 65535 {
+			break
+		}
+
+		listener, err := listen(candidate)
+		if err == nil {
+			if candidate != port {
+				log.Printf("%s port %d is unavailable, bound port %d instead", protocol, port, candidate)
+			}
+			boundListeners.Add(1)
+			return listener, candidate, nil
+		}
+
+		if utils.PortAuto {
+			log.Printf("Failed to bind %s port %d: %v", protocol, candidate, err)
+		}
+		lastErr = err
+	}
+
+	var zero T
+	return zero, 0, lastErr
+}
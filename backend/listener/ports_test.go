@@ -0,0 +1,43 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"sloggo/utils"
+	"testing"
+)
+
+func TestListenWithPortAuto(t *testing.T) {
+	originalPortAuto := utils.PortAuto
+	defer func() {
+		utils.PortAuto = originalPortAuto
+	}()
+
+	// Occupy a port so binding it fails
+	occupied, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to occupy a port: %v", err)
+	}
+	defer occupied.Close()
+
+	port := occupied.Addr().(*net.TCPAddr).Port
+	listen := func(port int) (net.Listener, error) {
+		return net.Listen("tcp", fmt.Sprintf(":%d", port))
+	}
+
+	utils.PortAuto = false
+	if _, _, err := listenWithPortAuto("TCP", port, listen); err == nil {
+		t.Fatal("Expected an error binding an occupied port without auto port")
+	}
+
+	utils.PortAuto = true
+	listener, boundPort, err := listenWithPortAuto("TCP", port, listen)
+	if err != nil {
+		t.Fatalf("Expected auto port to bind another port: %v", err)
+	}
+	defer listener.Close()
+
+	if boundPort <= port || boundPort >= port+maxPortAttempts {
+		t.Errorf("Expected a port between %d and %d, got %d", port+1, port+maxPortAttempts-1, boundPort)
+	}
+}
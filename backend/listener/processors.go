@@ -0,0 +1,17 @@
+package listener
+
+// defaultMaxProcessors is the number of connections or messages a listener processes concurrently when unconfigured
+const defaultMaxProcessors = 100
+
+// maxProcessors returns the concurrency of a listener, the protocol specific setting
+// falls back to SLOGGO_MAX_PROCESSORS, then to the default, non-positive values are ignored
+func maxProcessors(protocolValue int64, sharedValue int64) int {
+	if protocolValue > 0 {
+		return int(protocolValue)
+	}
+	if sharedValue > 0 {
+		return int(sharedValue)
+	}
+
+	return defaultMaxProcessors
+}
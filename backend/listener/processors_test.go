@@ -0,0 +1,27 @@
+package listener
+
+import "testing"
+
+func TestMaxProcessors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		protocolValue int64
+		sharedValue   int64
+		expected      int
+	}{
+		{"defaults", 0, 0, defaultMaxProcessors},
+		{"shared value", 0, 250, 250},
+		{"protocol value overrides shared value", 500, 250, 500},
+		{"protocol value without shared value", 20, 0, 20},
+		{"negative values are ignored", -1, -5, defaultMaxProcessors},
+		{"negative protocol value falls back to shared value", -1, 50, 50},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := maxProcessors(tc.protocolValue, tc.sharedValue); got != tc.expected {
+				t.Errorf("maxProcessors(%d, %d) = %d, want %d", tc.protocolValue, tc.sharedValue, got, tc.expected)
+			}
+		})
+	}
+}
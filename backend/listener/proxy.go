@@ -0,0 +1,163 @@
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// proxyV1MaxLength is the longest PROXY protocol v1 header, CRLF included
+	proxyV1MaxLength = 107
+
+	// proxyV2MaxAddressLength bounds the address block of a PROXY protocol v2 header, TLVs included
+	proxyV2MaxAddressLength = 512
+)
+
+// proxyV2Signature starts every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// readProxyHeader reads the PROXY protocol v1 or v2 header starting a connection behind a load balancer
+// It returns the original client address, or nil when the header carries none (UNKNOWN or LOCAL)
+func readProxyHeader(reader *bufio.Reader) (net.Addr, error) {
+	prefix, err := reader.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("error reading PROXY header: %v", err)
+	}
+
+	if bytes.Equal(prefix, proxyV2Signature) {
+		return readProxyV2Header(reader)
+	}
+	if bytes.HasPrefix(prefix, []byte("PROXY ")) {
+		return readProxyV1Header(reader)
+	}
+
+	return nil, errors.New("missing PROXY header")
+}
+
+// readProxyV1Header reads a human readable header such as "PROXY TCP4 192.0.2.1 192.0.2.2 56324 6514\r\n"
+func readProxyV1Header(reader *bufio.Reader) (net.Addr, error) {
+	var header []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("error reading PROXY header: %v", err)
+		}
+
+		header = append(header, b)
+		if b == '\n' {
+			break
+		}
+		if len(header) >= proxyV1MaxLength {
+			return nil, errors.New("PROXY header too long")
+		}
+	}
+
+	line, ok := strings.CutSuffix(string(header), "\r\n")
+	if !ok {
+		return nil, errors.New("PROXY header must end with CRLF")
+	}
+
+	fields := strings.Split(line, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		// The connection wasn't relayed for a client, e.g. a health check of the load balancer
+		return nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("malformed PROXY header %q", line)
+	}
+
+	ip := net.ParseIP(fields[2])
+	if ip == nil || net.ParseIP(fields[3]) == nil {
+		return nil, fmt.Errorf("invalid PROXY header addresses %q", line)
+	}
+
+	switch fields[1] {
+	case "TCP4":
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("PROXY header address %s is not IPv4", fields[2])
+		}
+	case "TCP6":
+		if ip.To4() != nil {
+			return nil, fmt.Errorf("PROXY header address %s is not IPv6", fields[2])
+		}
+	default:
+		return nil, fmt.Errorf("unknown PROXY protocol %q", fields[1])
+	}
+
+	port, err := parseProxyPort(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseProxyPort(fields[5]); err != nil {
+		return nil, err
+	}
+
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// parseProxyPort parses a port of a v1 header, written in decimal without leading zeros
+func parseProxyPort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 0 || port > 65535 || (len(value) > 1 && value[0] == '0') {
+		return 0, fmt.Errorf("invalid PROXY header port %q", value)
+	}
+	return port, nil
+}
+
+// readProxyV2Header reads a binary header, made of the signature, the version and command, the address family,
+// the length of the address block and the address block
+func readProxyV2Header(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, len(proxyV2Signature)+4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("error reading PROXY header: %v", err)
+	}
+
+	versionCommand := header[12]
+	family := header[13]
+	length := binary.BigEndian.Uint16(header[14:16])
+
+	if versionCommand>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", versionCommand>>4)
+	}
+	if length > proxyV2MaxAddressLength {
+		return nil, fmt.Errorf("PROXY header address block too long (%d bytes)", length)
+	}
+
+	addresses := make([]byte, length)
+	if _, err := io.ReadFull(reader, addresses); err != nil {
+		return nil, fmt.Errorf("error reading PROXY header: %v", err)
+	}
+
+	switch versionCommand & 0x0f {
+	case 0x0:
+		// LOCAL, the connection was opened by the load balancer itself
+		return nil, nil
+	case 0x1:
+		// PROXY, the address block holds the client address
+	default:
+		return nil, fmt.Errorf("unknown PROXY command %d", versionCommand&0x0f)
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(addresses) < 12 {
+			return nil, errors.New("PROXY header IPv4 address block too short")
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:4]), Port: int(binary.BigEndian.Uint16(addresses[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(addresses) < 36 {
+			return nil, errors.New("PROXY header IPv6 address block too short")
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:16]), Port: int(binary.BigEndian.Uint16(addresses[32:34]))}, nil
+	default:
+		// Unspecified or non TCP families carry no usable client address
+		return nil, nil
+	}
+}
@@ -0,0 +1,73 @@
+package listener
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	v2Header := func(command byte, family byte, addresses []byte) string {
+		header := append([]byte{}, proxyV2Signature...)
+		header = append(header, 0x20|command, family)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+		return string(append(header, addresses...))
+	}
+
+	tcp4 := []byte{203, 0, 113, 9, 10, 0, 0, 1}
+	tcp4 = binary.BigEndian.AppendUint16(tcp4, 40000)
+	tcp4 = binary.BigEndian.AppendUint16(tcp4, 6514)
+
+	tests := []struct {
+		name        string
+		header      string
+		expected    string
+		shouldError bool
+	}{
+		{name: "v1 TCP4", header: "PROXY TCP4 198.51.100.7 10.0.0.1 56324 6514\r\n", expected: "198.51.100.7:56324"},
+		{name: "v1 TCP6", header: "PROXY TCP6 2001:db8::7 2001:db8::1 56324 6514\r\n", expected: "[2001:db8::7]:56324"},
+		{name: "v1 UNKNOWN", header: "PROXY UNKNOWN\r\n"},
+		{name: "v2 TCP4", header: v2Header(0x1, 0x11, tcp4), expected: "203.0.113.9:40000"},
+		{name: "v2 LOCAL", header: v2Header(0x0, 0x00, nil)},
+		{name: "v1 without CRLF", header: "PROXY TCP4 198.51.100.7 10.0.0.1 56324 6514\n", shouldError: true},
+		{name: "v1 family mismatch", header: "PROXY TCP4 2001:db8::7 10.0.0.1 56324 6514\r\n", shouldError: true},
+		{name: "v1 invalid port", header: "PROXY TCP4 198.51.100.7 10.0.0.1 99999 6514\r\n", shouldError: true},
+		{name: "v1 missing fields", header: "PROXY TCP4 198.51.100.7\r\n", shouldError: true},
+		{name: "v1 too long", header: "PROXY TCP4 " + strings.Repeat("1", 200) + "\r\n", shouldError: true},
+		{name: "v2 truncated addresses", header: v2Header(0x1, 0x11, tcp4[:6]), shouldError: true},
+		{name: "missing header", header: "<13>1 2023-10-01T12:34:56Z host app - - - Message\n", shouldError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tc.header + "<13>1 - - - - - - Next\n"))
+
+			addr, err := readProxyHeader(reader)
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tc.expected == "" {
+				if addr != nil {
+					t.Errorf("Expected no client address, got %v", addr)
+				}
+			} else if addr == nil || addr.String() != tc.expected {
+				t.Errorf("Expected client address %s, got %v", tc.expected, addr)
+			}
+
+			// The header is consumed, the syslog frames follow
+			rest, _ := io.ReadAll(reader)
+			if string(rest) != "<13>1 - - - - - - Next\n" {
+				t.Errorf("Expected the header to be consumed, %q is left", rest)
+			}
+		})
+	}
+}
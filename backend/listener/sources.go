@@ -0,0 +1,131 @@
+package listener
+
+import (
+	"cmp"
+	"container/list"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxSources bounds the number of source hosts tracked, the least recently seen is evicted beyond it
+	maxSources = 10000
+
+	// sourceIdleTimeout is how long a source is kept without receiving anything
+	sourceIdleTimeout = 24 * time.Hour
+)
+
+// SourceStats reports the volume received from a source host
+type SourceStats struct {
+	Address  string    `json:"address"`
+	Bytes    int64     `json:"bytes"`
+	Messages int64     `json:"messages"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// sourceTracker counts the bytes and messages received by the listeners per source IP
+// The sources are kept from the most to the least recently seen, so evicting one doesn't scan them all
+type sourceTracker struct {
+	mu      sync.Mutex
+	sources map[string]*list.Element // Elements hold *SourceStats
+	order   *list.List
+}
+
+// newSourceTracker creates an empty source tracker
+func newSourceTracker() *sourceTracker {
+	return &sourceTracker{sources: make(map[string]*list.Element), order: list.New()}
+}
+
+// receivedSources holds the volume received by the TCP and UDP listeners
+var receivedSources = newSourceTracker()
+
+// record adds the bytes and messages received from a source
+func (t *sourceTracker) record(address string, bytes int, messages int) {
+	if address == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	element, ok := t.sources[address]
+	if ok {
+		t.order.MoveToFront(element)
+	} else {
+		t.evict(now)
+
+		element = t.order.PushFront(&SourceStats{Address: address})
+		t.sources[address] = element
+	}
+
+	source := element.Value.(*SourceStats)
+	source.Bytes += int64(bytes)
+	source.Messages += int64(messages)
+	source.LastSeen = now
+}
+
+// evict removes the idle sources and, beyond the limit, the least recently seen one, both at the back of the order
+func (t *sourceTracker) evict(now time.Time) {
+	for oldest := t.order.Back(); oldest != nil; oldest = t.order.Back() {
+		source := oldest.Value.(*SourceStats)
+		if now.Sub(source.LastSeen) <= sourceIdleTimeout && t.order.Len() < maxSources {
+			return
+		}
+
+		t.order.Remove(oldest)
+		delete(t.sources, source.Address)
+	}
+}
+
+// list returns the sources seen within the idle timeout, by decreasing volume
+func (t *sourceTracker) list() []SourceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	t.evict(now)
+
+	sources := make([]SourceStats, 0, t.order.Len())
+	for element := t.order.Front(); element != nil; element = element.Next() {
+		sources = append(sources, *element.Value.(*SourceStats))
+	}
+
+	slices.SortFunc(sources, func(a, b SourceStats) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Address, b.Address)
+	})
+
+	return sources
+}
+
+// Sources returns the volume received by the listeners per source IP, the largest senders first
+func Sources() []SourceStats {
+	return receivedSources.list()
+}
+
+// sourceAddress returns the IP of a remote address, used to key the sources
+func sourceAddress(addr net.Addr) string {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP.String()
+	case *net.TCPAddr:
+		return addr.IP.String()
+	}
+
+	if addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
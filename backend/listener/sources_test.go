@@ -0,0 +1,76 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSourceTracker(t *testing.T) {
+	tracker := newSourceTracker()
+
+	tracker.record("10.0.0.1", 100, 1)
+	tracker.record("10.0.0.2", 500, 0)
+	tracker.record("10.0.0.2", 0, 3)
+	tracker.record("10.0.0.1", 50, 1)
+
+	sources := tracker.list()
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %+v", sources)
+	}
+	if sources[0].Address != "10.0.0.2" || sources[0].Bytes != 500 || sources[0].Messages != 3 {
+		t.Errorf("Expected the largest sender first, got %+v", sources[0])
+	}
+	if sources[1].Address != "10.0.0.1" || sources[1].Bytes != 150 || sources[1].Messages != 2 {
+		t.Errorf("Expected the counters to add up, got %+v", sources[1])
+	}
+
+	// Idle sources are evicted, the least recently seen being 10.0.0.2
+	tracker.sources["10.0.0.2"].Value.(*SourceStats).LastSeen = time.Now().Add(-2 * sourceIdleTimeout)
+	if sources := tracker.list(); len(sources) != 1 || sources[0].Address != "10.0.0.1" {
+		t.Errorf("Expected the idle source to be evicted, got %+v", sources)
+	}
+}
+
+func TestSourceTrackerBounded(t *testing.T) {
+	tracker := newSourceTracker()
+
+	for i := range maxSources {
+		tracker.record(fmt.Sprintf("source-%d", i), 1, 1)
+	}
+
+	// Seeing source-0 again leaves source-1 as the least recently seen
+	tracker.record("source-0", 1, 1)
+	tracker.record("new-source", 1, 1)
+
+	if len(tracker.sources) != maxSources || tracker.order.Len() != maxSources {
+		t.Errorf("Expected at most %d sources, got %d", maxSources, len(tracker.sources))
+	}
+	if _, ok := tracker.sources["source-1"]; ok {
+		t.Error("Expected the least recently seen source to be evicted")
+	}
+	if _, ok := tracker.sources["source-0"]; !ok {
+		t.Error("Expected the source seen again to be kept")
+	}
+	if _, ok := tracker.sources["new-source"]; !ok {
+		t.Error("Expected the new source to be tracked")
+	}
+}
+
+func TestSourceAddress(t *testing.T) {
+	tests := []struct {
+		addr     net.Addr
+		expected string
+	}{
+		{&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 5514}, "192.0.2.1"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 6514}, "2001:db8::1"},
+		{nil, ""},
+	}
+
+	for _, tc := range tests {
+		if address := sourceAddress(tc.addr); address != tc.expected {
+			t.Errorf("sourceAddress(%v) = %q, want %q", tc.addr, address, tc.expected)
+		}
+	}
+}
@@ -2,6 +2,10 @@ package listener
 
 import (
 	"bufio"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"sloggo/db"
@@ -15,11 +19,35 @@ import (
 	"github.com/leodido/go-syslog/v4/rfc5424"
 )
 
+// recycleDrainTimeout is how long a recycled connection keeps reading the frames already sent before it's closed
+const recycleDrainTimeout = 250 * time.Millisecond
+
 var (
 	rfc5424Parser syslog.Machine
 	parserOnce    sync.Once
+
+	// recycledConnections counts the TCP connections closed after reaching their message count or lifetime
+	recycledConnections = expvar.NewInt("tcpConnectionsRecycled")
+
+	// tcpListener is the bound TCP listener, closed on shutdown
+	tcpListener   net.Listener
+	tcpListenerMu sync.Mutex
+
+	// tcpSemaphore limits the concurrent TCP and journal connections
+	tcpSemaphore     chan struct{}
+	tcpSemaphoreOnce sync.Once
 )
 
+// getTCPSemaphore returns the semaphore shared by the TCP and journal listeners
+func getTCPSemaphore() chan struct{} {
+	tcpSemaphoreOnce.Do(func() {
+		maxConcurrentProcessors := maxProcessors(utils.TcpMaxProcessors, utils.MaxProcessors)
+		log.Printf("TCP and journal listeners process up to %d connections concurrently", maxConcurrentProcessors)
+		tcpSemaphore = make(chan struct{}, maxConcurrentProcessors)
+	})
+	return tcpSemaphore
+}
+
 func getRFC5424Parser() syslog.Machine {
 	parserOnce.Do(func() {
 		rfc5424Parser = rfc5424.NewParser(rfc5424.WithBestEffort())
@@ -30,22 +58,27 @@ func getRFC5424Parser() syslog.Machine {
 func StartTCPListener() {
 	port := utils.TcpPort
 
-	_, err := net.LookupPort("tcp", port)
+	intPort, err := net.LookupPort("tcp", port)
 	if err != nil {
 		log.Fatalf("Invalid TCP port %s: %v", port, err)
 	}
 
-	listener, err := net.Listen("tcp", ":"+port)
+	listener, boundPort, err := listenWithPortAuto("TCP", intPort, func(port int) (net.Listener, error) {
+		return net.Listen("tcp", fmt.Sprintf(":%d", port))
+	})
 	if err != nil {
 		log.Fatalf("Failed to start TCP listener on port %s: %v", port, err)
 	}
 	defer listener.Close()
 
-	log.Printf("TCP listener is running on port :%s", port)
+	tcpListenerMu.Lock()
+	tcpListener = listener
+	tcpListenerMu.Unlock()
+
+	log.Printf("TCP listener is running on port :%d", boundPort)
 
 	// Use a semaphore to limit concurrent processors
-	maxConcurrentProcessors := 100
-	semaphore := make(chan struct{}, maxConcurrentProcessors)
+	semaphore := getTCPSemaphore()
 
 	// Create a WaitGroup to track active connections
 	var wg sync.WaitGroup
@@ -53,10 +86,23 @@ func StartTCPListener() {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				// The listener is shut down
+				return
+			}
 			log.Printf("Error accepting TCP connection: %v", err)
 			continue
 		}
 
+		// Behind a load balancer the client address is only known once the PROXY protocol header is read
+		if !utils.ProxyProtocol {
+			if source := sourceAddress(conn.RemoteAddr()); !isAllowedSource(source) {
+				recordDrop(source, dropAllowlist)
+				conn.Close()
+				continue
+			}
+		}
+
 		select {
 		case semaphore <- struct{}{}:
 			// Slot acquired, process the connection
@@ -71,28 +117,88 @@ func StartTCPListener() {
 				handleTCPConnection(c)
 			}(conn)
 		default:
-			log.Printf("Warning: TCP connection processing at capacity, rejecting connection")
+			// Reported per source every minute rather than for each connection
+			recordDrop(sourceAddress(conn.RemoteAddr()), dropCapacity)
 			conn.Close()
 		}
 	}
 }
 
+// ShutdownTCPListener stops accepting TCP connections and gives the active ones up to the grace period
+// to read and store the logs already on the wire, the connections still open after it are closed
+// Journal connections are served over TCP too and drained the same way
+func ShutdownTCPListener(grace time.Duration) {
+	tcpListenerMu.Lock()
+	if tcpListener != nil {
+		tcpListener.Close()
+	}
+	tcpListenerMu.Unlock()
+
+	closeJournalListener()
+
+	if !tcpConnections.drain(grace) {
+		log.Printf("Closed the TCP connections still open after the %v shutdown grace period", grace)
+	}
+}
+
 // handleTCPConnection handles a TCP connection
 func handleTCPConnection(conn net.Conn) {
 	handleTCPConnectionWithTimeout(conn, 30*time.Second)
 }
 
 func handleTCPConnectionWithTimeout(conn net.Conn, readTimeout time.Duration) {
+	tcpConnections.add(conn)
+	defer tcpConnections.done(conn)
 	defer conn.Close()
 
-	scanner := bufio.NewScanner(conn)
+	conn.SetReadDeadline(tcpConnections.readDeadline(readTimeout))
+
+	// Behind a load balancer the client address comes from the PROXY protocol header
+	var input io.Reader = conn
+	remoteAddr := conn.RemoteAddr()
+	if utils.ProxyProtocol {
+		reader := bufio.NewReader(conn)
+
+		clientAddr, err := readProxyHeader(reader)
+		if err != nil {
+			log.Printf("Rejected TCP connection from %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		if clientAddr != nil {
+			remoteAddr = clientAddr
+		}
+
+		input = reader
+	}
+
+	scanner := bufio.NewScanner(input)
 
 	// Configure scanner with a larger buffer for bigger messages
-	const maxScanSize = 1024 * 1024 // 1MB max message size
 	buffer := make([]byte, 0, 64*1024)
-	scanner.Buffer(buffer, maxScanSize)
+	scanner.Buffer(buffer, maxTCPMessageSize)
+
+	// Split octet-counted or delimited frames
+	scanner.Split(splitSyslogFrames(tcpDelimiter))
 
-	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	// Track the connection usage to recycle long-lived connections
+	openedAt := time.Now()
+	messages := 0
+	recycling := false
+
+	// Volume is counted per source IP, the sources not allowed aren't read at all
+	source := sourceAddress(remoteAddr)
+	if !isAllowedSource(source) {
+		recordDrop(source, dropAllowlist)
+		return
+	}
+
+	// Multi-line messages are held until the next message starts, store the last one when the connection ends
+	var joiner continuationJoiner
+	defer func() {
+		if message, ok := joiner.flush(); ok && storeTCPMessage(message, source) {
+			receivedSources.record(source, 0, 1)
+		}
+	}()
 
 	for {
 		// Scan for the next message
@@ -108,52 +214,79 @@ func handleTCPConnectionWithTimeout(conn net.Conn, readTimeout time.Duration) {
 			return
 		}
 
-		// Reset deadline after successful read
-		conn.SetReadDeadline(time.Now().Add(readTimeout))
-
-		message := strings.TrimSpace(scanner.Text())
-		if message == "" {
-			// Skip empty messages
-			continue
+		// Reset deadline after successful read, a recycled connection keeps its drain deadline
+		if !recycling {
+			conn.SetReadDeadline(tcpConnections.readDeadline(readTimeout))
 		}
 
-		parsed := false
-		var lastErr error
-
-		logFormat := utils.GetLogFormat()
-
-		// Try RFC5424 if enabled
-		if logFormat == "rfc5424" || logFormat == "auto" {
-			parser := getRFC5424Parser()
-			if syslogMsg, err := parser.Parse([]byte(message)); err == nil {
-				if rfc5424Msg, ok := syslogMsg.(*rfc5424.SyslogMessage); ok {
-					logEntry := formats.SyslogMessageToLogEntry(rfc5424Msg)
-					if logEntry != nil {
-						if err := db.StoreLog(*logEntry); err != nil {
-							log.Printf("Error storing log: %v", err)
-						}
-						parsed = true
-					}
-				}
-			} else {
-				lastErr = err
+		frame := scanner.Text()
+		receivedSources.record(source, len(frame), 0)
+
+		if utils.JoinContinuation {
+			var complete bool
+			if frame, complete = joiner.add(frame); !complete {
+				// Wait for the continuation lines of the message
+				continue
 			}
 		}
 
-		// Try RFC3164 if enabled and not yet parsed
-		if !parsed && (logFormat == "rfc3164" || logFormat == "auto") {
-			if logEntry, err := formats.ParseRFC3164ToLogEntry(message); err == nil {
-				if err := db.StoreLog(*logEntry); err != nil {
-					log.Printf("Error storing log: %v", err)
-				}
-				parsed = true
-			} else {
-				lastErr = err
-			}
+		if !storeTCPMessage(frame, source) {
+			continue
 		}
+		receivedSources.record(source, 0, 1)
 
-		if !parsed {
-			log.Printf("Failed to parse message with format %s: %v: %s", logFormat, lastErr, message)
+		messages++
+		if !recycling && shouldRecycleConnection(messages, openedAt) {
+			// The scanner may already hold the next frames and the client may still be writing,
+			// keep storing them for a short while so closing doesn't drop them, the client then reconnects
+			recycling = true
+			recycledConnections.Add(1)
+			conn.SetReadDeadline(time.Now().Add(recycleDrainTimeout))
+			if utils.Debug {
+				log.Printf("Recycling TCP connection from %s after %d messages", remoteAddr, messages)
+			}
 		}
 	}
 }
+
+// storeTCPMessage parses and stores a message received over TCP from the source IP, and reports whether it was stored
+func storeTCPMessage(frame string, source string) bool {
+	message := strings.TrimSpace(frame)
+	if message == "" {
+		// Skip empty messages
+		return false
+	}
+
+	logFormat := utils.GetTCPLogFormat()
+
+	logEntry, err := formats.ParseSyslogMessage(getRFC5424Parser(), message, logFormat)
+	if err != nil {
+		log.Printf("Failed to parse message with format %s: %v: %s", logFormat, err, message)
+		return false
+	}
+
+	if !isAllowedHostname(logEntry.Hostname) {
+		droppedMessages.Add(1)
+		recordDrop(source, dropAllowlist)
+		return false
+	}
+
+	if err := db.StoreLog(*logEntry); err != nil {
+		log.Printf("Error storing log: %v", err)
+	}
+
+	return true
+}
+
+// shouldRecycleConnection reports whether a connection reached its configured message count or lifetime
+func shouldRecycleConnection(messages int, openedAt time.Time) bool {
+	if utils.TcpMaxConnectionMessages > 0 && int64(messages) >= utils.TcpMaxConnectionMessages {
+		return true
+	}
+
+	if utils.TcpMaxConnectionSeconds > 0 && time.Since(openedAt) >= time.Duration(utils.TcpMaxConnectionSeconds)*time.Second {
+		return true
+	}
+
+	return false
+}
@@ -3,6 +3,7 @@ package listener
 import (
 	"fmt"
 	"net"
+	"slices"
 	"sloggo/db"
 	"sloggo/utils"
 	"strings"
@@ -98,3 +99,387 @@ func TestTCPConnectionReadTimeoutClosesConnection(t *testing.T) {
 		t.Fatal("TCP connection handler did not return after read timeout")
 	}
 }
+
+func TestTCPConnectionRecycledAfterMaxMessages(t *testing.T) {
+	originalMaxMessages := utils.TcpMaxConnectionMessages
+	defer func() {
+		utils.TcpMaxConnectionMessages = originalMaxMessages
+	}()
+	utils.TcpMaxConnectionMessages = 2
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	recycledBefore := recycledConnections.Value()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	// The third message is still read and stored before the connection is closed
+	go func() {
+		for i := range 3 {
+			message := fmt.Sprintf("<13>1 2023-10-01T12:34:56Z recycle-host recycle-app - - - Recycle message %d\n", i)
+			if _, err := clientConn.Write([]byte(message)); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("TCP connection handler did not return after reaching the message limit")
+	}
+
+	if recycled := recycledConnections.Value() - recycledBefore; recycled != 1 {
+		t.Errorf("Expected 1 recycled connection, got %d", recycled)
+	}
+}
+
+func TestShouldRecycleConnection(t *testing.T) {
+	originalMaxMessages := utils.TcpMaxConnectionMessages
+	originalMaxSeconds := utils.TcpMaxConnectionSeconds
+	defer func() {
+		utils.TcpMaxConnectionMessages = originalMaxMessages
+		utils.TcpMaxConnectionSeconds = originalMaxSeconds
+	}()
+
+	// Both limits are optional
+	utils.TcpMaxConnectionMessages = 0
+	utils.TcpMaxConnectionSeconds = 0
+	if shouldRecycleConnection(1000000, time.Now().Add(-24*time.Hour)) {
+		t.Error("Expected connections never to be recycled without limits")
+	}
+
+	utils.TcpMaxConnectionSeconds = 60
+	if shouldRecycleConnection(1, time.Now()) {
+		t.Error("Expected a new connection not to be recycled")
+	}
+	if !shouldRecycleConnection(1, time.Now().Add(-time.Minute)) {
+		t.Error("Expected a connection to be recycled after its lifetime")
+	}
+}
+
+func TestTCPConnectionDrainedOnShutdown(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, 30*time.Second)
+		close(done)
+	}()
+
+	// A slow client still sending while the shutdown starts
+	go func() {
+		for i := range 3 {
+			message := fmt.Sprintf("<13>1 2023-10-01T12:34:56Z drain-host drain-app - - - Drain message %d\n", i)
+			if _, err := clientConn.Write([]byte(message)); err != nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	if !tcpConnections.drain(2 * time.Second) {
+		t.Error("Expected the connection to be drained before the grace period")
+	}
+
+	// The idle connection is released well before the grace period
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the drain to finish once the connection was idle, took %v", elapsed)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after draining")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var count int
+	err := db.GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = ?", "drain-host").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the 3 messages sent during the shutdown to be stored, got %d", count)
+	}
+}
+
+func TestTCPConnectionClosedAfterGracePeriod(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, 30*time.Second)
+		close(done)
+	}()
+
+	// A client that never stops sending keeps the connection busy past the grace period
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := clientConn.Write([]byte("<13>1 2023-10-01T12:34:56Z busy-host busy-app - - - Busy message\n")); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if tcpConnections.drain(300 * time.Millisecond) {
+		t.Error("Expected the busy connection to be force-closed")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after being force-closed")
+	}
+}
+
+func TestTCPConnectionJoinsContinuationLines(t *testing.T) {
+	originalJoin := utils.JoinContinuation
+	defer func() {
+		utils.JoinContinuation = originalJoin
+	}()
+	utils.JoinContinuation = true
+
+	serverConn, clientConn := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	stackTrace := "<11>1 2023-10-01T12:34:56Z trace-host trace-app - - - java.lang.IllegalStateException: boom\n" +
+		"\tat com.example.Service.run(Service.java:42)\n" +
+		"\tat java.base/java.lang.Thread.run(Thread.java:1583)\n" +
+		"Caused by: java.io.IOException: broken pipe\n" +
+		"\t... 2 more\n" +
+		"<14>1 2023-10-01T12:34:57Z trace-host trace-app - - - Recovered\n"
+	if _, err := clientConn.Write([]byte(stackTrace)); err != nil {
+		t.Fatalf("Failed to send log message: %v", err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after the connection was closed")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	rows, err := db.GetDBInstance().Query("SELECT msg FROM logs WHERE hostname = ? ORDER BY timestamp", "trace-host")
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			t.Fatalf("Failed to scan row: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	expected := []string{
+		"java.lang.IllegalStateException: boom\n" +
+			"\tat com.example.Service.run(Service.java:42)\n" +
+			"\tat java.base/java.lang.Thread.run(Thread.java:1583)\n" +
+			"Caused by: java.io.IOException: broken pipe\n" +
+			"\t... 2 more",
+		"Recovered",
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d logs, got %d: %q", len(expected), len(messages), messages)
+	}
+	for i, msg := range messages {
+		if msg != expected[i] {
+			t.Errorf("Log %d: got %q, want %q", i, msg, expected[i])
+		}
+	}
+}
+
+func TestTCPConnectionWithProxyHeader(t *testing.T) {
+	originalProxyProtocol := utils.ProxyProtocol
+	defer func() {
+		utils.ProxyProtocol = originalProxyProtocol
+	}()
+	utils.ProxyProtocol = true
+
+	send := func(data string) {
+		serverConn, clientConn := net.Pipe()
+
+		done := make(chan struct{})
+		go func() {
+			handleTCPConnectionWithTimeout(serverConn, time.Second)
+			close(done)
+		}()
+
+		// The handler may close the connection before everything is written
+		clientConn.Write([]byte(data))
+		clientConn.Close()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("TCP connection handler did not return after the connection was closed")
+		}
+	}
+
+	send("PROXY TCP4 198.51.100.7 10.0.0.1 56324 6514\r\n<13>1 2023-10-01T12:34:56Z proxy-host proxy-app - - - Proxied message\n")
+	send("PROXY TCP4 not-an-ip 10.0.0.1 56324 6514\r\n<13>1 2023-10-01T12:34:56Z proxy-host proxy-app - - - Malformed header\n")
+	send("<13>1 2023-10-01T12:34:56Z proxy-host proxy-app - - - Missing header\n")
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	rows, err := db.GetDBInstance().Query("SELECT msg FROM logs WHERE hostname = ?", "proxy-host")
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			t.Fatalf("Failed to scan row: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) != 1 || messages[0] != "Proxied message" {
+		t.Errorf("Expected only the message after a valid header to be stored, got %v", messages)
+	}
+
+	// The volume is attributed to the client rather than the load balancer
+	found := false
+	for _, source := range Sources() {
+		if source.Address == "198.51.100.7" {
+			found = source.Messages == 1
+		}
+	}
+	if !found {
+		t.Errorf("Expected 1 message from the proxied client address, got %+v", Sources())
+	}
+}
+
+func TestTCPConnectionRecycleKeepsPipelinedFrames(t *testing.T) {
+	originalMaxMessages := utils.TcpMaxConnectionMessages
+	defer func() {
+		utils.TcpMaxConnectionMessages = originalMaxMessages
+	}()
+	utils.TcpMaxConnectionMessages = 3
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	// Every frame is sent in a single write, the scanner buffers the ones beyond the limit
+	const frames = 5
+	var messages strings.Builder
+	for i := range frames {
+		fmt.Fprintf(&messages, "<13>1 2023-10-01T12:34:56Z pipeline-host pipeline-app - - - Pipelined message %d\n", i)
+	}
+	go clientConn.Write([]byte(messages.String()))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after reaching the message limit")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var count int
+	if err := db.GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = ?", "pipeline-host").Scan(&count); err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != frames {
+		t.Errorf("Expected the %d pipelined frames to be stored, got %d", frames, count)
+	}
+}
+
+func TestTCPConnectionStoresMessagesWithoutPriority(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		handleTCPConnectionWithTimeout(serverConn, time.Second)
+		close(done)
+	}()
+
+	// Neither line is octet-counted, the RFC5424 one starting with its version digit
+	messages := "1 2023-10-01T12:34:56Z no-pri-host rfc5424-app - - - Without PRI\n" +
+		"Oct 11 22:14:15 no-pri-host rfc3164-app: Without PRI\n"
+	if _, err := clientConn.Write([]byte(messages)); err != nil {
+		t.Fatalf("Failed to send log messages: %v", err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TCP connection handler did not return after the connection was closed")
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	rows, err := db.GetDBInstance().Query("SELECT app_name, facility, severity, msg FROM logs WHERE hostname = ? ORDER BY app_name", "no-pri-host")
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	stored := []string{}
+	for rows.Next() {
+		var appName, message string
+		var facility, severity int
+		if err := rows.Scan(&appName, &facility, &severity, &message); err != nil {
+			t.Fatalf("Failed to scan row: %v", err)
+		}
+
+		// The configured default priority, user.notice as SLOGGO_DEFAULT_FACILITY and SLOGGO_DEFAULT_SEVERITY are unset
+		if facility != 1 || severity != 5 || message != "Without PRI" {
+			t.Errorf("%s: expected user.notice %q, got facility %d, severity %d and %q", appName, "Without PRI", facility, severity, message)
+		}
+		stored = append(stored, appName)
+	}
+
+	if !slices.Equal(stored, []string{"rfc3164-app", "rfc5424-app"}) {
+		t.Errorf("Expected both messages to be stored, got %v", stored)
+	}
+}
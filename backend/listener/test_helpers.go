@@ -59,7 +59,7 @@ func verifyLogEntry(t *testing.T, tc testCase) {
 	time.Sleep(200 * time.Millisecond)
 
 	// First check what's in the database for debugging
-	rows, err := db.GetDBInstance().Query("SELECT hostname, app_name, procid, msgid, msg, structured_data, severity, facility FROM logs")
+	rows, err := db.GetDBInstance().Query("SELECT hostname, app_name, procid, msgid, msg, COALESCE(structured_data, ''), severity, facility FROM logs")
 	if err != nil {
 		t.Fatalf("Failed to query database for debug: %v", err)
 	}
@@ -132,7 +132,7 @@ func getTestCases() []testCase {
 				appName:        "example-app",
 				procid:         "1234",
 				msgid:          "5678",
-				structuredData: "-",
+				structuredData: "",
 				msg:            "Test log message",
 				shouldError:    false,
 			},
@@ -162,7 +162,7 @@ func getTestCases() []testCase {
 				appName:        "kernel",
 				procid:         "0",
 				msgid:          "-",
-				structuredData: "-",
+				structuredData: "",
 				msg:            "Kernel panic - not syncing",
 				shouldError:    false,
 			},
@@ -177,7 +177,7 @@ func getTestCases() []testCase {
 				appName:        "su",
 				procid:         "-",
 				msgid:          "-",
-				structuredData: "-",
+				structuredData: "",
 				msg:            "'su root' failed for lonvick on /dev/pts/8",
 				shouldError:    false,
 			},
@@ -192,7 +192,7 @@ func getTestCases() []testCase {
 				appName:        "esphome",
 				procid:         "1234",
 				msgid:          "-",
-				structuredData: "-",
+				structuredData: "",
 				msg:            "Sensor reading: 42",
 				shouldError:    false,
 			},
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"context"
 	"log"
 	"net"
 	"sloggo/db"
@@ -17,6 +18,9 @@ import (
 var (
 	udpRFC5424Parser syslog.Machine
 	udpParserOnce    sync.Once
+
+	// udpRunning tracks the UDP listener until its messages are processed after a shutdown
+	udpRunning sync.WaitGroup
 )
 
 func getUDPRFC5424Parser() syslog.Machine {
@@ -26,7 +30,12 @@ func getUDPRFC5424Parser() syslog.Machine {
 	return udpRFC5424Parser
 }
 
-func StartUDPListener() {
+// StartUDPListener receives syslog messages over UDP until the context is done
+// It then waits for the messages being processed and stores the batch, unless SLOGGO_BATCH_PERSIST is set
+func StartUDPListener(ctx context.Context) {
+	udpRunning.Add(1)
+	defer udpRunning.Done()
+
 	port := utils.UdpPort
 
 	intPort, err := net.LookupPort("udp", port)
@@ -34,21 +43,23 @@ func StartUDPListener() {
 		log.Fatalf("Invalid UDP port %s: %v", port, err)
 	}
 
-	addr := net.UDPAddr{
-		Port: intPort,
-		IP:   net.ParseIP("0.0.0.0"),
-	}
-
-	listener, err := net.ListenUDP("udp", &addr)
+	listener, boundPort, err := listenWithPortAuto("UDP", intPort, func(port int) (*net.UDPConn, error) {
+		addr := net.UDPAddr{
+			Port: port,
+			IP:   net.ParseIP("0.0.0.0"),
+		}
+		return net.ListenUDP("udp", &addr)
+	})
 	if err != nil {
 		log.Fatalf("Failed to start UDP listener on port %s: %v", port, err)
 	}
 	defer listener.Close()
 
-	log.Printf("UDP listener is running on port :%s", port)
+	log.Printf("UDP listener is running on port :%d", boundPort)
 
 	// Use a semaphore to limit concurrent processors
-	maxConcurrentProcessors := 100
+	maxConcurrentProcessors := maxProcessors(utils.UdpMaxProcessors, utils.MaxProcessors)
+	log.Printf("UDP listener processes up to %d messages concurrently", maxConcurrentProcessors)
 	semaphore := make(chan struct{}, maxConcurrentProcessors)
 
 	// Use a WaitGroup to track active processors
@@ -58,12 +69,26 @@ func StartUDPListener() {
 	const bufferSize = 64 * 1024 // 64KB buffer
 	buffer := make([]byte, bufferSize)
 
+	// Expire the read deadline on shutdown rather than waiting for it
+	stop := context.AfterFunc(ctx, func() {
+		listener.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
 	for {
 		listener.SetReadDeadline(time.Now().Add(30 * time.Second))
+		if ctx.Err() != nil {
+			// The shutdown started before the deadline was set
+			break
+		}
 
-		n, _, err := listener.ReadFromUDP(buffer)
+		n, remoteAddr, err := listener.ReadFromUDP(buffer)
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				if ctx.Err() != nil {
+					// The listener is shut down
+					break
+				}
 				// Just a timeout, continue
 				continue
 			}
@@ -71,6 +96,15 @@ func StartUDPListener() {
 			continue
 		}
 
+		// Volume is counted per source IP, including the datagrams rejected at capacity but not the sources not allowed
+		source := sourceAddress(remoteAddr)
+		if !isAllowedSource(source) {
+			droppedMessages.Add(1)
+			recordDrop(source, dropAllowlist)
+			continue
+		}
+		receivedSources.record(source, n, 0)
+
 		// Make a copy of the received data to process
 		messageCopy := make([]byte, n)
 		copy(messageCopy, buffer[:n])
@@ -86,16 +120,43 @@ func StartUDPListener() {
 					<-semaphore
 					wg.Done()
 				}()
-				processUDPMessage(data)
+				receivedSources.record(source, 0, processUDPMessage(data, source))
 			}(messageCopy)
 		default:
-			log.Printf("Warning: UDP connection processing at capacity, rejecting connection")
+			// Reported per source every minute rather than for each message
+			recordDrop(source, dropCapacity)
+		}
+	}
+
+	// Let the messages being processed reach the batch before storing it
+	wg.Wait()
+
+	if !utils.BatchPersist {
+		if err := db.ProcessBatchStoreLogs(); err != nil {
+			log.Printf("Failed to store the UDP logs on shutdown: %v", err)
 		}
 	}
 }
 
-// processUDPMessage handles processing of a single UDP message
-func processUDPMessage(message []byte) {
+// ShutdownUDPListener waits up to the grace period for the UDP listener to process its messages,
+// once the context it was started with is done
+func ShutdownUDPListener(grace time.Duration) bool {
+	stopped := make(chan struct{})
+	go func() {
+		udpRunning.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(grace):
+		return false
+	}
+}
+
+// processUDPMessage handles processing of a single UDP message from the source IP, and returns the number of messages it stored
+func processUDPMessage(message []byte, source string) int {
 	// Process the input using go-syslog parser
 	input := string(message)
 
@@ -103,49 +164,33 @@ func processUDPMessage(message []byte) {
 	// Split by newlines in case multiple messages were sent in one datagram
 	parts := strings.SplitSeq(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
 
+	messages := 0
 	for part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
 			continue // Skip empty messages
 		}
 
-		parsed := false
-		var lastErr error
-
 		// Get current log format in a thread-safe manner
-		logFormat := utils.GetLogFormat()
-
-		// Try RFC5424 if enabled
-		if logFormat == "rfc5424" || logFormat == "auto" {
-			parser := getUDPRFC5424Parser()
-			if syslogMsg, err := parser.Parse([]byte(part)); err == nil {
-				if rfc5424Msg, ok := syslogMsg.(*rfc5424.SyslogMessage); ok {
-					if logEntry := formats.SyslogMessageToLogEntry(rfc5424Msg); logEntry != nil {
-						if err := db.StoreLog(*logEntry); err != nil {
-							log.Printf("Error storing UDP log: %v", err)
-						}
-						parsed = true
-					}
-				}
-			} else {
-				lastErr = err
-			}
+		logFormat := utils.GetUDPLogFormat()
+
+		logEntry, err := formats.ParseSyslogMessage(getUDPRFC5424Parser(), part, logFormat)
+		if err != nil {
+			log.Printf("Failed to parse UDP message with format %s: %v: %s", logFormat, err, input)
+			continue
 		}
 
-		// Try RFC3164 if enabled and not yet parsed
-		if !parsed && (logFormat == "rfc3164" || logFormat == "auto") {
-			if logEntry, err := formats.ParseRFC3164ToLogEntry(part); err == nil {
-				if err := db.StoreLog(*logEntry); err != nil {
-					log.Printf("Error storing UDP log: %v", err)
-				}
-				parsed = true
-			} else {
-				lastErr = err
-			}
+		if !isAllowedHostname(logEntry.Hostname) {
+			droppedMessages.Add(1)
+			recordDrop(source, dropAllowlist)
+			continue
 		}
+		messages++
 
-		if !parsed {
-			log.Printf("Failed to parse UDP message with format %s: %v: %s", logFormat, lastErr, input)
+		if err := db.StoreLog(*logEntry); err != nil {
+			log.Printf("Error storing UDP log: %v", err)
 		}
 	}
+
+	return messages
 }
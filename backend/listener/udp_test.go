@@ -1,8 +1,10 @@
 package listener
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"sloggo/db"
 	"sloggo/utils"
 	"strings"
 	"testing"
@@ -40,7 +42,7 @@ func TestUDPListener(t *testing.T) {
 	checkSchema(t)
 
 	port := 5514
-	go StartUDPListener()
+	go StartUDPListener(context.Background())
 
 	// Allow the listener to start
 	time.Sleep(1 * time.Second)
@@ -63,3 +65,45 @@ func TestUDPListener(t *testing.T) {
 		}
 	}
 }
+
+func TestUDPListenerShutdownStoresLogs(t *testing.T) {
+	originalPort := utils.UdpPort
+	defer func() {
+		utils.UdpPort = originalPort
+	}()
+	utils.UdpPort = "5515"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		StartUDPListener(ctx)
+		close(stopped)
+	}()
+
+	// Allow the listener to start
+	time.Sleep(500 * time.Millisecond)
+
+	for i := range 3 {
+		sendUDPMessage(t, "localhost:5515", fmt.Sprintf("<13>1 2023-10-01T12:34:56Z udp-shutdown-host udp-app - - - Buffered message %d", i))
+	}
+
+	// The listener returns well before its read deadline, with the batch stored
+	// TestUDPListener keeps its listener running, so wait for this one rather than with ShutdownUDPListener
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("UDP listener did not stop after the shutdown")
+	}
+
+	var count int
+	err := db.GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE hostname = ?", "udp-shutdown-host").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the 3 buffered messages to be stored on shutdown, got %d", count)
+	}
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"slices"
+	"sloggo/db"
 	"sloggo/server"
 	"sloggo/utils"
+	"syscall"
+	"time"
 
 	"sloggo/listener"
 )
@@ -12,16 +18,63 @@ import (
 func main() {
 	// Startup configuration log
 	log.Printf("Sloggo version: %s", utils.Version)
-	log.Printf("Config: listeners=%v udp_port=%s tcp_port=%s api_port=%s", utils.Listeners, utils.UdpPort, utils.TcpPort, utils.ApiPort)
-	log.Printf("Config: log_format=%s debug=%t retention_minutes=%d", utils.GetLogFormat(), utils.Debug, utils.LogRetentionMinutes)
+	log.Printf("Config: listeners=%v udp_port=%s tcp_port=%s journal_port=%s api_port=%s", utils.Listeners, utils.UdpPort, utils.TcpPort, utils.JournalPort, utils.ApiPort)
+	log.Printf("Config: log_format=%s tcp_log_format=%s udp_log_format=%s debug=%t retention_minutes=%d", utils.GetLogFormat(), utils.GetTCPLogFormat(), utils.GetUDPLogFormat(), utils.Debug, utils.LogRetentionMinutes)
+
+	// Shut down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go shutdownOnSignal(ctx)
+
+	// Flush the batch and clean up old logs periodically, shutdown flushes the last batch
+	db.StartBackgroundTasks(ctx)
+
+	// Check the store and query path before listeners accept traffic
+	if utils.SelfTest {
+		if err := db.SelfTest(utils.SelfTestRequired); err != nil {
+			log.Printf("Self-test failed: %v", err)
+		} else {
+			log.Printf("Self-test passed")
+		}
+	}
 
 	if slices.Contains(utils.Listeners, "udp") {
-		go listener.StartUDPListener()
+		go listener.StartUDPListener(ctx)
 	}
 
 	if slices.Contains(utils.Listeners, "tcp") {
 		go listener.StartTCPListener()
 	}
 
+	if slices.Contains(utils.Listeners, "journal") {
+		go listener.StartJournalListener()
+	}
+
 	server.StartHTTPServer()
 }
+
+// shutdownOnSignal drains the TCP connections and UDP messages once the context is done, then stores the pending batch,
+// or writes it to disk with SLOGGO_BATCH_PERSIST=true so it's restored on the next start
+func shutdownOnSignal(ctx context.Context) {
+	<-ctx.Done()
+
+	grace := time.Duration(utils.ShutdownGraceSeconds) * time.Second
+	log.Printf("Shutting down, draining TCP connections for up to %v", grace)
+	listener.ShutdownTCPListener(grace)
+
+	if !listener.ShutdownUDPListener(grace) {
+		log.Printf("The UDP listener was still processing messages after the %v shutdown grace period", grace)
+	}
+
+	if utils.BatchPersist {
+		if err := db.SpillBatch(); err != nil {
+			log.Printf("Failed to persist the pending batch: %v", err)
+			os.Exit(1)
+		}
+	} else if err := db.ProcessBatchStoreLogs(); err != nil {
+		log.Printf("Failed to store the pending batch: %v", err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
@@ -1,9 +1,20 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 )
 
+// rfc5424TimestampLayout is the RFC3339 layout limited to the microsecond precision allowed by RFC5424
+const rfc5424TimestampLayout = "2006-01-02T15:04:05.999999Z07:00"
+
+// sdParamEscaper escapes the characters RFC5424 requires to be escaped in structured data param values
+var sdParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
 // LogEntry represents a log entry in the system
 // It's used both for database operations and API responses
 type LogEntry struct {
@@ -14,12 +25,91 @@ type LogEntry struct {
 	Version        uint16    `json:"version,omitempty"`
 	Timestamp      time.Time `json:"timestamp"`
 	Hostname       string    `json:"hostname"`
-	AppName        string    `json:"appName"` // Note: DB column is app_name
-	ProcID         string    `json:"procId"`  // Note: DB column is procid
-	MsgID          string    `json:"msgId"`   // Note: DB column is msgid
-	StructuredData string    `json:"-"`       // Note: DB column is structured_data
-	Message        string    `json:"message"` // Note: DB column is msg
+	AppName        string    `json:"appName"`    // Note: DB column is app_name
+	ProcID         string    `json:"procId"`     // Note: DB column is procid
+	MsgID          string    `json:"msgId"`      // Note: DB column is msgid
+	StructuredData string    `json:"-"`          // Note: DB column is structured_data, empty (NULL) when absent
+	Message        string    `json:"message"`    // Note: DB column is msg
+	ReceivedAt     time.Time `json:"receivedAt"` // Note: DB column is received_at, Timestamp is the event time
+	Table          string    `json:"-"`          // Table the entry is stored in, the default logs table when empty
 
 	// Derived fields for API responses
 	ParsedStructuredData map[string]map[string]string `json:"structuredData,omitempty"` // Parsed form of StructuredData
 }
+
+// Priority returns the original syslog priority value (facility * 8 + severity)
+func (e LogEntry) Priority() int {
+	return int(e.Facility)*8 + int(e.Severity)
+}
+
+// MarshalJSON adds the derived priority to the JSON representation without storing it
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	// The alias type drops the methods to avoid recursing into MarshalJSON
+	type logEntry LogEntry
+
+	return json.Marshal(struct {
+		logEntry
+		Priority int `json:"priority"`
+	}{
+		logEntry: logEntry(e),
+		Priority: e.Priority(),
+	})
+}
+
+// ToRFC5424 reconstructs the RFC5424 syslog message of the entry, without framing
+// The structured data stored as JSON is converted back, with its elements and params sorted
+func (e LogEntry) ToRFC5424() string {
+	version := e.Version
+	if version == 0 {
+		version = 1
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "<%d>%d %s %s %s %s %s %s",
+		e.Priority(),
+		version,
+		e.Timestamp.Format(rfc5424TimestampLayout),
+		headerField(e.Hostname),
+		headerField(e.AppName),
+		headerField(e.ProcID),
+		headerField(e.MsgID),
+		e.structuredDataElements(),
+	)
+
+	if e.Message != "" {
+		message.WriteString(" ")
+		message.WriteString(e.Message)
+	}
+
+	return message.String()
+}
+
+// headerField returns the RFC5424 nil value "-" for empty header fields
+func headerField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// structuredDataElements converts the structured data stored as JSON to RFC5424 elements
+func (e LogEntry) structuredDataElements() string {
+	var structuredData map[string]map[string]string
+	if err := json.Unmarshal([]byte(e.StructuredData), &structuredData); err != nil || len(structuredData) == 0 {
+		return "-"
+	}
+
+	var elements strings.Builder
+	for _, id := range slices.Sorted(maps.Keys(structuredData)) {
+		elements.WriteString("[" + id)
+
+		params := structuredData[id]
+		for _, name := range slices.Sorted(maps.Keys(params)) {
+			fmt.Fprintf(&elements, ` %s="%s"`, name, sdParamEscaper.Replace(params[name]))
+		}
+
+		elements.WriteString("]")
+	}
+
+	return elements.String()
+}
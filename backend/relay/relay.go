@@ -0,0 +1,150 @@
+package relay
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sloggo/models"
+	"sloggo/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// dialTimeout and writeTimeout bound each attempt to reach the upstream server
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+
+	// minRetryDelay and maxRetryDelay bound the exponential backoff between failed attempts
+	minRetryDelay = 250 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// Relay forwards log entries to an upstream syslog server, buffering them while it's unreachable
+type Relay struct {
+	network string
+	address string
+	queue   chan models.LogEntry
+	conn    net.Conn
+}
+
+var (
+	relay *Relay
+
+	// forwardedLogs counts the logs sent to the upstream server
+	forwardedLogs = expvar.NewInt("forwardedLogs")
+
+	// forwardDropped counts the logs dropped because the forward buffer was full
+	forwardDropped = expvar.NewInt("forwardDropped")
+)
+
+func init() {
+	if utils.ForwardAddr == "" {
+		return
+	}
+
+	network, address, err := ParseAddr(utils.ForwardAddr)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_FORWARD_ADDR, forwarding is disabled: %v", err)
+		return
+	}
+
+	relay = New(network, address, int(max(utils.ForwardBuffer, 1)))
+	go relay.Run()
+
+	log.Printf("Forwarding logs to %s://%s", network, address)
+}
+
+// ParseAddr parses an upstream address like "tcp://host:601", the network defaults to UDP without a scheme
+func ParseAddr(value string) (string, string, error) {
+	network, address, found := strings.Cut(value, "://")
+	if !found {
+		network, address = "udp", value
+	}
+
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("unsupported network %q", network)
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %v", address, err)
+	}
+
+	return network, address, nil
+}
+
+// New creates a relay buffering up to bufferSize logs, Run must be started to send them
+func New(network, address string, bufferSize int) *Relay {
+	return &Relay{
+		network: network,
+		address: address,
+		queue:   make(chan models.LogEntry, bufferSize),
+	}
+}
+
+// Forward queues the entry for the configured upstream server, if any
+// It never blocks, entries are dropped when the buffer is full
+func Forward(entry models.LogEntry) {
+	if relay != nil {
+		relay.Enqueue(entry)
+	}
+}
+
+// Enqueue queues the entry without blocking, it's dropped when the buffer is full
+func (r *Relay) Enqueue(entry models.LogEntry) {
+	select {
+	case r.queue <- entry:
+	default:
+		forwardDropped.Add(1)
+	}
+}
+
+// Run sends the queued entries in order, retrying each one until the upstream server accepts it
+func (r *Relay) Run() {
+	for entry := range r.queue {
+		message := entry.ToRFC5424()
+
+		retryDelay := minRetryDelay
+		for {
+			err := r.send(message)
+			if err == nil {
+				break
+			}
+
+			log.Printf("Error forwarding log to %s, retrying in %v: %v", r.address, retryDelay, err)
+			time.Sleep(retryDelay)
+			retryDelay = min(retryDelay*2, maxRetryDelay)
+		}
+
+		forwardedLogs.Add(1)
+	}
+}
+
+// send writes a message to the upstream server, connecting first if needed
+// TCP messages are octet-counted so multi-line messages stay whole
+func (r *Relay) send(message string) error {
+	if r.conn == nil {
+		conn, err := net.DialTimeout(r.network, r.address, dialTimeout)
+		if err != nil {
+			return fmt.Errorf("error connecting: %v", err)
+		}
+		r.conn = conn
+	}
+
+	frame := message
+	if r.network == "tcp" {
+		frame = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	r.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := io.WriteString(r.conn, frame); err != nil {
+		// Reconnect on the next attempt
+		r.conn.Close()
+		r.conn = nil
+		return fmt.Errorf("error writing: %v", err)
+	}
+
+	return nil
+}
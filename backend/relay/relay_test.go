@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sloggo/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		value           string
+		expectedNetwork string
+		expectedAddress string
+		shouldError     bool
+	}{
+		{"tcp://logs.example.com:601", "tcp", "logs.example.com:601", false},
+		{"udp://10.0.0.1:514", "udp", "10.0.0.1:514", false},
+		{"10.0.0.1:514", "udp", "10.0.0.1:514", false},
+		{"http://logs.example.com:80", "", "", true},
+		{"tcp://logs.example.com", "", "", true},
+	}
+
+	for _, tt := range tests {
+		network, address, err := ParseAddr(tt.value)
+		if tt.shouldError {
+			if err == nil {
+				t.Errorf("ParseAddr(%q): expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAddr(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if network != tt.expectedNetwork || address != tt.expectedAddress {
+			t.Errorf("ParseAddr(%q) = %s, %s, want %s, %s", tt.value, network, address, tt.expectedNetwork, tt.expectedAddress)
+		}
+	}
+}
+
+// readOctetCountedFrame reads a "<length> <message>" frame
+func readOctetCountedFrame(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	prefix, err := reader.ReadString(' ')
+	if err != nil {
+		t.Fatalf("Failed to read the frame length: %v", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(prefix))
+	if err != nil {
+		t.Fatalf("Invalid frame length %q: %v", prefix, err)
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		t.Fatalf("Failed to read the frame: %v", err)
+	}
+
+	return string(frame)
+}
+
+func TestRelayRetriesUntilUpstreamIsReachable(t *testing.T) {
+	// Reserve a port, the upstream only starts listening once the relay failed to connect
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	address := reserved.Addr().String()
+	reserved.Close()
+
+	relay := New("tcp", address, 10)
+	go relay.Run()
+
+	entries := []models.LogEntry{
+		{Facility: 1, Severity: 3, Timestamp: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), Hostname: "relay-host", AppName: "app", ProcID: "-", MsgID: "-", StructuredData: "-", Message: "java.lang.Exception: boom\n\tat A.b(A.java:1)"},
+		{Facility: 1, Severity: 6, Timestamp: time.Date(2024, 6, 1, 12, 0, 1, 0, time.UTC), Hostname: "relay-host", AppName: "app", ProcID: "-", MsgID: "-", StructuredData: `{"meta":{"env":"prod"}}`, Message: "Recovered"},
+	}
+	for _, entry := range entries {
+		relay.Enqueue(entry)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	upstream, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("Failed to start the upstream server: %v", err)
+	}
+	defer upstream.Close()
+
+	conn, err := upstream.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept the relay connection: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	for _, entry := range entries {
+		if frame := readOctetCountedFrame(t, reader); frame != entry.ToRFC5424() {
+			t.Errorf("Got frame %q, want %q", frame, entry.ToRFC5424())
+		}
+	}
+}
+
+func TestRelayDropsWhenBufferIsFull(t *testing.T) {
+	// Without Run nothing is sent, so the buffer fills up
+	relay := New("udp", "127.0.0.1:514", 1)
+
+	droppedBefore := forwardDropped.Value()
+	relay.Enqueue(models.LogEntry{Message: "Buffered"})
+	relay.Enqueue(models.LogEntry{Message: "Dropped"})
+
+	if dropped := forwardDropped.Value() - droppedBefore; dropped != 1 {
+		t.Errorf("Expected 1 dropped log, got %d", dropped)
+	}
+}
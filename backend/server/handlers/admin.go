@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sloggo/utils"
+	"strings"
+)
+
+// RequireAdmin guards admin endpoints behind the SLOGGO_ADMIN_TOKEN bearer token
+// Admin endpoints are disabled when no token is configured
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if utils.AdminToken == "" {
+			writeError(w, http.StatusForbidden, "forbidden", "Admin endpoints are disabled")
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(utils.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/db"
+	"strconv"
+	"time"
+)
+
+// maxAggregateGroups bounds the number of groups returned by the aggregate endpoint
+const maxAggregateGroups = 1000
+
+const (
+	// maxSparklineGroups bounds the groups with a sparkline, with maxSparklineBuckets it bounds the bucketed values
+	maxSparklineGroups = 50
+
+	// maxSparklineBuckets bounds the number of buckets of each sparkline
+	maxSparklineBuckets = 100
+
+	// defaultSparklineWindow is the sparkline window when no timestamp range is requested
+	defaultSparklineWindow = 24 * time.Hour
+)
+
+// SparklineWindow describes the time buckets of the sparklines
+type SparklineWindow struct {
+	Start   int64 `json:"start"` // Milliseconds
+	End     int64 `json:"end"`   // Milliseconds
+	Buckets int   `json:"buckets"`
+}
+
+// AggregateResponse represents the API response format for aggregations
+type AggregateResponse struct {
+	Metric   string            `json:"metric"`
+	GroupBy  string            `json:"groupBy"`
+	Timezone string            `json:"timezone,omitempty"`
+	Data     []db.AggregateRow `json:"data"`
+
+	// Sparkline describes the buckets of the sparklines, only set with sparkline=true
+	Sparkline *SparklineWindow `json:"sparkline,omitempty"`
+}
+
+// AggregateHandler handles the aggregation endpoint
+// It computes a metric (count or noiseScore) per value of the groupBy field, with the same filters as the logs endpoint
+// groupBy=hourOfDay buckets logs by hour of the day, in the timezone given by the tz parameter (default: UTC)
+// sparkline=true adds the metric of each group over equal time buckets of the timestamp range (default: last 24 hours)
+func AggregateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = "count"
+	} else if metric != "count" && metric != "noiseScore" {
+		addInvalidParam("metric", metric, "must be count or noiseScore")
+	}
+
+	groupBy := query.Get("groupBy")
+	if groupBy == "" {
+		groupBy = "hostname"
+	} else if !db.IsAggregateGroupField(groupBy) {
+		addInvalidParam("groupBy", groupBy, "unsupported group field")
+	}
+
+	limit := 50
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= maxAggregateGroups {
+			limit = parsedLimit
+		} else {
+			addInvalidParam("limit", limitStr, "must be an integer between 1 and "+strconv.Itoa(maxAggregateGroups))
+		}
+	}
+
+	// Timezone of the hourOfDay buckets, validated here as the database only reports it at query time
+	timezone := "UTC"
+	if tz := query.Get("tz"); tz != "" {
+		if _, err := time.LoadLocation(tz); err == nil && tz != "Local" {
+			timezone = tz
+		} else {
+			addInvalidParam("tz", tz, "must be an IANA timezone such as Europe/Paris")
+		}
+	}
+
+	// Sparklines of the top groups, bounded to maxSparklineGroups groups of maxSparklineBuckets buckets
+	sparkline := false
+	buckets := 24
+	if sparklineStr := query.Get("sparkline"); sparklineStr != "" {
+		if parsed, err := strconv.ParseBool(sparklineStr); err == nil {
+			sparkline = parsed
+		} else {
+			addInvalidParam("sparkline", sparklineStr, "must be true or false")
+		}
+	}
+	if sparkline {
+		if groupBy == "hourOfDay" {
+			addInvalidParam("sparkline", query.Get("sparkline"), "is not supported with groupBy=hourOfDay")
+		}
+		if limit > maxSparklineGroups {
+			addInvalidParam("limit", query.Get("limit"), "must be at most "+strconv.Itoa(maxSparklineGroups)+" with sparkline=true")
+		}
+		if bucketsStr := query.Get("buckets"); bucketsStr != "" {
+			if parsedBuckets, err := strconv.Atoi(bucketsStr); err == nil && parsedBuckets > 0 && parsedBuckets <= maxSparklineBuckets {
+				buckets = parsedBuckets
+			} else {
+				addInvalidParam("buckets", bucketsStr, "must be an integer between 1 and "+strconv.Itoa(maxSparklineBuckets))
+			}
+		}
+	}
+
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	// The groups and their sparklines cover the same window
+	if sparkline && (filters["startDate"] == nil || filters["endDate"] == nil) {
+		now := time.Now().UTC()
+		filters["startDate"] = now.Add(-defaultSparklineWindow)
+		filters["endDate"] = now
+	} else if sparkline && !filters["endDate"].(time.Time).After(filters["startDate"].(time.Time)) {
+		addInvalidParam("timestamp", query.Get("timestamp"), "must end after it starts with sparkline=true")
+	}
+
+	// Unlike the logs endpoint, an aggregation over ignored parameters would be misleading
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	rows, err := db.Aggregate(metric, groupBy, filters, limit, timezone)
+	if err != nil {
+		log.Printf("Error computing aggregate: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	response := AggregateResponse{Metric: metric, GroupBy: groupBy, Data: rows}
+	if groupBy == "hourOfDay" {
+		response.Timezone = timezone
+	}
+
+	if sparkline {
+		if err := db.Sparklines(metric, groupBy, filters, rows, buckets); err != nil {
+			log.Printf("Error computing sparklines: %v", err)
+			writeInternalError(w)
+			return
+		}
+
+		response.Sparkline = &SparklineWindow{
+			Start:   filters["startDate"].(time.Time).UnixMilli(),
+			End:     filters["endDate"].(time.Time).UnixMilli(),
+			Buckets: buckets,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sloggo/db"
+	"sloggo/models"
+	"sloggo/utils"
+	"strings"
+	"time"
+)
+
+// bulkChunkSize is the number of entries stored at once by the bulk ingest endpoint
+const bulkChunkSize = 50000
+
+// maxBulkRejections bounds the number of rejected lines detailed in the bulk ingest response
+const maxBulkRejections = 100
+
+// BulkRejection describes a line rejected by the bulk ingest endpoint
+type BulkRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// BulkIngestResponse reports the outcome and throughput of a bulk load
+type BulkIngestResponse struct {
+	Accepted      int             `json:"accepted"`
+	Rejected      int             `json:"rejected"`
+	DurationMs    int64           `json:"durationMs"`
+	RowsPerSecond float64         `json:"rowsPerSecond"`
+	Rejections    []BulkRejection `json:"rejections"`
+	Error         *ErrorDetail    `json:"error,omitempty"` // Set with a non-2xx status code, the counts report the progress made
+}
+
+// BulkIngestHandler bulk loads historical NDJSON logs, for instance when migrating from another log store
+// Lines use the ingest format but must carry their original timestamp, which is stored as is.
+// Entries are stored in large chunks as the body is read, without waiting for the batch timer.
+func BulkIngestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	table, ok := parseTableParam(w, r)
+	if !ok {
+		return
+	}
+
+	startTime := time.Now()
+
+	scanner := bufio.NewScanner(r.Body)
+
+	// Configure scanner with a larger buffer for bigger messages
+	const maxScanSize = 1024 * 1024 // 1MB max line size
+	buffer := make([]byte, 0, 64*1024)
+	scanner.Buffer(buffer, maxScanSize)
+
+	response := BulkIngestResponse{Rejections: []BulkRejection{}}
+	reject := func(line int, reason string) {
+		response.Rejected++
+		if len(response.Rejections) < maxBulkRejections {
+			response.Rejections = append(response.Rejections, BulkRejection{Line: line, Reason: reason})
+		}
+	}
+
+	// Logs older than the retention period would be deleted by the next cleanup
+	retentionCutoff := time.Now().Add(-time.Duration(utils.LogRetentionMinutes) * time.Minute)
+
+	chunk := make([]models.LogEntry, 0, bulkChunkSize)
+	storeChunk := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := db.StoreLogsDirect(chunk); err != nil {
+			return err
+		}
+		response.Accepted += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	status := http.StatusOK
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseIngestLine(line, true, time.Time{})
+		if err != nil {
+			reject(lineNumber, err.Error())
+			continue
+		}
+		if entry.Timestamp.Before(retentionCutoff) {
+			reject(lineNumber, "timestamp older than the retention period")
+			continue
+		}
+
+		entry.Table = table
+		chunk = append(chunk, *entry)
+		if len(chunk) >= bulkChunkSize {
+			if err := storeChunk(); err != nil {
+				status = http.StatusInternalServerError
+				response.Error = &ErrorDetail{Code: "internal_error", Message: fmt.Sprintf("error storing logs before line %d, %d logs were stored", lineNumber+1, response.Accepted)}
+				log.Printf("Bulk ingest from %s failed: %v", r.RemoteAddr, err)
+				break
+			}
+		}
+	}
+
+	if status == http.StatusOK {
+		if err := scanner.Err(); err != nil {
+			status = http.StatusBadRequest
+			code := "bad_request"
+			if errors.Is(err, bufio.ErrTooLong) {
+				status = http.StatusRequestEntityTooLarge
+				code = "payload_too_large"
+			}
+			response.Error = &ErrorDetail{Code: code, Message: fmt.Sprintf("error reading line %d: %v", lineNumber+1, err)}
+			log.Printf("Bulk ingest from %s interrupted: %v", r.RemoteAddr, err)
+		}
+
+		// What was read before an interrupted stream is kept, like the ingest endpoint does
+		if err := storeChunk(); err != nil {
+			status = http.StatusInternalServerError
+			response.Error = &ErrorDetail{Code: "internal_error", Message: fmt.Sprintf("error storing the last logs, %d logs were stored", response.Accepted)}
+			log.Printf("Bulk ingest from %s failed: %v", r.RemoteAddr, err)
+		}
+	}
+
+	duration := time.Since(startTime)
+	response.DurationMs = duration.Milliseconds()
+	if seconds := duration.Seconds(); seconds > 0 {
+		response.RowsPerSecond = float64(response.Accepted) / seconds
+	}
+
+	log.Printf("Bulk ingest from %s: %d accepted, %d rejected in %v (%.0f rows/s)", r.RemoteAddr, response.Accepted, response.Rejected, duration, response.RowsPerSecond)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
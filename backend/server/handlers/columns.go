@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"sloggo/db"
+)
+
+// Column describes a log field for clients rendering tables and filters
+type Column struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Type        string `json:"type"`                  // int, string, timestamp or object
+	FilterParam string `json:"filterParam,omitempty"` // Query parameter filtering on the column, if any
+	Facetable   bool   `json:"facetable"`
+	Sortable    bool   `json:"sortable"`
+}
+
+// ColumnsResponse represents the API response format for columns
+type ColumnsResponse struct {
+	Data []Column `json:"data"`
+}
+
+// logColumns lists the fields of the log entries returned by the API, in display order
+// Facetable and sortable are derived from the facets and sort whitelist so they can't drift
+var logColumns = []Column{
+	{Name: "id", Label: "ID", Type: "int"},
+	{Name: "timestamp", Label: "Timestamp", Type: "timestamp", FilterParam: "timestamp"},
+	{Name: "receivedAt", Label: "Received at", Type: "timestamp", FilterParam: "receivedAt"},
+	{Name: "severity", Label: "Severity", Type: "int", FilterParam: "severity"},
+	{Name: "facility", Label: "Facility", Type: "int", FilterParam: "facility"},
+	{Name: "priority", Label: "Priority", Type: "int", FilterParam: "priority"},
+	{Name: "hostname", Label: "Hostname", Type: "string", FilterParam: "hostname"},
+	{Name: "appName", Label: "App name", Type: "string", FilterParam: "appName"},
+	{Name: "procId", Label: "Process ID", Type: "string", FilterParam: "procId"},
+	{Name: "msgId", Label: "Message ID", Type: "string", FilterParam: "msgId"},
+	{Name: "message", Label: "Message", Type: "string", FilterParam: "search"},
+	{Name: "structuredData", Label: "Structured data", Type: "object", FilterParam: "sd.<path>"},
+}
+
+// ColumnsHandler handles the endpoint describing the log columns
+func ColumnsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	facetKeys := db.FacetKeys()
+
+	columns := make([]Column, len(logColumns))
+	for i, column := range logColumns {
+		_, column.Sortable = sortColumns[column.Name]
+		column.Facetable = slices.Contains(facetKeys, column.Name)
+		columns[i] = column
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(ColumnsResponse{Data: columns}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
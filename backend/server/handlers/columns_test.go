@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestColumnsHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/logs/columns", nil)
+	w := httptest.NewRecorder()
+
+	ColumnsHandler(w, req)
+
+	var result ColumnsResponse
+	if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+
+	columns := make(map[string]Column, len(result.Data))
+	for _, column := range result.Data {
+		columns[column.Name] = column
+	}
+
+	// Every sortable field is described as a sortable column
+	for field := range sortColumns {
+		if column, ok := columns[field]; !ok || !column.Sortable {
+			t.Errorf("Expected a sortable %q column, got %+v", field, column)
+		}
+	}
+
+	expected := map[string]Column{
+		"severity":  {Name: "severity", Label: "Severity", Type: "int", FilterParam: "severity", Facetable: true, Sortable: true},
+		"procId":    {Name: "procId", Label: "Process ID", Type: "string", FilterParam: "procId", Facetable: true, Sortable: true},
+		"hostname":  {Name: "hostname", Label: "Hostname", Type: "string", FilterParam: "hostname", Sortable: true},
+		"timestamp": {Name: "timestamp", Label: "Timestamp", Type: "timestamp", FilterParam: "timestamp", Sortable: true},
+		"id":        {Name: "id", Label: "ID", Type: "int"},
+	}
+	for name, want := range expected {
+		if columns[name] != want {
+			t.Errorf("Column %q: got %+v, want %+v", name, columns[name], want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"sloggo/models"
+	"sloggo/utils"
+	"time"
+)
+
+// LegacyLogEntry is the log entry representation used with SLOGGO_API_COMPAT=legacy
+// It renames fields for frontends built against the older API, the model tags are left untouched
+type LegacyLogEntry struct {
+	RowID          int64                        `json:"id"`
+	Facility       uint8                        `json:"facility"`
+	Severity       uint8                        `json:"severity"`
+	Priority       int                          `json:"priority"`
+	Version        uint16                       `json:"version,omitempty"`
+	Timestamp      time.Time                    `json:"timestamp"`
+	Host           string                       `json:"host"`
+	App            string                       `json:"app"`
+	ProcID         string                       `json:"procId"`
+	MsgID          string                       `json:"msgId"`
+	Message        string                       `json:"message"`
+	StructuredData map[string]map[string]string `json:"structuredData,omitempty"`
+}
+
+// legacyLogsResponse overrides the data of a logs response with legacy entries
+type legacyLogsResponse struct {
+	LogsResponse
+	Data []LegacyLogEntry `json:"data"`
+}
+
+// compatResponse maps the response to the representation selected by SLOGGO_API_COMPAT
+func compatResponse(response LogsResponse) any {
+	if utils.ApiCompat != "legacy" {
+		return response
+	}
+
+	return legacyLogsResponse{
+		LogsResponse: response,
+		Data:         toLegacyLogEntries(response.Data),
+	}
+}
+
+// compatLogs maps log entries to the representation selected by SLOGGO_API_COMPAT
+func compatLogs(logs []models.LogEntry) any {
+	if utils.ApiCompat != "legacy" {
+		return logs
+	}
+
+	return toLegacyLogEntries(logs)
+}
+
+// toLegacyLogEntries converts log entries to their legacy representation
+func toLegacyLogEntries(logs []models.LogEntry) []LegacyLogEntry {
+	entries := make([]LegacyLogEntry, len(logs))
+
+	for i, entry := range logs {
+		entries[i] = LegacyLogEntry{
+			RowID:          entry.RowID,
+			Facility:       entry.Facility,
+			Severity:       entry.Severity,
+			Priority:       entry.Priority(),
+			Version:        entry.Version,
+			Timestamp:      entry.Timestamp,
+			Host:           entry.Hostname,
+			App:            entry.AppName,
+			ProcID:         entry.ProcID,
+			MsgID:          entry.MsgID,
+			Message:        entry.Message,
+			StructuredData: entry.ParsedStructuredData,
+		}
+	}
+
+	return entries
+}
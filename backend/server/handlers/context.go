@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sloggo/db"
+	"sloggo/models"
+	"strconv"
+)
+
+// maxContextLines bounds the number of logs returned on each side of the anchor
+const maxContextLines = 500
+
+// LogContextResponse represents the API response format for the context of a log
+// Data holds the surrounding logs and the anchor log, ordered by timestamp
+type LogContextResponse struct {
+	Data     any   `json:"data"`
+	AnchorID int64 `json:"anchorId"`
+}
+
+// LogContextHandler handles the endpoint returning the logs surrounding a log
+// The surrounding logs come from the same hostname and app name as the anchor, with the usual filters applied on top
+func LogContextHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		addInvalidParam("id", idStr, "must be an integer")
+	}
+
+	parseLines := func(param string) int {
+		lines := 20
+		if linesStr := query.Get(param); linesStr != "" {
+			if parsedLines, err := strconv.Atoi(linesStr); err == nil && parsedLines >= 0 && parsedLines <= maxContextLines {
+				lines = parsedLines
+			} else {
+				addInvalidParam(param, linesStr, "must be an integer between 0 and "+strconv.Itoa(maxContextLines))
+			}
+		}
+		return lines
+	}
+
+	before := parseLines("before")
+	after := parseLines("after")
+
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	logContext, err := db.GetContext(id, before, after, filters)
+	if errors.Is(err, db.ErrLogNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Log not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching log context: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	logs := make([]models.LogEntry, 0, len(logContext.Before)+1+len(logContext.After))
+	logs = append(logs, logContext.Before...)
+	logs = append(logs, logContext.Anchor)
+	logs = append(logs, logContext.After...)
+	prepareLogs(logs)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(LogContextResponse{Data: compatLogs(logs), AnchorID: logContext.Anchor.RowID}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/db"
+	"strconv"
+)
+
+// maxDistinctValues bounds the number of values returned by the distinct endpoint
+const maxDistinctValues = 1000
+
+// DistinctResponse represents the API response format for distinct values
+type DistinctResponse struct {
+	Field     string   `json:"field"`
+	Values    []string `json:"values"`
+	Truncated bool     `json:"truncated"`
+}
+
+// DistinctHandler handles the endpoint listing the distinct values of a field
+// It is lighter than facets when counts aren't needed, and accepts the same filters as the logs endpoint
+func DistinctHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	field := query.Get("field")
+	if !db.IsDistinctField(field) {
+		addInvalidParam("field", field, "must be hostname, appName, procId or msgId")
+	}
+
+	limit := maxDistinctValues
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= maxDistinctValues {
+			limit = parsedLimit
+		} else {
+			addInvalidParam("limit", limitStr, "must be an integer between 1 and "+strconv.Itoa(maxDistinctValues))
+		}
+	}
+
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	values, truncated, err := db.Distinct(field, filters, limit)
+	if err != nil {
+		log.Printf("Error fetching distinct values: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(DistinctResponse{Field: field, Values: values, Truncated: truncated}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// newEncoder returns the JSON encoder of a response, indented with ?pretty=true to read it by hand
+// Responses are compact by default, as expected by the frontend
+func newEncoder(w http.ResponseWriter, r *http.Request) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	if r.URL.Query().Get("pretty") == "true" {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder
+}
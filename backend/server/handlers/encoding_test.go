@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{"compact by default", "/api/logs", "{\"a\":1}\n"},
+		{"indented with pretty", "/api/logs?pretty=true", "{\n  \"a\": 1\n}\n"},
+		{"compact unless true", "/api/logs?pretty=1", "{\"a\":1}\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			if err := newEncoder(w, httptest.NewRequest("GET", tc.target, nil)).Encode(map[string]int{"a": 1}); err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if w.Body.String() != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ErrorResponse is the envelope of the API responses with a non-2xx status code
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error with a stable code for clients and a human readable message
+type ErrorDetail struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Params  []InvalidParam `json:"params,omitempty"` // Set for invalid_params errors
+}
+
+// writeError responds with the JSON error envelope
+func writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	writeErrorDetail(w, statusCode, ErrorDetail{Code: code, Message: message})
+}
+
+// writeErrorDetail responds with the JSON error envelope of a detailed error
+func writeErrorDetail(w http.ResponseWriter, statusCode int, detail ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: detail}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// writeMethodNotAllowed responds that the request method is not supported by the endpoint
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+}
+
+// writeInternalError responds with an internal error, the cause is only logged
+func writeInternalError(w http.ResponseWriter) {
+	writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
+}
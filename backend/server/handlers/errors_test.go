@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name         string
+		write        func(w http.ResponseWriter)
+		expectedCode int
+		expectedErr  string
+	}{
+		{"bad request", func(w http.ResponseWriter) { writeInvalidParams(w, []InvalidParam{{Param: "size", Value: "0"}}) }, http.StatusBadRequest, "invalid_params"},
+		{"not found", func(w http.ResponseWriter) { writeError(w, http.StatusNotFound, "not_found", "Log not found") }, http.StatusNotFound, "not_found"},
+		{"method not allowed", writeMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"internal error", writeInternalError, http.StatusInternalServerError, "internal_error"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.write(w)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedCode, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected a JSON error, got %q", contentType)
+			}
+
+			var response ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if response.Error.Code != tc.expectedErr || response.Error.Message == "" {
+				t.Errorf("Expected a %s error with a message, got %+v", tc.expectedErr, response.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/db"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxEventHosts bounds the number of hosts returned by the events endpoint
+	maxEventHosts = 1000
+
+	// maxEventSample bounds the number of recent logs returned by the events endpoint
+	maxEventSample = 500
+)
+
+// EventsResponse represents the API response format for the correlation of an event type
+type EventsResponse struct {
+	MsgID  string         `json:"msgId"`
+	Total  int64          `json:"total"`
+	Hosts  []db.EventHost `json:"hosts"`
+	Sample any            `json:"sample"`
+}
+
+// EventsHandler handles the endpoint correlating the logs of an event type, identified by their MsgID, across hosts
+// It returns the count per host and the most recent logs, within the time window of the logs endpoint
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+	msgID := r.PathValue("msgid")
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	limit := 50
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= maxEventHosts {
+			limit = parsedLimit
+		} else {
+			addInvalidParam("limit", limitStr, "must be an integer between 1 and "+strconv.Itoa(maxEventHosts))
+		}
+	}
+
+	sample := 20
+	if sampleStr := query.Get("sample"); sampleStr != "" {
+		if parsedSample, err := strconv.Atoi(sampleStr); err == nil && parsedSample >= 0 && parsedSample <= maxEventSample {
+			sample = parsedSample
+		} else {
+			addInvalidParam("sample", sampleStr, "must be an integer between 0 and "+strconv.Itoa(maxEventSample))
+		}
+	}
+
+	window := parseWindowParam(query, addInvalidParam)
+
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	// Counts over the window the dashboard shows, an explicit timestamp range takes precedence
+	if start := windowStartOf(filters, window, time.Now().UTC()); !start.IsZero() {
+		filters["startDate"] = start
+	}
+
+	summary, err := db.GetEvents(msgID, filters, limit, sample)
+	if err != nil {
+		log.Printf("Error fetching events: %v", err)
+		writeInternalError(w)
+		return
+	}
+	prepareLogs(summary.Sample)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := EventsResponse{MsgID: msgID, Total: summary.Total, Hosts: summary.Hosts, Sample: compatLogs(summary.Sample)}
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
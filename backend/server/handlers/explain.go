@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/db"
+	"sloggo/utils"
+	"strconv"
+	"time"
+)
+
+// ExplainHandler handles the debugging endpoint returning the EXPLAIN ANALYZE plan of the logs query
+// It accepts the same size, direction, sort and filter parameters as the logs endpoint, in the query string or a form body
+func ExplainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid form body")
+		return
+	}
+	query := r.Form
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	size := 50
+	if sizeStr := query.Get("size"); sizeStr != "" {
+		if parsedSize, err := strconv.Atoi(sizeStr); err == nil && parsedSize > 0 {
+			size = parsedSize
+		} else {
+			addInvalidParam("size", sizeStr, "must be a positive integer")
+		}
+	}
+
+	direction := query.Get("direction")
+	if direction == "" {
+		direction = "next"
+	} else if direction != "next" && direction != "prev" && direction != "tail" {
+		addInvalidParam("direction", direction, "must be next, prev or tail")
+	}
+
+	sortField, sortOrder := parseSort(query, addInvalidParam)
+	window := parseWindowParam(query, addInvalidParam)
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	// Apply the same defaults as the logs endpoint so the plan matches the dashboard query
+	if !hasSeverityParam(query) && len(defaultSeverities) > 0 {
+		filters["severity"] = defaultSeverities
+	}
+
+	now := time.Now().UTC().Add(1 * time.Minute)
+	if start := windowStartOf(filters, window, now); !start.IsZero() && utils.DefaultWindowRows {
+		filters["startDate"] = start
+	}
+
+	plan, err := db.ExplainLogs(size, now, direction, filters, sortField, sortOrder)
+	if err != nil {
+		log.Printf("Error explaining logs query: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(plan)); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sloggo/db"
+	"sloggo/formats"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tableReason describes the accepted values of the table parameter
+func tableReason() string {
+	return "must be one of " + strings.Join(db.Tables(), ", ")
+}
+
+// parseTableParam validates the table parameter of the ingest endpoints, empty for the default table
+// It responds with the invalid parameter and returns false when the table is unknown
+func parseTableParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	table := r.URL.Query().Get("table")
+	if table != "" && !db.IsTable(table) {
+		writeInvalidParams(w, []InvalidParam{{Param: "table", Value: table, Reason: tableReason()}})
+		return "", false
+	}
+	return table, true
+}
+
+// parseFilters parses the filter parameters shared by the logs endpoints
+// It reports whether the invalid parameters must be rejected even without strict mode
+func parseFilters(query url.Values, addInvalidParam func(param string, value string, reason string)) (map[string]any, bool) {
+	filters := make(map[string]any)
+
+	// Hostname filter
+	if hostname := query.Get("hostname"); hostname != "" {
+		filters["hostname"] = hostname
+	}
+
+	// App name filter
+	if appName := query.Get("appName"); appName != "" {
+		filters["appName"] = appName
+	}
+
+	// Process ID filter
+	if procId := query.Get("procId"); procId != "" {
+		filters["procId"] = procId
+	}
+
+	// Message ID filter
+	if msgId := query.Get("msgId"); msgId != "" {
+		filters["msgId"] = msgId
+	}
+
+	// Message search, space-separated terms are ANDed by default or ORed with searchMode=any
+	if search := strings.Fields(query.Get("search")); len(search) > 0 {
+		filters["search"] = search
+
+		if query.Get("searchMode") == "any" {
+			filters["searchMode"] = "any"
+		} else {
+			filters["searchMode"] = "all"
+		}
+
+		// Fields the terms are matched against, only the message by default as scanning structured data is slower
+		if searchFields := query.Get("searchFields"); searchFields != "" {
+			fields := []string{}
+			for field := range strings.SplitSeq(searchFields, ",") {
+				field = strings.TrimSpace(field)
+				if !db.IsSearchField(field) {
+					addInvalidParam("searchFields", field, "must be message, msg or structuredData")
+				} else if !slices.Contains(fields, field) {
+					fields = append(fields, field)
+				}
+			}
+
+			if len(fields) > 0 {
+				filters["searchFields"] = fields
+			}
+		}
+	}
+
+	// Global search box, each space-separated term matches any of the message and header fields
+	if global := strings.Fields(query.Get("global")); len(global) > 0 {
+		filters["global"] = global
+	}
+
+	// Message emptiness filter, to find or hide header-only messages
+	if hasMessage := query.Get("hasMessage"); hasMessage != "" {
+		if parsed, err := strconv.ParseBool(hasMessage); err == nil {
+			filters["hasMessage"] = parsed
+		} else {
+			addInvalidParam("hasMessage", hasMessage, "must be true or false")
+		}
+	}
+
+	// Structured data presence filter
+	if hasStructuredData := query.Get("hasStructuredData"); hasStructuredData != "" {
+		if parsed, err := strconv.ParseBool(hasStructuredData); err == nil {
+			filters["hasStructuredData"] = parsed
+		} else {
+			addInvalidParam("hasStructuredData", hasStructuredData, "must be true or false")
+		}
+	}
+
+	// Structured data filters on dotted paths, e.g. "sd.kubernetes.pod_name=web-1"
+	structuredDataFilters := []*db.StructuredDataFilter{}
+	for _, param := range slices.Sorted(maps.Keys(query)) {
+		path, ok := strings.CutPrefix(param, "sd.")
+		if !ok || query.Get(param) == "" {
+			continue
+		}
+
+		if filter, err := db.NewStructuredDataFilter(path, query.Get(param)); err == nil {
+			structuredDataFilters = append(structuredDataFilters, filter)
+		} else {
+			addInvalidParam(param, query.Get(param), err.Error())
+		}
+	}
+	if len(structuredDataFilters) > 0 {
+		filters["structuredData"] = structuredDataFilters
+	}
+
+	// Filter expression, e.g. "(severity<=3 AND appName=db) OR hostname=edge1"
+	// An invalid expression is always rejected, ignoring it would silently return unfiltered logs
+	rejectInvalidParams := false
+	if q := query.Get("q"); q != "" {
+		if expression, err := db.ParseExpression(q); err == nil {
+			filters["expression"] = expression
+		} else {
+			addInvalidParam("q", q, err.Error())
+			rejectInvalidParams = true
+		}
+	}
+
+	// Table of the logs, one of SLOGGO_TABLES, an unknown table is always rejected rather than querying another one
+	if table := query.Get("table"); table != "" {
+		if db.IsTable(table) {
+			filters["table"] = table
+		} else {
+			addInvalidParam("table", table, tableReason())
+			rejectInvalidParams = true
+		}
+	}
+
+	// Facility filter
+	if facilityStr := query.Get("facility"); facilityStr != "" {
+		facilityValues := strings.Split(facilityStr, ",")
+		facilities := make([]int, 0, len(facilityValues))
+
+		for _, v := range facilityValues {
+			if facility, err := strconv.Atoi(v); err == nil {
+				facilities = append(facilities, facility)
+			} else {
+				addInvalidParam("facility", v, "must be an integer")
+			}
+		}
+
+		if len(facilities) > 0 {
+			filters["facility"] = facilities
+		}
+	}
+
+	// Severity filter
+	if severityStr := query.Get("severity"); severityStr != "" {
+		severityValues := strings.Split(severityStr, ",")
+		severities := make([]int, 0, len(severityValues))
+
+		for _, v := range severityValues {
+			if severity, err := strconv.Atoi(v); err == nil {
+				severities = append(severities, severity)
+			} else {
+				addInvalidParam("severity", v, "must be an integer")
+			}
+		}
+
+		if len(severities) > 0 {
+			filters["severity"] = severities
+		}
+	}
+
+	// Severity range filters, lower severities are more severe so maxSeverity=4 keeps warnings and worse
+	for _, param := range []string{"minSeverity", "maxSeverity"} {
+		if severityStr := query.Get(param); severityStr != "" {
+			if severity, err := formats.ParseSeverity(severityStr); err == nil {
+				filters[param] = int(severity)
+			} else {
+				addInvalidParam(param, severityStr, "must be a severity between 0 and 7 or a level name")
+			}
+		}
+	}
+	if minSeverity, ok := filters["minSeverity"].(int); ok {
+		if maxSeverity, ok := filters["maxSeverity"].(int); ok && minSeverity > maxSeverity {
+			addInvalidParam("minSeverity", query.Get("minSeverity"), "must not be greater than maxSeverity")
+		}
+	}
+
+	// Priority filter, the raw PRI value (facility * 8 + severity)
+	if priorityStr := query.Get("priority"); priorityStr != "" {
+		priorityValues := strings.Split(priorityStr, ",")
+		priorities := make([]int, 0, len(priorityValues))
+
+		for _, v := range priorityValues {
+			if priority, err := strconv.Atoi(v); err == nil {
+				priorities = append(priorities, priority)
+			} else {
+				addInvalidParam("priority", v, "must be an integer")
+			}
+		}
+
+		if len(priorities) > 0 {
+			filters["priority"] = priorities
+		}
+	}
+
+	// Date range filter, on the event time
+	if dateStr := query.Get("timestamp"); dateStr != "" {
+		if start, end, err := parseTimeRange(dateStr); err == nil {
+			filters["startDate"] = start
+			filters["endDate"] = end
+		} else {
+			addInvalidParam("timestamp", dateStr, err.Error())
+		}
+	}
+
+	// Received time range filter, regardless of the event time
+	if receivedStr := query.Get("receivedAt"); receivedStr != "" {
+		if start, end, err := parseTimeRange(receivedStr); err == nil {
+			filters["receivedStartDate"] = start
+			filters["receivedEndDate"] = end
+		} else {
+			addInvalidParam("receivedAt", receivedStr, err.Error())
+		}
+	}
+
+	// Incremental reads of the rows stored after a given id
+	if sinceIdStr := query.Get("sinceId"); sinceIdStr != "" {
+		if sinceId, err := strconv.ParseInt(sinceIdStr, 10, 64); err == nil && sinceId >= 0 {
+			filters["sinceId"] = sinceId
+		} else {
+			addInvalidParam("sinceId", sinceIdStr, "must be a non-negative integer")
+		}
+	}
+
+	// Range of ids, inclusive, to read stable chunks of the logs for exports
+	for _, param := range []string{"fromId", "toId"} {
+		if idStr := query.Get(param); idStr != "" {
+			if id, err := strconv.ParseInt(idStr, 10, 64); err == nil && id >= 0 {
+				filters[param] = id
+			} else {
+				addInvalidParam(param, idStr, "must be a non-negative integer")
+			}
+		}
+	}
+	if fromId, ok := filters["fromId"].(int64); ok {
+		if toId, ok := filters["toId"].(int64); ok && toId < fromId {
+			addInvalidParam("toId", query.Get("toId"), "must be greater than or equal to fromId")
+		}
+	}
+
+	return filters, rejectInvalidParams
+}
+
+// parseWindowParam parses the window parameter, SLOGGO_DEFAULT_WINDOW when absent and 0 for "all"
+func parseWindowParam(query url.Values, addInvalidParam func(param string, value string, reason string)) time.Duration {
+	windowStr := query.Get("window")
+	if windowStr == "" {
+		return defaultWindow
+	}
+
+	window, err := parseWindow(windowStr)
+	if err != nil {
+		addInvalidParam("window", windowStr, "must be a duration such as 15m, 24h or 7d, or all")
+		return defaultWindow
+	}
+	return window
+}
+
+// hasExplicitRange reports whether the filters hold a timestamp range, which takes precedence over the time window
+func hasExplicitRange(filters map[string]any) bool {
+	return filters["startDate"] != nil && filters["endDate"] != nil
+}
+
+// windowStartOf returns the start of the time window ending at now,
+// or the zero time when the window is disabled or an explicit timestamp range takes precedence
+func windowStartOf(filters map[string]any, window time.Duration, now time.Time) time.Time {
+	if window <= 0 || hasExplicitRange(filters) {
+		return time.Time{}
+	}
+	return now.Add(-window)
+}
+
+// parseTimeRange parses a "start-end" range of timestamps in milliseconds
+func parseTimeRange(value string) (time.Time, time.Time, error) {
+	dateValues := strings.Split(value, "-")
+	if len(dateValues) != 2 {
+		return time.Time{}, time.Time{}, errors.New("must be formatted as start-end")
+	}
+
+	startMillis, startErr := strconv.ParseInt(dateValues[0], 10, 64)
+	endMillis, endErr := strconv.ParseInt(dateValues[1], 10, 64)
+	if startErr != nil || endErr != nil {
+		return time.Time{}, time.Time{}, errors.New("must be two timestamps in milliseconds")
+	}
+
+	return time.UnixMilli(startMillis), time.UnixMilli(endMillis), nil
+}
+
+// writeInvalidParams responds with the list of invalid parameters
+func writeInvalidParams(w http.ResponseWriter, invalidParams []InvalidParam) {
+	writeErrorDetail(w, http.StatusBadRequest, ErrorDetail{Code: "invalid_params", Message: "Invalid parameters", Params: invalidParams})
+}
@@ -1,11 +1,59 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"sloggo/db"
+	"sloggo/listener"
 )
 
+// HealthDetailsResponse represents the detailed health of the backend
+type HealthDetailsResponse struct {
+	Status string        `json:"status"`
+	Batch  db.BatchStats `json:"batch"`
+}
+
 // HealthHandler handles the health check endpoint
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Sloggo backend is running"))
 }
+
+// ReadyHandler handles the readiness endpoint
+// Responds with 503 until the database is set up and at least one syslog listener is bound
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	if !db.IsReady() {
+		http.Error(w, "Database is not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !listener.IsReady() {
+		http.Error(w, "No listener is bound", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Sloggo backend is ready"))
+}
+
+// HealthDetailsHandler reports the state of the batch processor
+// Responds with 503 and a "degraded" status when pending logs have not been flushed for several intervals
+func HealthDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	response := HealthDetailsResponse{
+		Status: "ok",
+		Batch:  db.GetBatchStats(),
+	}
+
+	statusCode := http.StatusOK
+	if response.Batch.Stalled {
+		response.Status = "degraded"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
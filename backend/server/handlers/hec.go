@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sloggo/db"
+	"sloggo/formats"
+	"sloggo/models"
+	"sloggo/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HECEvent represents an event of the Splunk HTTP Event Collector format
+type HECEvent struct {
+	Time       json.Number     `json:"time"` // Seconds since the epoch, with an optional fraction
+	Host       string          `json:"host"`
+	Source     string          `json:"source"`
+	SourceType string          `json:"sourcetype"`
+	Index      string          `json:"index"`
+	Event      json.RawMessage `json:"event"` // A string, or a JSON value stored as is
+	Fields     map[string]any  `json:"fields"`
+}
+
+// HECResponse is the response of the HEC endpoints, with the status codes of Splunk
+type HECResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	InvalidEventNumber *int   `json:"invalid-event-number,omitempty"`
+}
+
+// writeHECResponse responds with a HEC status, agents expect it instead of the API error envelope
+func writeHECResponse(w http.ResponseWriter, statusCode int, response HECResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// HECHandler handles the Splunk HTTP Event Collector endpoint, so that agents configured for HEC can send logs
+// The body holds one or more concatenated JSON events, with the "Splunk <token>" authorization when SLOGGO_HEC_TOKEN is set
+func HECHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	if utils.HECToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Splunk ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Splunk")
+			writeHECResponse(w, http.StatusUnauthorized, HECResponse{Text: "Token is required", Code: 2})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(utils.HECToken)) != 1 {
+			writeHECResponse(w, http.StatusForbidden, HECResponse{Text: "Invalid token", Code: 4})
+			return
+		}
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	stored := 0
+
+	// Events before an invalid one are stored, the response tells the agent which one failed
+	for eventNumber := 0; ; eventNumber++ {
+		var event HECEvent
+		err := decoder.Decode(&event)
+		if errors.Is(err, io.EOF) {
+			if eventNumber == 0 {
+				writeHECResponse(w, http.StatusBadRequest, HECResponse{Text: "No data", Code: 5})
+				return
+			}
+			break
+		}
+		if err != nil {
+			writeHECResponse(w, http.StatusBadRequest, HECResponse{Text: "Invalid data format", Code: 6, InvalidEventNumber: &eventNumber})
+			return
+		}
+
+		entry, response := parseHECEvent(event)
+		if entry == nil {
+			response.InvalidEventNumber = &eventNumber
+			writeHECResponse(w, http.StatusBadRequest, response)
+			return
+		}
+
+		if err := db.StoreLog(*entry); err != nil {
+			log.Printf("Error storing HEC event after %d stored: %v", stored, err)
+			writeHECResponse(w, http.StatusInternalServerError, HECResponse{Text: "Internal server error", Code: 8, InvalidEventNumber: &eventNumber})
+			return
+		}
+		stored++
+	}
+
+	writeHECResponse(w, http.StatusOK, HECResponse{Text: "Success", Code: 0})
+}
+
+// HECHealthHandler handles the health endpoint of the HEC API, checked by agents before sending
+func HECHealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHECResponse(w, http.StatusOK, HECResponse{Text: "HEC is healthy", Code: 17})
+}
+
+// parseHECEvent converts a HEC event into a user-level informational log entry
+// The sourcetype is the app name, falling back to the source, the source, index and fields are kept as structured data
+// An invalid event returns a nil entry and the HEC response describing the error
+func parseHECEvent(event HECEvent) (*models.LogEntry, HECResponse) {
+	if len(event.Event) == 0 || bytes.Equal(event.Event, []byte("null")) {
+		return nil, HECResponse{Text: "Event field is required", Code: 12}
+	}
+
+	var message string
+	if event.Event[0] == '"' {
+		if err := json.Unmarshal(event.Event, &message); err != nil {
+			return nil, HECResponse{Text: "Invalid data format", Code: 6}
+		}
+	} else {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, event.Event); err != nil {
+			return nil, HECResponse{Text: "Invalid data format", Code: 6}
+		}
+		message = compacted.String()
+	}
+	if strings.TrimSpace(message) == "" {
+		return nil, HECResponse{Text: "Event field cannot be blank", Code: 13}
+	}
+
+	timestamp := time.Now()
+	if event.Time != "" {
+		seconds, err := strconv.ParseFloat(event.Time.String(), 64)
+		if err != nil || seconds < 0 {
+			return nil, HECResponse{Text: "Invalid data format", Code: 6}
+		}
+		timestamp = time.UnixMicro(int64(math.Round(seconds * 1e6)))
+	}
+
+	appName := event.SourceType
+	if appName == "" {
+		appName = event.Source
+	}
+
+	entry := &models.LogEntry{
+		Severity:  6,
+		Facility:  1,
+		Version:   1,
+		Timestamp: timestamp,
+		Hostname:  formats.NormalizeCase("hostname", defaultDash(event.Host)),
+		AppName:   formats.NormalizeCase("appName", defaultDash(appName)),
+		ProcID:    "-",
+		MsgID:     "-",
+		Message:   formats.StripMessage(message),
+	}
+
+	structuredData := map[string]map[string]string{}
+	metadata := map[string]string{}
+	for name, value := range map[string]string{"source": event.Source, "index": event.Index} {
+		if value != "" {
+			metadata[name] = value
+		}
+	}
+	if len(metadata) > 0 {
+		structuredData["hec"] = metadata
+	}
+	if len(event.Fields) > 0 {
+		fields := make(map[string]string, len(event.Fields))
+		for name, value := range event.Fields {
+			fields[name] = fmt.Sprint(value)
+		}
+		structuredData["fields"] = fields
+	}
+	if len(structuredData) > 0 {
+		encoded, err := json.Marshal(structuredData)
+		if err != nil {
+			return nil, HECResponse{Text: "Invalid data format", Code: 6}
+		}
+		entry.StructuredData = string(encoded)
+	}
+
+	return entry, HECResponse{}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/db"
+)
+
+// IDRangeHandler handles the endpoint returning the lowest and highest ids of the logs matching the filters
+// Exports split that range into fromId and toId chunks of the logs endpoint, to read them in parallel and resume
+func IDRangeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	filters, _ := parseFilters(r.URL.Query(), addInvalidParam)
+
+	// Chunks computed from ignored parameters would silently export other logs
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	idRange, err := db.GetIDRange(filters)
+	if err != nil {
+		log.Printf("Error fetching id range: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(idRange); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
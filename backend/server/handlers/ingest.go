@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sloggo/db"
+	"sloggo/formats"
+	"sloggo/models"
+	"strings"
+	"time"
+)
+
+// IngestEntry represents a single NDJSON line accepted by the ingest endpoint
+type IngestEntry struct {
+	Severity       *uint8                       `json:"severity"`
+	Facility       *uint8                       `json:"facility"`
+	Timestamp      *time.Time                   `json:"timestamp"`
+	Hostname       string                       `json:"hostname"`
+	AppName        string                       `json:"appName"`
+	ProcID         string                       `json:"procId"`
+	MsgID          string                       `json:"msgId"`
+	Message        string                       `json:"message"`
+	StructuredData map[string]map[string]string `json:"structuredData"`
+}
+
+// IngestResponse reports how many lines of the stream were stored
+type IngestResponse struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// eventTimestampHeader holds the event time of the lines without a timestamp, for forwarders uploading batches late
+const eventTimestampHeader = "X-Event-Timestamp"
+
+// ingestReportInterval controls how often long-lived streams log their progress
+var ingestReportInterval = 30 * time.Second
+
+// IngestHandler handles the ingestion of NDJSON logs over HTTP
+// The body is read incrementally so a single long-lived connection can stream logs continuously,
+// each line is added to the batch as soon as it arrives
+func IngestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	table, ok := parseTableParam(w, r)
+	if !ok {
+		return
+	}
+
+	// The header must be a valid RFC3339 timestamp, guessing a delayed upload's event time would corrupt it
+	var eventTimestamp time.Time
+	if header := r.Header.Get(eventTimestampHeader); header != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, header)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", eventTimestampHeader+" must be an RFC3339 timestamp, such as 2024-06-01T12:00:00Z")
+			return
+		}
+		eventTimestamp = parsed
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+
+	// Configure scanner with a larger buffer for bigger messages
+	const maxScanSize = 1024 * 1024 // 1MB max line size
+	buffer := make([]byte, 0, 64*1024)
+	scanner.Buffer(buffer, maxScanSize)
+
+	response := IngestResponse{}
+	lastReport := time.Now()
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseIngestLine(line, false, eventTimestamp)
+		if err == nil {
+			entry.Table = table
+		}
+
+		if err != nil {
+			response.Rejected++
+			log.Printf("Rejected ingest line: %v", err)
+		} else if err := db.StoreLog(*entry); err != nil {
+			response.Rejected++
+			log.Printf("Error storing ingested log: %v", err)
+		} else {
+			response.Accepted++
+		}
+
+		if time.Since(lastReport) >= ingestReportInterval {
+			log.Printf("Ingest stream from %s: %d accepted, %d rejected so far", r.RemoteAddr, response.Accepted, response.Rejected)
+			lastReport = time.Now()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		// The client went away or sent an oversized line, what was read so far is kept
+		log.Printf("Ingest stream from %s interrupted after %d accepted, %d rejected: %v", r.RemoteAddr, response.Accepted, response.Rejected, err)
+
+		if errors.Is(err, bufio.ErrTooLong) {
+			writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large", "Line too long")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// parseIngestLine converts a single NDJSON line into a log entry
+// Lines without a timestamp are stamped with the default timestamp, the current time when zero,
+// or rejected when a timestamp is required
+func parseIngestLine(line string, requireTimestamp bool, defaultTimestamp time.Time) (*models.LogEntry, error) {
+	var ingestEntry IngestEntry
+	if err := json.Unmarshal([]byte(line), &ingestEntry); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	// Default to user-level informational messages
+	entry := &models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       formats.NormalizeCase("hostname", defaultDash(ingestEntry.Hostname)),
+		AppName:        formats.NormalizeCase("appName", defaultDash(ingestEntry.AppName)),
+		ProcID:         defaultDash(ingestEntry.ProcID),
+		MsgID:          defaultDash(ingestEntry.MsgID),
+		StructuredData: "",
+		Message:        formats.StripMessage(ingestEntry.Message),
+	}
+
+	if ingestEntry.Severity != nil {
+		if *ingestEntry.Severity > 7 {
+			return nil, fmt.Errorf("severity out of range (must be 0-7): %d", *ingestEntry.Severity)
+		}
+		entry.Severity = *ingestEntry.Severity
+	}
+
+	if ingestEntry.Facility != nil {
+		if *ingestEntry.Facility > 23 {
+			return nil, fmt.Errorf("facility out of range (must be 0-23): %d", *ingestEntry.Facility)
+		}
+		entry.Facility = *ingestEntry.Facility
+	}
+
+	if ingestEntry.Timestamp != nil {
+		entry.Timestamp = *ingestEntry.Timestamp
+	} else if requireTimestamp {
+		return nil, errors.New("missing timestamp")
+	} else if !defaultTimestamp.IsZero() {
+		entry.Timestamp = defaultTimestamp
+	}
+
+	if len(ingestEntry.StructuredData) > 0 {
+		structuredData, err := json.Marshal(ingestEntry.StructuredData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid structured data: %v", err)
+		}
+		entry.StructuredData = string(structuredData)
+	}
+
+	return entry, nil
+}
+
+// defaultDash returns the syslog nil value "-" for empty fields
+func defaultDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"sloggo/db"
+	"sloggo/formats"
 	"sloggo/models"
 	"sloggo/utils"
 	"strconv"
@@ -19,6 +22,9 @@ type LogsResponse struct {
 	Meta       InfiniteQueryMeta `json:"meta"`
 	NextCursor *int64            `json:"nextCursor"`
 	PrevCursor *int64            `json:"prevCursor"`
+
+	// NextSinceID is the sinceId of the next incremental read, only set when sinceId is requested
+	NextSinceID *int64 `json:"nextSinceId,omitempty"`
 }
 
 // InfiniteQueryMeta contains metadata for infinite scrolling
@@ -28,9 +34,250 @@ type InfiniteQueryMeta struct {
 	ChartData      []db.ChartDataPoint         `json:"chartData"`
 	Facets         map[string]db.FacetMetadata `json:"facets"`
 	Metadata       map[string]any              `json:"metadata,omitempty"`
+
+	// Warnings lists the parts of the response that couldn't be computed, the logs are returned regardless
+	Warnings []QueryWarning `json:"warnings,omitempty"`
+
+	// HasNext and HasPrev report whether more logs follow the page towards nextCursor and prevCursor
+	HasNext bool `json:"hasNext"`
+	HasPrev bool `json:"hasPrev"`
+
+	// compactFacets encodes the facets as counts keyed by value, requested with facetFormat=compact
+	compactFacets bool
+
+	// DisplayTimezone is the timezone timestamps should be displayed in, only set with SLOGGO_DISPLAY_TIMEZONE
+	DisplayTimezone *DisplayTimezone `json:"displayTimezone,omitempty"`
+}
+
+// MarshalJSON encodes the facets in their compact representation when requested
+func (m InfiniteQueryMeta) MarshalJSON() ([]byte, error) {
+	// The alias type drops the methods to avoid recursing into MarshalJSON
+	type infiniteQueryMeta InfiniteQueryMeta
+
+	if !m.compactFacets {
+		return json.Marshal(infiniteQueryMeta(m))
+	}
+
+	return json.Marshal(struct {
+		infiniteQueryMeta
+		Facets map[string]map[string]int `json:"facets"`
+	}{
+		infiniteQueryMeta: infiniteQueryMeta(m),
+		Facets:            compactFacets(m.Facets),
+	})
+}
+
+// compactFacets maps each facet to the counts of its values, keyed by the value as a string
+func compactFacets(facets map[string]db.FacetMetadata) map[string]map[string]int {
+	compact := make(map[string]map[string]int, len(facets))
+	for key, facet := range facets {
+		counts := make(map[string]int, len(facet.Rows))
+		for _, row := range facet.Rows {
+			counts[fmt.Sprint(row.Value)] = row.Total
+		}
+		compact[key] = counts
+	}
+
+	return compact
+}
+
+// DisplayTimezone describes the configured display timezone, timestamps themselves stay in UTC
+type DisplayTimezone struct {
+	Name          string `json:"name"`          // IANA name, such as Europe/Paris
+	Offset        string `json:"offset"`        // Current UTC offset, such as +02:00
+	OffsetSeconds int    `json:"offsetSeconds"` // Current UTC offset in seconds
+}
+
+// QueryWarning describes a failed subquery of the logs endpoint, the cause is only logged
+type QueryWarning struct {
+	Query   string `json:"query"` // "facets" or "chartData"
+	Message string `json:"message"`
+}
+
+// InvalidParam describes a query parameter that could not be parsed
+type InvalidParam struct {
+	Param  string `json:"param"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// getFacets and getChartData compute the secondary parts of the logs response, replaced in tests to simulate failures
+var (
+	getFacets    = db.GetFacets
+	getChartData = db.GetChartData
+)
+
+// maxFacetLimit bounds the facetLimit parameter
+const maxFacetLimit = 1000
+
+// sortColumns maps the sortable API fields to their database columns
+var sortColumns = map[string]string{
+	"timestamp":  "timestamp",
+	"severity":   "severity",
+	"facility":   "facility",
+	"hostname":   "hostname",
+	"appName":    "app_name",
+	"procId":     "procid",
+	"msgId":      "msgid",
+	"message":    "msg",
+	"receivedAt": "received_at",
+}
+
+// defaultSeverities is the severity filter applied when none is requested, from SLOGGO_DEFAULT_SEVERITY_FILTER
+var defaultSeverities []int
+
+func init() {
+	if utils.DefaultSeverityFilter == "" {
+		return
+	}
+
+	severities, err := parseSeverityList(utils.DefaultSeverityFilter)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_DEFAULT_SEVERITY_FILTER, no default severity filter is applied: %v", err)
+		return
+	}
+
+	defaultSeverities = severities
+}
+
+// defaultWindow bounds the view when no explicit range is requested, from SLOGGO_DEFAULT_WINDOW
+var defaultWindow time.Duration
+
+func init() {
+	if utils.DefaultWindow == "" {
+		return
+	}
+
+	window, err := parseWindow(utils.DefaultWindow)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_DEFAULT_WINDOW, no default window is applied: %v", err)
+		return
+	}
+
+	defaultWindow = window
+}
+
+// displayLocation is the timezone of SLOGGO_DISPLAY_TIMEZONE, nil when unset
+var displayLocation *time.Location
+
+func init() {
+	if utils.DisplayTimezone == "" {
+		return
+	}
+
+	location, err := time.LoadLocation(utils.DisplayTimezone)
+	if err != nil || utils.DisplayTimezone == "Local" {
+		log.Printf("Invalid SLOGGO_DISPLAY_TIMEZONE %q, expected an IANA timezone such as Europe/Paris", utils.DisplayTimezone)
+		return
+	}
+
+	displayLocation = location
+}
+
+// displayTimezone returns the display timezone with its offset at the given time, nil when none is configured
+// The offset changes with daylight saving time, so it's computed for each response
+func displayTimezone(location *time.Location, now time.Time) *DisplayTimezone {
+	if location == nil {
+		return nil
+	}
+
+	_, offset := now.In(location).Zone()
+
+	return &DisplayTimezone{
+		Name:          location.String(),
+		Offset:        now.In(location).Format("-07:00"),
+		OffsetSeconds: offset,
+	}
+}
+
+// parseWindow parses a time window such as "15m", "24h" or "7d", "all" disables the window
+func parseWindow(value string) (time.Duration, error) {
+	if value == "all" {
+		return 0, nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("invalid window %q", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid window %q", value)
+	}
+
+	return window, nil
+}
+
+// parseSeverityList parses a comma-separated list of severities between 0 and 7
+func parseSeverityList(config string) ([]int, error) {
+	severities := []int{}
+
+	for value := range strings.SplitSeq(config, ",") {
+		severity, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || severity < 0 || severity > 7 {
+			return nil, fmt.Errorf("invalid severity %q (must be 0-7)", value)
+		}
+		severities = append(severities, severity)
+	}
+
+	return severities, nil
+}
+
+// hasSeverityParam reports whether the query selects severities, as a list or a range
+func hasSeverityParam(query url.Values) bool {
+	for _, param := range []string{"severity", "minSeverity", "maxSeverity"} {
+		if _, ok := query[param]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSort parses the "field.order" sort parameter into a database column and order, newest first by default
+func parseSort(query url.Values, addInvalidParam func(param string, value string, reason string)) (string, string) {
+	sortField := "timestamp"
+	sortOrder := "DESC"
+
+	if sortStr := query.Get("sort"); sortStr != "" {
+		sortParts := strings.Split(sortStr, ".")
+
+		if len(sortParts) == 2 {
+			if column, ok := sortColumns[sortParts[0]]; ok {
+				sortField = column
+			} else {
+				addInvalidParam("sort", sortStr, "unknown sort field")
+			}
+
+			if sortParts[1] == "asc" {
+				sortOrder = "ASC"
+			} else if sortParts[1] != "desc" {
+				addInvalidParam("sort", sortStr, "order must be asc or desc")
+			}
+		} else {
+			addInvalidParam("sort", sortStr, "must be formatted as field.order")
+		}
+	}
+
+	return sortField, sortOrder
 }
 
 // LogsHandler handles the API endpoint for logs
+//
+// With direction=tail the endpoint can be polled to follow new logs:
+// rows strictly newer than the cursor millisecond are returned oldest first,
+// and nextCursor is the cursor to send with the next poll (unchanged when nothing is new).
+// The cursor defaults to the current time and never goes past it.
+// A full page never splits a millisecond, so no row is skipped between polls,
+// unless a single millisecond holds more rows than the page size: the rest of that millisecond is skipped.
+//
+// With sinceId the rows stored after that id are returned in insertion order,
+// and nextSinceId is the sinceId to send with the next read.
+// Ids are row ids, a cleanup or retention deleting logs may renumber them and make the next read skip or repeat rows.
 func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	requestStartTime := time.Now()
 
@@ -46,19 +293,27 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w)
 		return
 	}
 
 	// Parse query parameters
 	query := r.URL.Query()
 
+	// Invalid parameters are ignored, unless strict mode is requested
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
 	// Pagination parameters
 	size := 50
 
 	if sizeStr := query.Get("size"); sizeStr != "" {
 		if parsedSize, err := strconv.Atoi(sizeStr); err == nil && parsedSize > 0 {
 			size = parsedSize
+		} else {
+			addInvalidParam("size", sizeStr, "must be a positive integer")
 		}
 	}
 
@@ -66,65 +321,72 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	direction := query.Get("direction")
 	if direction == "" {
 		direction = "next"
-	} else if direction != "next" && direction != "prev" {
+	} else if direction != "next" && direction != "prev" && direction != "tail" {
+		addInvalidParam("direction", direction, "must be next, prev or tail")
 		direction = "next"
 	}
 
-	// Filters
-	filters := make(map[string]any)
-
-	// Hostname filter
-	if hostname := query.Get("hostname"); hostname != "" {
-		filters["hostname"] = hostname
-	}
-
-	// App name filter
-	if appName := query.Get("appName"); appName != "" {
-		filters["appName"] = appName
+	// Chart mode, absolute counts per bucket or the difference from the previous bucket
+	chartMode := query.Get("chartMode")
+	if chartMode == "" {
+		chartMode = "absolute"
+	} else if chartMode != "absolute" && chartMode != "delta" {
+		addInvalidParam("chartMode", chartMode, "must be absolute or delta")
+		chartMode = "absolute"
 	}
 
-	// Process ID filter
-	if procId := query.Get("procId"); procId != "" {
-		filters["procId"] = procId
-	}
-
-	// Message ID filter
-	if msgId := query.Get("msgId"); msgId != "" {
-		filters["msgId"] = msgId
+	// Chart series to compute, all severities by default
+	var chartSeverities []int
+	if chartSeveritiesStr := query.Get("chartSeverities"); chartSeveritiesStr != "" {
+		for value := range strings.SplitSeq(chartSeveritiesStr, ",") {
+			if severity, err := formats.ParseSeverity(value); err == nil {
+				chartSeverities = append(chartSeverities, int(severity))
+			} else {
+				addInvalidParam("chartSeverities", value, "must be a severity between 0 and 7 or a level name")
+			}
+		}
 	}
 
-	// Facility filter
-	if facilityStr := query.Get("facility"); facilityStr != "" {
-		facilityValues := strings.Split(facilityStr, ",")
-		facilities := make([]int, 0, len(facilityValues))
-
-		for _, v := range facilityValues {
-			if facility, err := strconv.Atoi(v); err == nil {
-				facilities = append(facilities, facility)
-			}
+	// Number of values of the high-cardinality facets, 0 uses SLOGGO_FACET_LIMIT
+	facetLimit := 0
+	if facetLimitStr := query.Get("facetLimit"); facetLimitStr != "" {
+		if parsedLimit, err := strconv.Atoi(facetLimitStr); err == nil && parsedLimit > 0 && parsedLimit <= maxFacetLimit {
+			facetLimit = parsedLimit
+		} else {
+			addInvalidParam("facetLimit", facetLimitStr, "must be an integer between 1 and "+strconv.Itoa(maxFacetLimit))
 		}
+	}
 
-		if len(facilities) > 0 {
-			filters["facility"] = facilities
+	// Facet values are sorted by descending count by default
+	facetSort := db.FacetSortCount
+	if facetSortStr := query.Get("facetSort"); facetSortStr != "" {
+		if facetSortStr == db.FacetSortCount || facetSortStr == db.FacetSortValue {
+			facetSort = facetSortStr
+		} else {
+			addInvalidParam("facetSort", facetSortStr, "must be count or value")
 		}
 	}
 
-	// Severity filter
-	if severityStr := query.Get("severity"); severityStr != "" {
-		severityValues := strings.Split(severityStr, ",")
-		severities := make([]int, 0, len(severityValues))
+	// Facets are arrays of value and total rows by default, or counts keyed by value
+	compact := false
+	if facetFormat := query.Get("facetFormat"); facetFormat == "compact" {
+		compact = true
+	} else if facetFormat != "" && facetFormat != "array" {
+		addInvalidParam("facetFormat", facetFormat, "must be array or compact")
+	}
 
-		for _, v := range severityValues {
-			if severity, err := strconv.Atoi(v); err == nil {
-				severities = append(severities, severity)
-			}
-		}
+	// Filters
+	filters, rejectInvalidParams := parseFilters(query, addInvalidParam)
+	rejectInvalidParams = rejectInvalidParams || query.Get("strict") == "true"
 
-		if len(severities) > 0 {
-			filters["severity"] = severities
-		}
+	// Apply the default severities when no severity parameter is present, an explicit empty value shows all severities
+	if !hasSeverityParam(query) && len(defaultSeverities) > 0 {
+		filters["severity"] = defaultSeverities
 	}
 
+	// Time window bounding the view when no explicit range is requested, "all" disables the default window
+	window := parseWindowParam(query, addInvalidParam)
+
 	// Parse cursor (timestamp) for pagination
 	var cursor time.Time
 	now := time.Now().UTC().Add(1 * time.Minute) // Allow for clock skew
@@ -139,6 +401,7 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		} else {
 			// Use current time if parsing fails
+			addInvalidParam("cursor", cursorStr, "must be a timestamp in milliseconds")
 			cursor = now
 		}
 	} else {
@@ -146,33 +409,36 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 		cursor = now
 	}
 
-	// Date range filter
-	if dateStr := query.Get("timestamp"); dateStr != "" {
-		dateValues := strings.Split(dateStr, "-")
+	// Tail polls echo the cursor back when nothing is new, a future cursor would skip the logs stored until then
+	if realNow := time.Now().UTC(); direction == "tail" && cursor.After(realNow) {
+		cursor = realNow
+	}
 
-		if len(dateValues) == 2 {
-			startMillis, startErr := strconv.ParseInt(dateValues[0], 10, 64)
-			endMillis, endErr := strconv.ParseInt(dateValues[1], 10, 64)
+	// Sort parameter
+	sortField, sortOrder := parseSort(query, addInvalidParam)
 
-			if startErr == nil && endErr == nil {
-				filters["startDate"] = time.Unix(0, startMillis*int64(time.Millisecond))
-				filters["endDate"] = time.Unix(0, endMillis*int64(time.Millisecond))
-			}
-		}
+	if rejectInvalidParams && len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
 	}
 
-	// Sort parameter
-	sortField := "timestamp"
-	sortOrder := "DESC"
+	// Incremental reads page by id from sinceId instead of the timestamp cursor
+	sinceId, incremental := filters["sinceId"].(int64)
+	logsCursor, logsDirection := cursor, direction
+	if incremental {
+		logsCursor, logsDirection = time.Time{}, "next"
+	}
 
-	if sortStr := query.Get("sort"); sortStr != "" {
-		sortParts := strings.Split(sortStr, ".")
+	// The window starts from now rather than the cursor, the cursor still pages through it
+	// Incremental reads and id ranges are not windowed, a late log would be skipped otherwise
+	var windowStart time.Time
+	explicitRange := hasExplicitRange(filters)
+	idRange := filters["fromId"] != nil || filters["toId"] != nil
+	if !incremental && !idRange {
+		windowStart = windowStartOf(filters, window, now)
 
-		if len(sortParts) == 2 {
-			sortField = sortParts[0]
-			if sortParts[1] == "asc" {
-				sortOrder = "ASC"
-			}
+		if !windowStart.IsZero() && utils.DefaultWindowRows {
+			filters["startDate"] = windowStart
 		}
 	}
 
@@ -180,19 +446,29 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	var wg sync.WaitGroup
 	var logs []models.LogEntry
 	var totalCount, filterCount int
+	var hasMore, hasBehind bool
 	var facets map[string]db.FacetMetadata
 	var chartData []db.ChartDataPoint
 	var logsErr, facetsErr, chartErr error
 
 	wg.Add(3)
 
+	// Closed once the logs are fetched, the chart may depend on them
+	logsDone := make(chan struct{})
+
 	// Time for all database operations
 	queryStartTime := time.Now()
 
 	// Get logs from database
 	go func() {
 		defer wg.Done()
-		logs, totalCount, filterCount, logsErr = db.GetLogs(size, cursor, direction, filters, sortField, sortOrder)
+		defer close(logsDone)
+		logs, totalCount, filterCount, hasMore, logsErr = db.GetLogsPage(size, logsCursor, logsDirection, filters, sortField, sortOrder)
+
+		// The first page has nothing before it, later ones look up a single row on the other side of the cursor
+		if logsErr == nil && (query.Get("cursor") != "" || sinceId > 0) {
+			hasBehind, logsErr = db.HasLogsBehind(logsCursor, logsDirection, filters)
+		}
 
 		if utils.Debug {
 			log.Printf("⚡ GetLogs execution time: %v", time.Since(queryStartTime))
@@ -202,7 +478,7 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get facets for filtering
 	go func() {
 		defer wg.Done()
-		facets, facetsErr = db.GetFacets(filters)
+		facets, facetsErr = getFacets(filters, facetLimit, facetSort)
 
 		if utils.Debug {
 			log.Printf("⚡ GetFacets execution time: %v", time.Since(queryStartTime))
@@ -212,7 +488,23 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get chart data
 	go func() {
 		defer wg.Done()
-		chartData, chartErr = db.GetChartData(cursor, filters)
+
+		// The chart follows the explicit date filter when present, then the time window,
+		// otherwise the time span of the returned page so both stay aligned
+		chartFilters := filters
+		if !explicitRange {
+			if !windowStart.IsZero() {
+				chartFilters = withTimeRange(filters, windowStart, now)
+			} else {
+				<-logsDone
+
+				if start, end, ok := pageTimeSpan(logs); ok {
+					chartFilters = withTimeRange(filters, start, end)
+				}
+			}
+		}
+
+		chartData, chartErr = getChartData(cursor, chartFilters, chartMode, chartSeverities)
 
 		if utils.Debug {
 			log.Printf("⚡️ GetChartData execution time: %v", time.Since(queryStartTime))
@@ -227,42 +519,32 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Check for errors
 	if logsErr != nil {
 		log.Printf("Error fetching logs: %v", logsErr)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 
+	// Facets and chart data are secondary, the logs are returned without them when they fail
+	var warnings []QueryWarning
 	if facetsErr != nil {
 		log.Printf("Error fetching facets: %v", facetsErr)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		facets = map[string]db.FacetMetadata{}
+		warnings = append(warnings, QueryWarning{Query: "facets", Message: "Facets could not be computed"})
 	}
 
 	if chartErr != nil {
 		log.Printf("Error fetching chart data: %v", chartErr)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		chartData = []db.ChartDataPoint{}
+		warnings = append(warnings, QueryWarning{Query: "chartData", Message: "Chart data could not be computed"})
 	}
 
 	// Process logs for API response format
 	processStartTime := time.Now()
-	for i := range logs {
-		// Parse structured data JSON if present
-		structData := make(map[string]map[string]string)
+	prepareLogs(logs)
 
-		if logs[i].StructuredData != "" && logs[i].StructuredData != "-" {
-			// Attempt to parse the JSON data
-			if err := json.Unmarshal([]byte(logs[i].StructuredData), &structData); err != nil {
-				log.Printf("Error parsing structured data for row %d", logs[i].RowID)
-			}
-		}
-
-		// Calculate priority
-		logs[i].ParsedStructuredData = structData
-
-		// Ensure timestamp is properly formatted for JavaScript to parse
-		// This is already handled by Go's JSON marshaller, but making it explicit
-		if logs[i].Timestamp.IsZero() {
-			logs[i].Timestamp = time.Now()
+	// The list view doesn't show structured data, lite responses leave it out to save bandwidth
+	if query.Get("lite") == "true" {
+		for i := range logs {
+			logs[i].ParsedStructuredData = nil
 		}
 	}
 
@@ -276,6 +558,25 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 		prevVal := logs[0].Timestamp.UnixNano() / int64(time.Millisecond)
 		nextCursor = &nextVal
 		prevCursor = &prevVal
+	} else if direction == "tail" {
+		// Nothing new yet, the client keeps polling with the same cursor
+		nextVal := cursor.UnixNano() / int64(time.Millisecond)
+		nextCursor = &nextVal
+	}
+
+	// Previous pages are newer logs, or older ones for tail and incremental reads
+	hasNext, hasPrev := hasMore, hasBehind
+	if direction == "prev" && !incremental {
+		hasNext, hasPrev = hasBehind, hasMore
+	}
+
+	// The next incremental read starts after the last returned id, or the same one when nothing is new
+	var nextSinceID *int64
+	if incremental {
+		for _, entry := range logs {
+			sinceId = max(sinceId, entry.RowID)
+		}
+		nextSinceID = &sinceId
 	}
 
 	// Prepare the response
@@ -283,14 +584,20 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 	response := LogsResponse{
 		Data: logs,
 		Meta: InfiniteQueryMeta{
-			TotalRowCount:  totalCount,
-			FilterRowCount: filterCount,
-			ChartData:      chartData,
-			Facets:         facets,
-			Metadata:       map[string]any{},
+			TotalRowCount:   totalCount,
+			FilterRowCount:  filterCount,
+			ChartData:       chartData,
+			Facets:          facets,
+			Metadata:        map[string]any{},
+			Warnings:        warnings,
+			HasNext:         hasNext,
+			HasPrev:         hasPrev,
+			compactFacets:   compact,
+			DisplayTimezone: displayTimezone(displayLocation, time.Now()),
 		},
-		NextCursor: nextCursor,
-		PrevCursor: prevCursor,
+		NextCursor:  nextCursor,
+		PrevCursor:  prevCursor,
+		NextSinceID: nextSinceID,
 	}
 
 	if utils.Debug {
@@ -302,9 +609,9 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Send the response to the client
 	encodeStartTime := time.Now()
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := newEncoder(w, r).Encode(compatResponse(response)); err != nil {
 		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeInternalError(w)
 		return
 	}
 
@@ -313,3 +620,57 @@ func LogsHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("⚡️ Total request handling time: %v\n\n", time.Since(requestStartTime))
 	}
 }
+
+// pageTimeSpan returns the oldest and newest timestamps of the returned logs
+func pageTimeSpan(logs []models.LogEntry) (time.Time, time.Time, bool) {
+	if len(logs) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start, end := logs[0].Timestamp, logs[0].Timestamp
+	for _, entry := range logs[1:] {
+		if entry.Timestamp.Before(start) {
+			start = entry.Timestamp
+		}
+		if entry.Timestamp.After(end) {
+			end = entry.Timestamp
+		}
+	}
+
+	return start, end, true
+}
+
+// withTimeRange returns a copy of the filters restricted to the given time range
+func withTimeRange(filters map[string]any, start time.Time, end time.Time) map[string]any {
+	rangeFilters := make(map[string]any, len(filters)+2)
+	for k, v := range filters {
+		rangeFilters[k] = v
+	}
+	rangeFilters["startDate"] = start
+	rangeFilters["endDate"] = end
+
+	return rangeFilters
+}
+
+// prepareLogs fills the derived fields of log entries for API responses
+func prepareLogs(logs []models.LogEntry) {
+	for i := range logs {
+		// Parse structured data JSON if present
+		structData := make(map[string]map[string]string)
+
+		if logs[i].StructuredData != "" {
+			// Attempt to parse the JSON data
+			if err := json.Unmarshal([]byte(logs[i].StructuredData), &structData); err != nil {
+				log.Printf("Error parsing structured data for row %d", logs[i].RowID)
+			}
+		}
+
+		logs[i].ParsedStructuredData = structData
+
+		// Ensure timestamp is properly formatted for JavaScript to parse
+		// This is already handled by Go's JSON marshaller, but making it explicit
+		if logs[i].Timestamp.IsZero() {
+			logs[i].Timestamp = time.Now()
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sloggo/db"
+	"sloggo/models"
+	"sloggo/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSeverityList(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      string
+		expected    int
+		shouldError bool
+	}{
+		{"all but debug", "0,1,2,3,4,5,6", 7, false},
+		{"spaces", "3, 4", 2, false},
+		{"out of range", "3,8", 0, true},
+		{"not a number", "error", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			severities, err := parseSeverityList(tc.config)
+			if tc.shouldError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(severities) != tc.expected {
+				t.Errorf("expected %d severities, got %d", tc.expected, len(severities))
+			}
+		})
+	}
+}
+
+func TestDefaultSeverityFilter(t *testing.T) {
+	originalDefault := defaultSeverities
+	defer func() {
+		defaultSeverities = originalDefault
+	}()
+	defaultSeverities = []int{0, 1, 2, 3, 4, 5, 6}
+
+	for i, severity := range []uint8{6, 7} {
+		err := db.StoreLog(models.LogEntry{
+			Severity:       severity,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now().Add(-time.Minute),
+			Hostname:       "default-severity-host",
+			AppName:        "default-severity-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        "Default severity message " + string(rune('a'+i)),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"default hides debug", "", 1},
+		{"explicit severity overrides default", "&severity=7", 1},
+		{"explicit empty severity shows all", "&severity=", 2},
+		{"severity range overrides default", "&minSeverity=debug", 1},
+		{"severity range includes debug", "&maxSeverity=7", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/logs?hostname=default-severity-host"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			LogsHandler(w, req)
+
+			var result struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if len(result.Data) != tc.expected {
+				t.Errorf("Expected %d logs, got %d", tc.expected, len(result.Data))
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expected    time.Duration
+		shouldError bool
+	}{
+		{value: "15m", expected: 15 * time.Minute},
+		{value: "24h", expected: 24 * time.Hour},
+		{value: "7d", expected: 7 * 24 * time.Hour},
+		{value: "all", expected: 0},
+		{value: "0s", shouldError: true},
+		{value: "-1h", shouldError: true},
+		{value: "0d", shouldError: true},
+		{value: "week", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			window, err := parseWindow(tc.value)
+			if tc.shouldError {
+				if err == nil {
+					t.Errorf("expected error, got %v", window)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if window != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, window)
+			}
+		})
+	}
+}
+
+func TestWindowStartOf(t *testing.T) {
+	now := time.Now().UTC()
+	rangeFilters := map[string]any{"startDate": now.Add(-time.Minute), "endDate": now}
+
+	if start := windowStartOf(map[string]any{}, time.Hour, now); !start.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Expected the window to start an hour ago, got %v", start)
+	}
+	if start := windowStartOf(map[string]any{}, 0, now); !start.IsZero() {
+		t.Errorf("Expected no window start with window=all, got %v", start)
+	}
+	if start := windowStartOf(rangeFilters, time.Hour, now); !start.IsZero() {
+		t.Errorf("Expected the explicit range to take precedence, got %v", start)
+	}
+}
+
+func TestDefaultWindow(t *testing.T) {
+	originalWindow, originalRows := defaultWindow, utils.DefaultWindowRows
+	defer func() {
+		defaultWindow, utils.DefaultWindowRows = originalWindow, originalRows
+	}()
+	defaultWindow, utils.DefaultWindowRows = time.Hour, true
+
+	for i, age := range []time.Duration{10 * time.Minute, 2 * time.Hour} {
+		err := db.StoreLog(models.LogEntry{
+			Severity:       6,
+			Facility:       1,
+			Version:        1,
+			Timestamp:      time.Now().Add(-age),
+			Hostname:       "default-window-host",
+			AppName:        "default-window-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        "Default window message " + string(rune('a'+i)),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	explicitRange := fmt.Sprintf("&timestamp=%d-%d", time.Now().Add(-3*time.Hour).UnixMilli(), time.Now().UnixMilli())
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected int
+	}{
+		{"default window hides older logs", "", 1},
+		{"window parameter overrides default", "&window=3h", 2},
+		{"window all disables default", "&window=all", 2},
+		{"explicit range overrides window", explicitRange, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/logs?hostname=default-window-host"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			LogsHandler(w, req)
+
+			var result struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if len(result.Data) != tc.expected {
+				t.Errorf("Expected %d logs, got %d", tc.expected, len(result.Data))
+			}
+		})
+	}
+}
+
+func TestPartialResultsOnSubqueryFailure(t *testing.T) {
+	originalGetFacets := getFacets
+	defer func() { getFacets = originalGetFacets }()
+	getFacets = func(map[string]any, int, string) (map[string]db.FacetMetadata, error) {
+		return nil, errors.New("facets timed out")
+	}
+
+	err := db.StoreLog(models.LogEntry{
+		Severity:       3,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "partial-host",
+		AppName:        "partial-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Partial results message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/logs?hostname=partial-host", nil)
+	w := httptest.NewRecorder()
+
+	LogsHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 when only the facets fail, got %d", w.Code)
+	}
+
+	var result LogsResponse
+	if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(result.Data) != 1 {
+		t.Errorf("Expected the logs to be returned, got %d", len(result.Data))
+	}
+	if result.Meta.Facets == nil || result.Meta.ChartData == nil {
+		t.Error("Expected the facets and chart data to be present, empty when failed")
+	}
+	if len(result.Meta.Warnings) != 1 || result.Meta.Warnings[0].Query != "facets" {
+		t.Errorf("Expected a single facets warning, got %+v", result.Meta.Warnings)
+	}
+}
+
+func TestUnknownTableRejected(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/logs?table=logs_missing", nil)
+	w := httptest.NewRecorder()
+
+	LogsHandler(w, req)
+
+	if w.Code != 400 {
+		t.Fatalf("Expected status 400 for an unknown table, got %d", w.Code)
+	}
+
+	var result ErrorResponse
+	if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(result.Error.Params) != 1 || result.Error.Params[0].Param != "table" {
+		t.Errorf("Expected the table parameter to be reported, got %+v", result.Error.Params)
+	}
+
+	req = httptest.NewRequest("POST", "/api/ingest?table=logs_missing", strings.NewReader("{}\n"))
+	w = httptest.NewRecorder()
+
+	IngestHandler(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("Expected status 400 ingesting into an unknown table, got %d", w.Code)
+	}
+}
+
+func TestLiteLogsOmitStructuredData(t *testing.T) {
+	err := db.StoreLog(models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "lite-host",
+		AppName:        "lite-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: `{"request":{"id":"lite-1"}}`,
+		Message:        "Lite message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	for _, lite := range []bool{false, true} {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/logs?hostname=lite-host&lite=%t", lite), nil)
+		w := httptest.NewRecorder()
+
+		LogsHandler(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var result struct {
+			Data []map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		if len(result.Data) != 1 {
+			t.Fatalf("Expected 1 log, got %d", len(result.Data))
+		}
+
+		if _, ok := result.Data[0]["structuredData"]; ok == lite {
+			t.Errorf("With lite=%t, expected structuredData to be present: %t, got %s", lite, !lite, result.Data[0]["structuredData"])
+		}
+		if _, ok := result.Data[0]["message"]; !ok {
+			t.Errorf("With lite=%t, expected the message to be present", lite)
+		}
+	}
+}
+
+func TestDisplayTimezone(t *testing.T) {
+	if tz := displayTimezone(nil, time.Now()); tz != nil {
+		t.Errorf("Expected no display timezone when unset, got %+v", tz)
+	}
+
+	location, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("Timezone database unavailable: %v", err)
+	}
+
+	// The offset follows daylight saving time
+	summer := displayTimezone(location, time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC))
+	if summer.Name != "Europe/Paris" || summer.Offset != "+02:00" || summer.OffsetSeconds != 7200 {
+		t.Errorf("Unexpected summer display timezone: %+v", summer)
+	}
+
+	winter := displayTimezone(location, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	if winter.Offset != "+01:00" || winter.OffsetSeconds != 3600 {
+		t.Errorf("Unexpected winter display timezone: %+v", winter)
+	}
+}
+
+func TestCompactFacets(t *testing.T) {
+	meta := InfiniteQueryMeta{
+		Facets: map[string]db.FacetMetadata{
+			"severity": {Rows: []db.FacetRow{{Value: 3, Total: 120}, {Value: 6, Total: 4000}}},
+			"hostname": {Rows: []db.FacetRow{{Value: "web-1", Total: 7}}, Approximate: true},
+		},
+	}
+
+	// Rows remain the default representation
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatalf("Failed to encode meta: %v", err)
+	}
+
+	var rows struct {
+		Facets map[string]db.FacetMetadata `json:"facets"`
+	}
+	if err := json.Unmarshal(encoded, &rows); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(rows.Facets["severity"].Rows) != 2 || !rows.Facets["hostname"].Approximate {
+		t.Errorf("Expected the facet rows, got %s", encoded)
+	}
+
+	meta.compactFacets = true
+	encoded, err = json.Marshal(meta)
+	if err != nil {
+		t.Fatalf("Failed to encode meta: %v", err)
+	}
+
+	var compact struct {
+		Facets        map[string]map[string]int `json:"facets"`
+		TotalRowCount *int                      `json:"totalRowCount"`
+	}
+	if err := json.Unmarshal(encoded, &compact); err != nil {
+		t.Fatalf("Invalid compact JSON: %s", encoded)
+	}
+
+	if compact.Facets["severity"]["3"] != 120 || compact.Facets["severity"]["6"] != 4000 || compact.Facets["hostname"]["web-1"] != 7 {
+		t.Errorf("Unexpected compact facets: %s", encoded)
+	}
+	if compact.TotalRowCount == nil {
+		t.Errorf("Expected the other meta fields to be kept, got %s", encoded)
+	}
+}
+
+func TestTailCursorNeverInFuture(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixMilli()
+
+	for _, path := range []string{
+		"/api/logs?direction=tail&hostname=tail-nothing-host",
+		fmt.Sprintf("/api/logs?direction=tail&hostname=tail-nothing-host&cursor=%d", future),
+	} {
+		before := time.Now().UnixMilli()
+
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+
+		LogsHandler(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+		}
+
+		var result LogsResponse
+		if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+			t.Fatalf("%s: invalid JSON response: %v", path, err)
+		}
+
+		// Nothing is new, the cursor is echoed back at the current time rather than ahead of it
+		if result.NextCursor == nil {
+			t.Fatalf("%s: expected a next cursor", path)
+		}
+		if *result.NextCursor < before || *result.NextCursor > time.Now().UnixMilli() {
+			t.Errorf("%s: expected the next cursor to be the current time, got %d", path, *result.NextCursor)
+		}
+	}
+}
+
+func TestSearchStructuredData(t *testing.T) {
+	err := db.StoreLog(models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now(),
+		Hostname:       "search-fields-host",
+		AppName:        "search-fields-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: `{"order":{"id":"order-7731"}}`,
+		Message:        "Order shipped",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	testCases := []struct {
+		searchFields string
+		expected     int
+	}{
+		{"", 0},
+		{"msg,structuredData", 1},
+		{"message,structuredData", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.searchFields, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/logs?strict=true&hostname=search-fields-host&search=order-7731&searchFields="+tc.searchFields, nil)
+			w := httptest.NewRecorder()
+
+			LogsHandler(w, req)
+
+			if w.Code != 200 {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var result struct {
+				Data []json.RawMessage `json:"data"`
+			}
+			if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if len(result.Data) != tc.expected {
+				t.Errorf("Expected %d logs, got %d", tc.expected, len(result.Data))
+			}
+		})
+	}
+}
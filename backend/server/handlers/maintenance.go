@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sloggo/db"
+	"sync"
+	"time"
+)
+
+// CompactionStatus reports the state of the last compaction
+type CompactionStatus struct {
+	Status     string     `json:"status"` // idle, running, done or failed
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+var (
+	compactionMutex  sync.Mutex
+	compactionStatus = CompactionStatus{Status: "idle"}
+)
+
+// CompactHandler handles the compaction endpoint
+// POST starts a compaction in the background and responds immediately, GET reports its status
+func CompactHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		compactionMutex.Lock()
+		status := compactionStatus
+		compactionMutex.Unlock()
+
+		writeCompactionStatus(w, http.StatusOK, status)
+	case "POST":
+		compactionMutex.Lock()
+		if compactionStatus.Status == "running" {
+			compactionMutex.Unlock()
+
+			writeError(w, http.StatusConflict, "conflict", "A compaction is already running")
+			return
+		}
+
+		startedAt := time.Now().UTC()
+		compactionStatus = CompactionStatus{Status: "running", StartedAt: &startedAt}
+		status := compactionStatus
+		compactionMutex.Unlock()
+
+		go runCompaction(startedAt)
+
+		writeCompactionStatus(w, http.StatusAccepted, status)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// runCompaction compacts the database and records the outcome
+func runCompaction(startedAt time.Time) {
+	err := db.Compact()
+	finishedAt := time.Now().UTC()
+
+	status := CompactionStatus{Status: "done", StartedAt: &startedAt, FinishedAt: &finishedAt}
+	if err != nil {
+		log.Printf("Error compacting database: %v", err)
+		status.Status = "failed"
+		status.Error = err.Error()
+	}
+
+	compactionMutex.Lock()
+	compactionStatus = status
+	compactionMutex.Unlock()
+}
+
+// writeCompactionStatus encodes the compaction status with the given status code
+func writeCompactionStatus(w http.ResponseWriter, statusCode int, status CompactionStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// ImportRequest is the body of the Parquet import endpoint
+type ImportRequest struct {
+	Path string `json:"path"` // Parquet file on the server
+}
+
+// ImportResponse reports the outcome of a Parquet import
+type ImportResponse struct {
+	Imported   int64 `json:"imported"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// ImportHandler handles the Parquet import endpoint, loading an archived Parquet file back into the database
+func ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	var request ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Path == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Expected a JSON body with the path of a Parquet file")
+		return
+	}
+
+	startTime := time.Now()
+	imported, err := db.ImportParquet(request.Path)
+	if err != nil {
+		log.Printf("Error importing %s: %v", request.Path, err)
+		writeError(w, http.StatusUnprocessableEntity, "import_failed", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := ImportResponse{Imported: imported, DurationMs: time.Since(startTime).Milliseconds()}
+	if err := newEncoder(w, r).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sloggo/db"
+	"time"
+)
+
+// severityLevels names the syslog severities, indexed by severity
+var severityLevels = [8]string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// SeverityShare represents the logs of one severity in the severity distribution
+type SeverityShare struct {
+	Severity int     `json:"severity"`
+	Level    string  `json:"level"`
+	Count    int     `json:"count"`
+	Percent  float64 `json:"percent"` // Share of the total, rounded to 2 decimals
+}
+
+// SeverityDistributionResponse represents the API response format for the severity distribution
+type SeverityDistributionResponse struct {
+	Total int             `json:"total"`
+	Data  []SeverityShare `json:"data"`
+}
+
+// SeverityDistributionHandler handles the endpoint returning the logs per severity, ready for a pie chart
+// Every severity is listed in order, with the filters and time window of the logs endpoint
+func SeverityDistributionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	invalidParams := []InvalidParam{}
+	addInvalidParam := func(param string, value string, reason string) {
+		invalidParams = append(invalidParams, InvalidParam{Param: param, Value: value, Reason: reason})
+	}
+
+	window := parseWindowParam(query, addInvalidParam)
+
+	filters, _ := parseFilters(query, addInvalidParam)
+
+	if len(invalidParams) > 0 {
+		writeInvalidParams(w, invalidParams)
+		return
+	}
+
+	// Counts over the window the dashboard shows, an explicit timestamp range takes precedence
+	if start := windowStartOf(filters, window, time.Now().UTC()); !start.IsZero() {
+		filters["startDate"] = start
+	}
+
+	rows, err := db.SeverityDistribution(filters)
+	if err != nil {
+		log.Printf("Error fetching severity distribution: %v", err)
+		writeInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(severityDistribution(rows)); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// severityDistribution lists every severity with its count and share of the total
+func severityDistribution(rows []db.FacetRow) SeverityDistributionResponse {
+	response := SeverityDistributionResponse{Data: make([]SeverityShare, len(severityLevels))}
+	for severity, level := range severityLevels {
+		response.Data[severity] = SeverityShare{Severity: severity, Level: level}
+	}
+
+	for _, row := range rows {
+		if severity, ok := row.Value.(int); ok && severity >= 0 && severity < len(severityLevels) {
+			response.Data[severity].Count = row.Total
+			response.Total += row.Total
+		}
+	}
+
+	if response.Total > 0 {
+		for i := range response.Data {
+			percent := float64(response.Data[i].Count) * 100 / float64(response.Total)
+			response.Data[i].Percent = math.Round(percent*100) / 100
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"sloggo/db"
+	"sloggo/models"
+	"testing"
+	"time"
+)
+
+func TestSeverityDistribution(t *testing.T) {
+	response := severityDistribution([]db.FacetRow{{Value: 3, Total: 1}, {Value: 6, Total: 2}})
+
+	if response.Total != 3 || len(response.Data) != 8 {
+		t.Fatalf("Expected 8 severities totalling 3 logs, got %+v", response)
+	}
+	if response.Data[3].Level != "error" || response.Data[3].Count != 1 || response.Data[3].Percent != 33.33 {
+		t.Errorf("Unexpected error share: %+v", response.Data[3])
+	}
+	if response.Data[6].Count != 2 || response.Data[6].Percent != 66.67 {
+		t.Errorf("Unexpected info share: %+v", response.Data[6])
+	}
+	if response.Data[0].Count != 0 || response.Data[0].Percent != 0 {
+		t.Errorf("Expected severities without logs to be listed empty, got %+v", response.Data[0])
+	}
+
+	if empty := severityDistribution(nil); empty.Total != 0 || empty.Data[7].Percent != 0 {
+		t.Errorf("Expected an empty distribution without logs, got %+v", empty)
+	}
+}
+
+func TestSeverityDistributionHandler(t *testing.T) {
+	now := time.Now()
+	for i, severity := range []uint8{4, 4, 4, 2} {
+		err := db.StoreLog(models.LogEntry{
+			Severity:  severity,
+			Facility:  1,
+			Version:   1,
+			Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			Hostname:  "pie-host",
+			AppName:   "pie-app",
+			ProcID:    "-",
+			MsgID:     "-",
+			Message:   "Pie message",
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	// Outside of the window
+	err := db.StoreLog(models.LogEntry{Severity: 7, Facility: 1, Timestamp: now.Add(-48 * time.Hour), Hostname: "pie-host", AppName: "pie-app", Message: "Old"})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/logs/severity-distribution?hostname=pie-host&window=24h", nil)
+	w := httptest.NewRecorder()
+
+	SeverityDistributionHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var result SeverityDistributionResponse
+	if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if result.Total != 4 {
+		t.Errorf("Expected the 4 logs of the window, got %d", result.Total)
+	}
+	if result.Data[4].Percent != 75 || result.Data[2].Percent != 25 || result.Data[7].Count != 0 {
+		t.Errorf("Unexpected distribution: %+v", result.Data)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sloggo/listener"
+)
+
+// SourcesResponse represents the API response format for the source hosts
+type SourcesResponse struct {
+	Data []listener.SourceStats `json:"data"`
+}
+
+// SourcesHandler handles the endpoint reporting the volume received from each source host
+// The counters are kept in memory by the syslog listeners, they restart from zero with the backend
+func SourcesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := newEncoder(w, r).Encode(SourcesResponse{Data: listener.Sources()}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
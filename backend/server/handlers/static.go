@@ -16,7 +16,7 @@ func StaticHandler(staticDir string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Don't serve the index.html for API requests
 		if strings.HasPrefix(r.URL.Path, "/api/") {
-			http.NotFound(w, r)
+			writeError(w, http.StatusNotFound, "not_found", "Endpoint not found")
 			return
 		}
 
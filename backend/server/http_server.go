@@ -1,6 +1,7 @@
 package server
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"net/http/pprof"
@@ -19,10 +20,63 @@ func (s *Server) setupRoutes() {
 
 	// Health check endpoint
 	mux.HandleFunc("/api/health", handlers.HealthHandler)
+	mux.HandleFunc("/api/health/details", handlers.HealthDetailsHandler)
+
+	// Readiness endpoint, for probes gating traffic
+	mux.HandleFunc("/api/ready", handlers.ReadyHandler)
+
+	// Runtime and batching metrics
+	mux.Handle("/api/metrics", expvar.Handler())
 
 	// API endpoint for logs
 	mux.HandleFunc("/api/logs", handlers.LogsHandler)
 
+	// API endpoint describing the log columns
+	mux.HandleFunc("/api/logs/columns", handlers.ColumnsHandler)
+
+	// API endpoint for aggregations over logs
+	mux.HandleFunc("/api/logs/aggregate", handlers.AggregateHandler)
+
+	// API endpoint for the distinct values of a field
+	mux.HandleFunc("/api/logs/distinct", handlers.DistinctHandler)
+
+	// API endpoint for the range of ids of the logs, to export them in chunks
+	mux.HandleFunc("/api/logs/id-range", handlers.IDRangeHandler)
+
+	// API endpoint for the logs per severity, for pie charts
+	mux.HandleFunc("/api/logs/severity-distribution", handlers.SeverityDistributionHandler)
+
+	// API endpoint for the logs surrounding a log
+	mux.HandleFunc("/api/logs/{id}/context", handlers.LogContextHandler)
+
+	// API endpoint correlating the logs of an event type across hosts
+	mux.HandleFunc("/api/events/{msgid}", handlers.EventsHandler)
+
+	// API endpoint for the volume received from each source host
+	mux.HandleFunc("/api/sources", handlers.SourcesHandler)
+
+	// API endpoint for NDJSON log ingestion
+	mux.HandleFunc("/api/ingest", handlers.IngestHandler)
+
+	// API endpoint for bulk loading historical NDJSON logs
+	mux.HandleFunc("/api/ingest/bulk", handlers.BulkIngestHandler)
+
+	// Splunk HTTP Event Collector endpoints, for agents configured for HEC
+	mux.HandleFunc("/services/collector", handlers.HECHandler)
+	mux.HandleFunc("/services/collector/event", handlers.HECHandler)
+	mux.HandleFunc("/services/collector/event/1.0", handlers.HECHandler)
+	mux.HandleFunc("/services/collector/health", handlers.HECHealthHandler)
+	mux.HandleFunc("/services/collector/health/1.0", handlers.HECHealthHandler)
+
+	// Admin endpoints, guarded by SLOGGO_ADMIN_TOKEN
+	mux.HandleFunc("/api/maintenance/compact", handlers.RequireAdmin(handlers.CompactHandler))
+	mux.HandleFunc("/api/maintenance/import", handlers.RequireAdmin(handlers.ImportHandler))
+
+	if utils.Debug {
+		// Query plan of the logs query, for debugging slow queries
+		mux.HandleFunc("/api/explain", handlers.RequireAdmin(handlers.ExplainHandler))
+	}
+
 	if utils.Pprof {
 		log.Printf("pprof endpoints are enabled at /debug/pprof/")
 		mux.HandleFunc("/debug/pprof/", pprof.Index)
@@ -7,6 +7,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
+	"sloggo/db"
+	"sloggo/models"
+	"sloggo/server/handlers"
+	"sloggo/utils"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,6 +33,26 @@ func TestServer(t *testing.T) {
 			expectedCode: http.StatusOK,
 			expectedBody: "Sloggo backend is running",
 		},
+		{
+			name:         "Readiness returns 503 until a listener is bound",
+			path:         "/api/ready",
+			method:       "GET",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Detailed health returns valid JSON",
+			path:           "/api/health/details",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:           "Metrics endpoint returns valid JSON",
+			path:           "/api/metrics",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
 		{
 			name:           "Logs endpoint returns valid JSON",
 			path:           "/api/logs",
@@ -55,6 +81,103 @@ func TestServer(t *testing.T) {
 			expectedCode:   http.StatusOK,
 			checkJSONValid: true,
 		},
+		{
+			name:           "Logs endpoint ignores invalid parameters by default",
+			path:           "/api/logs?facility=abc&cursor=yesterday",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:         "Logs endpoint rejects invalid parameters in strict mode",
+			path:         "/api/logs?strict=true&facility=abc",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Columns endpoint returns valid JSON",
+			path:           "/api/logs/columns",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:           "Aggregate endpoint returns valid JSON",
+			path:           "/api/logs/aggregate?metric=noiseScore&groupBy=appName&severity=3",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:           "Aggregate endpoint groups by hour of day",
+			path:           "/api/logs/aggregate?groupBy=hourOfDay",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:           "Aggregate endpoint returns sparklines",
+			path:           "/api/logs/aggregate?groupBy=hostname&limit=5&sparkline=true&buckets=12",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:         "Aggregate endpoint bounds the sparkline groups",
+			path:         "/api/logs/aggregate?groupBy=hostname&limit=500&sparkline=true",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Aggregate endpoint rejects unknown timezone",
+			path:         "/api/logs/aggregate?groupBy=hourOfDay&tz=Mars/Olympus",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Aggregate endpoint rejects unknown metric",
+			path:         "/api/logs/aggregate?metric=median",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Aggregate endpoint rejects unknown group field",
+			path:         "/api/logs/aggregate?groupBy=message",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Context endpoint returns 404 for unknown log",
+			path:         "/api/logs/999999999/context",
+			method:       "GET",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Context endpoint rejects invalid id",
+			path:         "/api/logs/abc/context?before=5",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Distinct endpoint returns valid JSON",
+			path:           "/api/logs/distinct?field=hostname&severity=3",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
+		{
+			name:         "Distinct endpoint rejects unknown field",
+			path:         "/api/logs/distinct?field=message",
+			method:       "GET",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Sources endpoint returns valid JSON",
+			path:           "/api/sources",
+			method:         "GET",
+			expectedCode:   http.StatusOK,
+			checkJSONValid: true,
+		},
 		{
 			name:         "Logs endpoint with method not allowed",
 			path:         "/api/logs",
@@ -291,3 +414,645 @@ func TestMockServer(t *testing.T) {
 		}
 	}
 }
+
+func TestStrictModeInvalidParams(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	req := httptest.NewRequest("GET", "/api/logs?strict=true&facility=1,abc&cursor=yesterday&sort=unknown.asc&chartMode=rate&sd.origin..ip=10.0.0.1&window=week&facetLimit=0&hasMessage=maybe&maxSeverity=loud", nil)
+	w := httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	var result handlers.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if result.Error.Code != "invalid_params" {
+		t.Errorf("Expected the invalid_params error code, got %q", result.Error.Code)
+	}
+
+	invalid := map[string]string{}
+	for _, param := range result.Error.Params {
+		invalid[param.Param] = param.Value
+	}
+
+	expected := map[string]string{
+		"facility":      "abc",
+		"cursor":        "yesterday",
+		"sort":          "unknown.asc",
+		"chartMode":     "rate",
+		"sd.origin..ip": "10.0.0.1",
+		"window":        "week",
+		"facetLimit":    "0",
+		"hasMessage":    "maybe",
+		"maxSeverity":   "loud",
+	}
+	for param, value := range expected {
+		if invalid[param] != value {
+			t.Errorf("Expected invalid param %s=%q, got %q", param, value, invalid[param])
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"invalid parameters", "GET", "/api/logs?strict=true&facility=abc", http.StatusBadRequest, "invalid_params"},
+		{"unknown log", "GET", "/api/logs/999999999/context", http.StatusNotFound, "not_found"},
+		{"unknown endpoint", "GET", "/api/nonexistent", http.StatusNotFound, "not_found"},
+		{"unsupported method", "POST", "/api/logs", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			w := httptest.NewRecorder()
+
+			server.server.Handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected a JSON error, got %q", contentType)
+			}
+
+			var result handlers.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if result.Error.Code != tc.expectedErr || result.Error.Message == "" {
+				t.Errorf("Expected a %s error with a message, got %+v", tc.expectedErr, result.Error)
+			}
+		})
+	}
+
+	// The health endpoints stay plain text
+	req := httptest.NewRequest("GET", "/api/ready", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+	if contentType := w.Result().Header.Get("Content-Type"); strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Expected a plain readiness error, got %q", contentType)
+	}
+}
+
+func TestInvalidExpressionRejected(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	// Unlike other parameters, an invalid expression is rejected without strict mode
+	req := httptest.NewRequest("GET", "/api/logs?q=lower(hostname)%3Dedge1", nil)
+	w := httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/api/logs?q=severity%3C%3D3%20AND%20hostname%3Dedge1", nil)
+	w = httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Result().StatusCode)
+	}
+}
+
+func TestIngestEndpoint(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	body := strings.Join([]string{
+		`{"severity":3,"facility":1,"hostname":"ingest-host","appName":"ingest-app","message":"Ingested message 1"}`,
+		`not json`,
+		`{"hostname":"ingest-host","appName":"ingest-app","message":"Ingested message 2","structuredData":{"meta@1":{"key":"value"}}}`,
+		`{"severity":9,"message":"Out of range severity"}`,
+		``,
+	}, "\n")
+
+	req := httptest.NewRequest("POST", "/api/ingest", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var result struct {
+		Accepted int `json:"accepted"`
+		Rejected int `json:"rejected"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+
+	if result.Accepted != 2 || result.Rejected != 2 {
+		t.Errorf("Expected 2 accepted and 2 rejected lines, got %d and %d", result.Accepted, result.Rejected)
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	var count int
+	err := db.GetDBInstance().QueryRow("SELECT COUNT(*) FROM logs WHERE app_name = ?", "ingest-app").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 ingested logs in database, got %d", count)
+	}
+
+	// Only POST is accepted
+	req = httptest.NewRequest("GET", "/api/ingest", nil)
+	w = httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, w.Result().StatusCode)
+	}
+}
+
+func TestIngestEventTimestampHeader(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	body := strings.Join([]string{
+		`{"hostname":"event-time-host","appName":"event-time-app","message":"Delayed message"}`,
+		`{"hostname":"event-time-host","appName":"event-time-app","message":"Own timestamp","timestamp":"2024-06-01T08:00:00Z"}`,
+	}, "\n")
+
+	req := httptest.NewRequest("POST", "/api/ingest", strings.NewReader(body))
+	req.Header.Set("X-Event-Timestamp", "2024-06-01T12:30:00.5Z")
+	w := httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Result().StatusCode)
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	// Lines without a timestamp get the header's, the others keep their own
+	expected := map[string]time.Time{
+		"Delayed message": time.Date(2024, 6, 1, 12, 30, 0, 500000000, time.UTC),
+		"Own timestamp":   time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for message, timestamp := range expected {
+		var stored time.Time
+		err := db.GetDBInstance().QueryRow("SELECT timestamp FROM logs WHERE app_name = ? AND msg = ?", "event-time-app", message).Scan(&stored)
+		if err != nil {
+			t.Fatalf("Failed to query database: %v", err)
+		}
+		if !stored.Equal(timestamp) {
+			t.Errorf("Expected %q to be stored at %v, got %v", message, timestamp, stored)
+		}
+	}
+
+	// The header is validated strictly, nothing is stored with an invalid one
+	req = httptest.NewRequest("POST", "/api/ingest", strings.NewReader(`{"appName":"event-time-app","message":"Invalid header"}`))
+	req.Header.Set("X-Event-Timestamp", "2024-06-01 12:30:00")
+	w = httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid header, got %d", http.StatusBadRequest, w.Result().StatusCode)
+	}
+}
+
+func TestHECEndpoint(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	originalToken := utils.HECToken
+	defer func() { utils.HECToken = originalToken }()
+	utils.HECToken = "hec-secret"
+
+	body := `{"time":1754308800.25,"host":"hec-host","sourcetype":"hec-app","event":"HEC message 1"}` +
+		`{"host":"hec-host","source":"/var/log/app.log","sourcetype":"hec-app","event":{"user":"alice"},"fields":{"env":"prod"}}`
+
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+		expectedCode  int
+		expectedHEC   int
+	}{
+		{"missing token", "", body, http.StatusUnauthorized, 2},
+		{"invalid token", "Splunk wrong", body, http.StatusForbidden, 4},
+		{"no data", "Splunk hec-secret", "", http.StatusBadRequest, 5},
+		{"missing event", "Splunk hec-secret", `{"host":"hec-host"}`, http.StatusBadRequest, 12},
+		{"blank event", "Splunk hec-secret", `{"event":""}`, http.StatusBadRequest, 13},
+		{"invalid JSON", "Splunk hec-secret", `{"event":`, http.StatusBadRequest, 6},
+		{"events", "Splunk hec-secret", body, http.StatusOK, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/services/collector/event", strings.NewReader(tc.body))
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			server.server.Handler.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedCode, w.Code)
+			}
+
+			var result handlers.HECResponse
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if result.Code != tc.expectedHEC {
+				t.Errorf("Expected HEC code %d, got %d (%s)", tc.expectedHEC, result.Code, result.Text)
+			}
+		})
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	rows, err := db.GetDBInstance().Query("SELECT epoch_ms(timestamp), msg, structured_data FROM logs WHERE hostname = 'hec-host' AND app_name = 'hec-app' ORDER BY timestamp")
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []string
+	var timestamps []int64
+	var structuredData *string
+	for rows.Next() {
+		var message string
+		var timestamp int64
+		if err := rows.Scan(&timestamp, &message, &structuredData); err != nil {
+			t.Fatalf("Failed to scan row: %v", err)
+		}
+		messages = append(messages, message)
+		timestamps = append(timestamps, timestamp)
+	}
+
+	if !slices.Equal(messages, []string{"HEC message 1", `{"user":"alice"}`}) {
+		t.Errorf("Unexpected HEC messages: %v", messages)
+	}
+	if len(timestamps) > 0 && timestamps[0] != 1754308800250 {
+		t.Errorf("Expected the event time to be kept, got %d", timestamps[0])
+	}
+	if structuredData == nil || !strings.Contains(*structuredData, `"env":"prod"`) || !strings.Contains(*structuredData, `"source":"/var/log/app.log"`) {
+		t.Errorf("Expected the source and fields as structured data, got %v", structuredData)
+	}
+
+	req := httptest.NewRequest("GET", "/services/collector/health", nil)
+	w := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the HEC health endpoint to respond 200, got %d", w.Code)
+	}
+}
+
+func TestBulkIngestEndpoint(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	timestamp := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Millisecond)
+	body := strings.Join([]string{
+		fmt.Sprintf(`{"timestamp":%q,"severity":3,"hostname":"bulk-host","appName":"bulk-app","message":"Bulk message 1"}`, timestamp.Format(time.RFC3339Nano)),
+		`{"hostname":"bulk-host","appName":"bulk-app","message":"Missing timestamp"}`,
+		fmt.Sprintf(`{"timestamp":%q,"hostname":"bulk-host","appName":"bulk-app","message":"Bulk message 2"}`, timestamp.Add(time.Second).Format(time.RFC3339Nano)),
+		`{"timestamp":"2000-01-01T00:00:00Z","hostname":"bulk-host","appName":"bulk-app","message":"Beyond retention"}`,
+		``,
+	}, "\n")
+
+	req := httptest.NewRequest("POST", "/api/ingest/bulk", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	server.server.Handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var result handlers.BulkIngestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+
+	if result.Accepted != 2 || result.Rejected != 2 {
+		t.Errorf("Expected 2 accepted and 2 rejected lines, got %d and %d", result.Accepted, result.Rejected)
+	}
+	if len(result.Rejections) != 2 || result.Rejections[0].Line != 2 || result.Rejections[1].Line != 4 {
+		t.Errorf("Expected lines 2 and 4 to be rejected, got %+v", result.Rejections)
+	}
+
+	// Logs are stored without waiting for the batch, with their original timestamps
+	var stored time.Time
+	err := db.GetDBInstance().QueryRow("SELECT MIN(timestamp) FROM logs WHERE app_name = ?", "bulk-app").Scan(&stored)
+	if err != nil {
+		t.Fatalf("Failed to query database: %v", err)
+	}
+	if !stored.Equal(timestamp) {
+		t.Errorf("Expected the original timestamp %v, got %v", timestamp, stored)
+	}
+}
+
+func TestChartWindowFollowsPage(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+	for i := range 5 {
+		err := db.StoreLog(models.LogEntry{
+			Severity:       uint8(i % 8),
+			Facility:       1,
+			Version:        1,
+			Timestamp:      base.Add(time.Duration(i) * time.Minute),
+			Hostname:       "chart-host",
+			AppName:        "chart-app",
+			ProcID:         "-",
+			MsgID:          "-",
+			StructuredData: "-",
+			Message:        fmt.Sprintf("Chart message %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to store log entry: %v", err)
+		}
+	}
+
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	server := NewServer()
+	server.setupRoutes()
+
+	dateRange := fmt.Sprintf("%d-%d", base.Add(-time.Minute).UnixMilli(), base.Add(10*time.Minute).UnixMilli())
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedRows int
+		expectedSum  int
+	}{
+		{"Chart follows the returned page", "/api/logs?appName=chart-app&size=3", 3, 3},
+		{"Chart follows the explicit date filter", "/api/logs?appName=chart-app&size=3&timestamp=" + dateRange, 3, 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+
+			server.server.Handler.ServeHTTP(w, req)
+
+			var result struct {
+				Data []map[string]any `json:"data"`
+				Meta struct {
+					ChartData []map[string]float64 `json:"chartData"`
+				} `json:"meta"`
+			}
+			if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+
+			sum := 0
+			for _, point := range result.Meta.ChartData {
+				for key, value := range point {
+					if key != "timestamp" {
+						sum += int(value)
+					}
+				}
+			}
+
+			if len(result.Data) != tc.expectedRows {
+				t.Errorf("Expected %d rows, got %d", tc.expectedRows, len(result.Data))
+			}
+			if sum != tc.expectedSum {
+				t.Errorf("Expected chart to count %d logs, got %d", tc.expectedSum, sum)
+			}
+		})
+	}
+}
+
+func TestSinceIdIncrementalReads(t *testing.T) {
+	server := NewServer()
+	server.setupRoutes()
+
+	store := func(messages ...string) {
+		for _, message := range messages {
+			err := db.StoreLog(models.LogEntry{
+				Severity:  6,
+				Facility:  1,
+				Version:   1,
+				Timestamp: time.Now(),
+				Hostname:  "since-host",
+				AppName:   "since-app",
+				ProcID:    "-",
+				MsgID:     "-",
+				Message:   message,
+			})
+			if err != nil {
+				t.Fatalf("Failed to store log entry: %v", err)
+			}
+		}
+		if err := db.ProcessBatchStoreLogs(); err != nil {
+			t.Fatalf("Failed to process batch: %v", err)
+		}
+	}
+
+	read := func(sinceId int64) ([]string, int64) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/logs?appName=since-app&sinceId=%d", sinceId), nil)
+		w := httptest.NewRecorder()
+
+		server.server.Handler.ServeHTTP(w, req)
+
+		var result struct {
+			Data []struct {
+				Message string `json:"message"`
+			} `json:"data"`
+			NextSinceID *int64 `json:"nextSinceId"`
+		}
+		if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		if result.NextSinceID == nil {
+			t.Fatal("Expected a nextSinceId")
+		}
+
+		messages := []string{}
+		for _, entry := range result.Data {
+			messages = append(messages, entry.Message)
+		}
+		return messages, *result.NextSinceID
+	}
+
+	store("Since message 1", "Since message 2")
+	messages, sinceId := read(0)
+	if !slices.Equal(messages, []string{"Since message 1", "Since message 2"}) {
+		t.Fatalf("Expected the first rows in insertion order, got %v", messages)
+	}
+
+	store("Since message 3")
+	messages, nextSinceId := read(sinceId)
+	if !slices.Equal(messages, []string{"Since message 3"}) {
+		t.Errorf("Expected only the new row, got %v", messages)
+	}
+
+	// Nothing new, the client keeps the same sinceId
+	messages, lastSinceId := read(nextSinceId)
+	if len(messages) != 0 || lastSinceId != nextSinceId {
+		t.Errorf("Expected no rows and sinceId %d, got %v and %d", nextSinceId, messages, lastSinceId)
+	}
+}
+
+func TestLegacyCompatMode(t *testing.T) {
+	originalCompat := utils.ApiCompat
+	defer func() {
+		utils.ApiCompat = originalCompat
+	}()
+
+	err := db.StoreLog(models.LogEntry{
+		Severity:       6,
+		Facility:       1,
+		Version:        1,
+		Timestamp:      time.Now().Add(-time.Minute),
+		Hostname:       "compat-host",
+		AppName:        "compat-app",
+		ProcID:         "-",
+		MsgID:          "-",
+		StructuredData: "-",
+		Message:        "Compat message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to store log entry: %v", err)
+	}
+	if err := db.ProcessBatchStoreLogs(); err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	server := NewServer()
+	server.setupRoutes()
+
+	testCases := []struct {
+		name        string
+		compat      string
+		presentKeys []string
+		absentKeys  []string
+	}{
+		{"default field names", "", []string{"hostname", "appName"}, []string{"host", "app"}},
+		{"legacy field names", "legacy", []string{"host", "app"}, []string{"hostname", "appName"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			utils.ApiCompat = tc.compat
+
+			req := httptest.NewRequest("GET", "/api/logs?appName=compat-app", nil)
+			w := httptest.NewRecorder()
+
+			server.server.Handler.ServeHTTP(w, req)
+
+			var result struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.NewDecoder(w.Result().Body).Decode(&result); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if len(result.Data) != 1 {
+				t.Fatalf("Expected 1 row, got %d", len(result.Data))
+			}
+
+			for _, key := range tc.presentKeys {
+				if _, ok := result.Data[0][key]; !ok {
+					t.Errorf("Expected key %q in response", key)
+				}
+			}
+			for _, key := range tc.absentKeys {
+				if _, ok := result.Data[0][key]; ok {
+					t.Errorf("Unexpected key %q in response", key)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactEndpoint(t *testing.T) {
+	originalToken := utils.AdminToken
+	defer func() {
+		utils.AdminToken = originalToken
+	}()
+
+	server := NewServer()
+	server.setupRoutes()
+
+	compact := func(method string, token string) *http.Response {
+		req := httptest.NewRequest(method, "/api/maintenance/compact", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+
+		server.server.Handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	// Disabled without a configured token
+	utils.AdminToken = ""
+	if resp := compact("POST", "secret"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	utils.AdminToken = "secret"
+	if resp := compact("POST", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	if resp := compact("POST", "secret"); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+
+	// The compaction runs in the background, poll its status
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp := compact("GET", "secret")
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		if status.Status != "running" {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if status.Status != "done" {
+		t.Errorf("Expected compaction to be done, got %q (%s)", status.Status, status.Error)
+	}
+}
@@ -0,0 +1,144 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sloggo/models"
+	"sloggo/utils"
+)
+
+// Config describes a step of the transform pipeline
+type Config struct {
+	Type        string `json:"type"`        // redact or rewrite
+	Field       string `json:"field"`       // message, hostname, appName, procId or msgId
+	Pattern     string `json:"pattern"`     // Regular expression matched against the field
+	Replacement string `json:"replacement"` // Replacement of the matches
+}
+
+// Transform modifies a parsed log entry before it's stored
+type Transform interface {
+	Apply(entry *models.LogEntry)
+}
+
+// factories build the transforms of each type, a new transform only needs to be added here
+var factories = map[string]func(config Config) (Transform, error){
+	"redact":  newRedact,
+	"rewrite": newRewrite,
+}
+
+// pipeline holds the transforms of SLOGGO_TRANSFORMS, in order
+var pipeline []Transform
+
+func init() {
+	if utils.Transforms == "" {
+		return
+	}
+
+	parsed, err := ParsePipeline(utils.Transforms)
+	if err != nil {
+		log.Printf("Invalid SLOGGO_TRANSFORMS, logs are stored untransformed: %v", err)
+		return
+	}
+
+	pipeline = parsed
+	log.Printf("Loaded %d transform(s)", len(pipeline))
+}
+
+// ParsePipeline parses a JSON array of transform configs into an ordered pipeline
+func ParsePipeline(config string) ([]Transform, error) {
+	var configs []Config
+	if err := json.Unmarshal([]byte(config), &configs); err != nil {
+		return nil, fmt.Errorf("error decoding transforms: %v", err)
+	}
+
+	transforms := make([]Transform, 0, len(configs))
+	for i, config := range configs {
+		factory, ok := factories[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("transform %d has an unknown type %q", i+1, config.Type)
+		}
+
+		transform, err := factory(config)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d: %v", i+1, err)
+		}
+		transforms = append(transforms, transform)
+	}
+
+	return transforms, nil
+}
+
+// Apply runs the configured pipeline on the entry
+func Apply(entry *models.LogEntry) {
+	for _, transform := range pipeline {
+		transform.Apply(entry)
+	}
+}
+
+// regexTransform replaces the matches of a pattern in a field
+type regexTransform struct {
+	field       func(entry *models.LogEntry) *string
+	pattern     *regexp.Regexp
+	replacement string
+	expand      bool // Whether the replacement expands $1 style references
+}
+
+// newRedact masks the matches of a pattern, in the message unless another field is given
+func newRedact(config Config) (Transform, error) {
+	if config.Field == "" {
+		config.Field = "message"
+	}
+	if config.Replacement == "" {
+		config.Replacement = "[REDACTED]"
+	}
+
+	return newRegexTransform(config, false)
+}
+
+// newRewrite replaces the matches of a pattern in a field, $1 style references are expanded
+func newRewrite(config Config) (Transform, error) {
+	if config.Field == "" {
+		return nil, fmt.Errorf("rewrite needs a field")
+	}
+
+	return newRegexTransform(config, true)
+}
+
+func newRegexTransform(config Config, expand bool) (Transform, error) {
+	field, ok := fields[config.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", config.Field)
+	}
+
+	if config.Pattern == "" {
+		return nil, fmt.Errorf("%s needs a pattern", config.Type)
+	}
+
+	pattern, err := regexp.Compile(config.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	return &regexTransform{field: field, pattern: pattern, replacement: config.Replacement, expand: expand}, nil
+}
+
+func (t *regexTransform) Apply(entry *models.LogEntry) {
+	value := t.field(entry)
+
+	if t.expand {
+		*value = t.pattern.ReplaceAllString(*value, t.replacement)
+	} else {
+		*value = t.pattern.ReplaceAllLiteralString(*value, t.replacement)
+	}
+}
+
+// fields gives access to the transformable fields of an entry
+var fields = map[string]func(entry *models.LogEntry) *string{
+	"message":  func(entry *models.LogEntry) *string { return &entry.Message },
+	"hostname": func(entry *models.LogEntry) *string { return &entry.Hostname },
+	"appName":  func(entry *models.LogEntry) *string { return &entry.AppName },
+	"procId":   func(entry *models.LogEntry) *string { return &entry.ProcID },
+	"msgId":    func(entry *models.LogEntry) *string { return &entry.MsgID },
+}
@@ -0,0 +1,74 @@
+package transform
+
+import (
+	"sloggo/models"
+	"testing"
+)
+
+func TestRedactCreditCardNumbers(t *testing.T) {
+	transforms, err := ParsePipeline(`[{"type":"redact","pattern":"\\b(?:\\d[ -]?){12,18}\\d\\b"}]`)
+	if err != nil {
+		t.Fatalf("Failed to parse pipeline: %v", err)
+	}
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Payment with 4111111111111111 accepted", "Payment with [REDACTED] accepted"},
+		{"Card 4111 1111 1111 1111 declined", "Card [REDACTED] declined"},
+		{"Card 5500-0000-0000-0004 and 340000000000009", "Card [REDACTED] and [REDACTED]"},
+		{"Order 12345 shipped", "Order 12345 shipped"},
+	}
+
+	for _, tc := range tests {
+		entry := models.LogEntry{Message: tc.message}
+		for _, transform := range transforms {
+			transform.Apply(&entry)
+		}
+
+		if entry.Message != tc.expected {
+			t.Errorf("Redacting %q: got %q, want %q", tc.message, entry.Message, tc.expected)
+		}
+	}
+}
+
+func TestPipelineOrder(t *testing.T) {
+	transforms, err := ParsePipeline(`[
+		{"type":"rewrite","field":"hostname","pattern":"^(\\w+)\\.internal$","replacement":"$1"},
+		{"type":"rewrite","field":"message","pattern":"^\\[app\\] ","replacement":""},
+		{"type":"redact","field":"message","pattern":"password=\\S+","replacement":"password=***"}
+	]`)
+	if err != nil {
+		t.Fatalf("Failed to parse pipeline: %v", err)
+	}
+
+	entry := models.LogEntry{Hostname: "web1.internal", Message: "[app] login with password=hunter2"}
+	for _, transform := range transforms {
+		transform.Apply(&entry)
+	}
+
+	if entry.Hostname != "web1" {
+		t.Errorf("Hostname: got %q, want %q", entry.Hostname, "web1")
+	}
+	if entry.Message != "login with password=***" {
+		t.Errorf("Message: got %q, want %q", entry.Message, "login with password=***")
+	}
+}
+
+func TestParsePipelineErrors(t *testing.T) {
+	configs := []string{
+		`not json`,
+		`[{"type":"uppercase","field":"message"}]`,
+		`[{"type":"rewrite","pattern":"x"}]`,
+		`[{"type":"redact","field":"severity","pattern":"x"}]`,
+		`[{"type":"redact"}]`,
+		`[{"type":"redact","pattern":"("}]`,
+	}
+
+	for _, config := range configs {
+		if _, err := ParsePipeline(config); err == nil {
+			t.Errorf("Expected an error for %s", config)
+		}
+	}
+}
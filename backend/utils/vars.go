@@ -15,10 +15,134 @@ var UdpPort string
 
 var TcpPort string
 
+var JournalPort string
+
+var TcpDelimiter string
+
+var TcpMaxConnectionMessages int64
+
+var TcpMaxConnectionSeconds int64
+
+var JoinContinuation bool
+
+var ProxyProtocol bool
+
+var AllowedHosts string
+
+var AllowedIPs string
+
+var MaxProcessors int64
+
+var TcpMaxProcessors int64
+
+var UdpMaxProcessors int64
+
 var ApiPort string
 
+var PortAuto bool
+
+var ApiCompat string
+
+var DefaultSeverityFilter string
+
+var DefaultWindow string
+
+var DisplayTimezone string
+
+var DefaultWindowRows bool
+
 var LogRetentionMinutes int64
 
+var RetentionApp string
+
+var RetentionHost string
+
+var MaxRows int64
+
+var MinFreeDiskMB int64
+
+var BatchOnError string
+
+var BatchPersist bool
+
+var SelfTest bool
+
+var SelfTestRequired bool
+
+var ShutdownGraceSeconds int64
+
+var DuckDBMemoryLimit string
+
+var DuckDBThreads int64
+
+var DuckDBExtensions string
+
+var S3AccessKeyID string
+
+var S3SecretAccessKey string
+
+var S3Region string
+
+var S3Endpoint string
+
+var DbOpenRetries int64
+
+var DbOpenRetrySeconds int64
+
+var AlertRules string
+
+var ForwardAddr string
+
+var ForwardBuffer int64
+
+var FacilityRemap string
+
+var DefaultFacility string
+
+var DefaultSeverity string
+
+var Sample string
+
+var Transforms string
+
+var MsgStripRegex string
+
+var InputEncoding string
+
+var HostnameMode string
+
+var TimestampSource string
+
+var TimestampToleranceSeconds int64
+
+var SeverityFromKeywords bool
+
+var SdSeverityField string
+
+var AdminToken string
+
+var HECToken string
+
+var NoiseWeights string
+
+var FacetCacheSeconds int64
+
+var FacetLimit int64
+
+var FacetSampleThreshold int64
+
+var FacetSampleSize int64
+
+var MaxDBQueries int64
+
+var StructuredDataFacets string
+
+var FacetExcludeAppName string
+
+var Tables string
+
+var NormalizeCase string
+
 var Pprof bool
 
 var Debug bool
@@ -30,6 +154,8 @@ var Version string // Set via -X flag during build
 //   - "auto"   : try RFC5424 first, then RFC3164 (default)
 //   - "rfc5424": only parse as RFC5424
 //   - "rfc3164": only parse as RFC3164
+//   - "winevt" : like "auto", lifting nxlog forwarded Windows event fields
+//
 // Any other value falls back to "auto".
 var logFormat string
 var logFormatMutex sync.RWMutex
@@ -48,23 +174,115 @@ func SetLogFormat(format string) {
 	logFormat = format
 }
 
+// tcpLogFormat and udpLogFormat override the log format of a listener, empty when not overridden
+var tcpLogFormat, udpLogFormat string
+
+// GetTCPLogFormat returns the log format of the TCP listener, SLOGGO_LOG_FORMAT unless overridden
+func GetTCPLogFormat() string {
+	if tcpLogFormat != "" {
+		return tcpLogFormat
+	}
+	return GetLogFormat()
+}
+
+// GetUDPLogFormat returns the log format of the UDP listener, SLOGGO_LOG_FORMAT unless overridden
+func GetUDPLogFormat() string {
+	if udpLogFormat != "" {
+		return udpLogFormat
+	}
+	return GetLogFormat()
+}
+
+// parseLogFormat returns the supported log format named by value, "auto" for any other value
+func parseLogFormat(value string) string {
+	switch value {
+	case "rfc5424", "rfc3164", "winevt":
+		return value
+	default:
+		return "auto"
+	}
+}
+
 func init() {
 	Listeners = strings.Split(GetSanitizedEnvString("SLOGGO_LISTENERS", "tcp,udp"), ",")
 	UdpPort = GetSanitizedEnvString("SLOGGO_UDP_PORT", "5514")
 	TcpPort = GetSanitizedEnvString("SLOGGO_TCP_PORT", "6514")
+	JournalPort = GetSanitizedEnvString("SLOGGO_JOURNAL_PORT", "6515")
+	TcpDelimiter = GetSanitizedEnvString("SLOGGO_TCP_DELIMITER", "lf")
+	TcpMaxConnectionMessages = GetSanitizedEnvInt64("SLOGGO_TCP_MAX_CONNECTION_MESSAGES", 0) // Default to unlimited
+	TcpMaxConnectionSeconds = GetSanitizedEnvInt64("SLOGGO_TCP_MAX_CONNECTION_SECONDS", 0)   // Default to unlimited
+	MaxProcessors = GetSanitizedEnvInt64("SLOGGO_MAX_PROCESSORS", 0)                         // Default to 100
+	TcpMaxProcessors = GetSanitizedEnvInt64("SLOGGO_TCP_MAX_PROCESSORS", 0)                  // Default to SLOGGO_MAX_PROCESSORS
+	UdpMaxProcessors = GetSanitizedEnvInt64("SLOGGO_UDP_MAX_PROCESSORS", 0)                  // Default to SLOGGO_MAX_PROCESSORS
+	JoinContinuation = GetSanitizedEnvString("SLOGGO_JOIN_CONTINUATION", "false") == "true"
+	ProxyProtocol = GetSanitizedEnvString("SLOGGO_PROXY_PROTOCOL", "false") == "true"
+	AllowedHosts = GetSanitizedEnvString("SLOGGO_ALLOWED_HOSTS", "")
+	AllowedIPs = GetSanitizedEnvString("SLOGGO_ALLOWED_IPS", "")
 	ApiPort = GetSanitizedEnvString("SLOGGO_API_PORT", "8080")
+	PortAuto = GetSanitizedEnvString("SLOGGO_PORT_AUTO", "false") == "true"
+	ApiCompat = GetSanitizedEnvString("SLOGGO_API_COMPAT", "")
+	DefaultSeverityFilter = GetSanitizedEnvString("SLOGGO_DEFAULT_SEVERITY_FILTER", "")
+	DefaultWindow = GetSanitizedEnvString("SLOGGO_DEFAULT_WINDOW", "")
+	DisplayTimezone = GetEnvString("SLOGGO_DISPLAY_TIMEZONE", "")
+	DefaultWindowRows = GetSanitizedEnvString("SLOGGO_DEFAULT_WINDOW_ROWS", "false") == "true"
 	LogRetentionMinutes = GetSanitizedEnvInt64("SLOGGO_LOG_RETENTION_MINUTES", 30*24*60) // Default to 30 days
+	RetentionApp = GetEnvString("SLOGGO_RETENTION_APP", "")
+	RetentionHost = GetEnvString("SLOGGO_RETENTION_HOST", "")
+	MaxRows = GetSanitizedEnvInt64("SLOGGO_MAX_ROWS", 0)               // Default to unlimited
+	MinFreeDiskMB = GetSanitizedEnvInt64("SLOGGO_MIN_FREE_DISK_MB", 0) // Default to disabled
+	BatchOnError = GetSanitizedEnvString("SLOGGO_BATCH_ON_ERROR", "abort")
+	BatchPersist = GetSanitizedEnvString("SLOGGO_BATCH_PERSIST", "false") == "true"
+	SelfTest = GetSanitizedEnvString("SLOGGO_SELFTEST", "false") == "true"
+	SelfTestRequired = GetSanitizedEnvString("SLOGGO_SELFTEST_REQUIRED", "false") == "true"
+	ShutdownGraceSeconds = GetSanitizedEnvInt64("SLOGGO_SHUTDOWN_GRACE_SECONDS", 5)
+	DuckDBMemoryLimit = GetSanitizedEnvString("SLOGGO_DUCKDB_MEMORY_LIMIT", "")
+	DuckDBThreads = GetSanitizedEnvInt64("SLOGGO_DUCKDB_THREADS", 0)
+	DuckDBExtensions = GetSanitizedEnvString("SLOGGO_DUCKDB_EXTENSIONS", "")
+	S3AccessKeyID = GetEnvString("SLOGGO_S3_ACCESS_KEY_ID", "")
+	S3SecretAccessKey = GetEnvString("SLOGGO_S3_SECRET_ACCESS_KEY", "")
+	S3Region = GetEnvString("SLOGGO_S3_REGION", "")
+	S3Endpoint = GetEnvString("SLOGGO_S3_ENDPOINT", "")
+	DbOpenRetries = GetSanitizedEnvInt64("SLOGGO_DB_OPEN_RETRIES", 5)
+	DbOpenRetrySeconds = GetSanitizedEnvInt64("SLOGGO_DB_OPEN_RETRY_SECONDS", 1)
+	AlertRules = GetEnvString("SLOGGO_ALERT_RULES", "")
+	ForwardAddr = GetSanitizedEnvString("SLOGGO_FORWARD_ADDR", "")
+	ForwardBuffer = GetSanitizedEnvInt64("SLOGGO_FORWARD_BUFFER", 10000)
+	FacilityRemap = GetEnvString("SLOGGO_FACILITY_REMAP", "")
+	DefaultFacility = GetSanitizedEnvString("SLOGGO_DEFAULT_FACILITY", "")
+	DefaultSeverity = GetSanitizedEnvString("SLOGGO_DEFAULT_SEVERITY", "")
+	Sample = GetEnvString("SLOGGO_SAMPLE", "")
+	Transforms = GetEnvString("SLOGGO_TRANSFORMS", "")
+	MsgStripRegex = GetEnvString("SLOGGO_MSG_STRIP_REGEX", "")
+	InputEncoding = GetSanitizedEnvString("SLOGGO_INPUT_ENCODING", "utf-8")
+	HostnameMode = GetSanitizedEnvString("SLOGGO_HOSTNAME_MODE", "raw")
+	TimestampSource = GetSanitizedEnvString("SLOGGO_TIMESTAMP_SOURCE", "message")
+	TimestampToleranceSeconds = GetSanitizedEnvInt64("SLOGGO_TIMESTAMP_TOLERANCE_SECONDS", 86400)
+	SeverityFromKeywords = GetSanitizedEnvString("SLOGGO_SEVERITY_FROM_KEYWORDS", "false") == "true"
+	SdSeverityField = GetEnvString("SLOGGO_SD_SEVERITY_FIELD", "")
+	AdminToken = GetEnvString("SLOGGO_ADMIN_TOKEN", "")
+	HECToken = GetEnvString("SLOGGO_HEC_TOKEN", "")
+	NoiseWeights = GetSanitizedEnvString("SLOGGO_NOISE_WEIGHTS", "")
+	FacetCacheSeconds = GetSanitizedEnvInt64("SLOGGO_FACET_CACHE_SECONDS", 5)
+	FacetLimit = GetSanitizedEnvInt64("SLOGGO_FACET_LIMIT", 50)
+	FacetSampleThreshold = GetSanitizedEnvInt64("SLOGGO_FACET_SAMPLE_THRESHOLD", 0) // Default to exact facets
+	FacetSampleSize = GetSanitizedEnvInt64("SLOGGO_FACET_SAMPLE_SIZE", 1000000)
+	MaxDBQueries = GetSanitizedEnvInt64("SLOGGO_MAX_DB_QUERIES", 0) // Default to unlimited
+	StructuredDataFacets = GetEnvString("SLOGGO_SD_FACETS", "")
+	FacetExcludeAppName = GetEnvString("SLOGGO_FACET_EXCLUDE_APPNAME", "")
+	Tables = GetSanitizedEnvString("SLOGGO_TABLES", "")
+	NormalizeCase = GetEnvString("SLOGGO_NORMALIZE_CASE", "")
 	Pprof = GetSanitizedEnvString("SLOGGO_PPROF", "false") == "true"
 	Debug = GetSanitizedEnvString("SLOGGO_DEBUG", "false") == "true"
 
 	// Configure log format selection
-	switch GetSanitizedEnvString("SLOGGO_LOG_FORMAT", "auto") {
-	case "rfc5424":
-		logFormat = "rfc5424"
-	case "rfc3164":
-		logFormat = "rfc3164"
-	default:
-		logFormat = "auto"
+	logFormat = parseLogFormat(GetSanitizedEnvString("SLOGGO_LOG_FORMAT", "auto"))
+
+	// Listener overrides, falling back to SLOGGO_LOG_FORMAT when unset
+	if format := GetSanitizedEnvString("SLOGGO_TCP_LOG_FORMAT", ""); format != "" {
+		tcpLogFormat = parseLogFormat(format)
+	}
+	if format := GetSanitizedEnvString("SLOGGO_UDP_LOG_FORMAT", ""); format != "" {
+		udpLogFormat = parseLogFormat(format)
 	}
 }
 
@@ -81,6 +299,17 @@ func GetSanitizedEnvString(key string, defaultValue string) string {
 	return value
 }
 
+// GetEnvString returns the trimmed value without changing its case, for values such as URLs or JSON
+func GetEnvString(key string, defaultValue string) string {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	return strings.TrimSpace(value)
+}
+
 func GetSanitizedEnvInt64(key string, defaultValue int64) int64 {
 	value := os.Getenv(key)
 